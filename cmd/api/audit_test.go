@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api.etin.dev/internal/data"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type stubAuditLog struct {
+	events []*data.AuditEvent
+}
+
+func (l *stubAuditLog) Insert(event *data.AuditEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestRecordAudit_CapturesActorRequestAndSnapshots(t *testing.T) {
+	audit := &stubAuditLog{}
+	app := &application{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		audit:    func(context.Context) auditLog { return audit },
+		sessions: newSessionManager(time.Hour),
+	}
+	token, _, err := app.sessions.create("admin@example.com")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	before := data.Tag{ID: 4, Name: "Go"}
+	after := data.Tag{ID: 4, Name: "Golang"}
+
+	handler := app.requestID(app.identifyActor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.recordAudit(r.Context(), "tag", "updated", 4, before, after)
+	})))
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/tags/4", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-ID", "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(audit.events) != 1 {
+		t.Fatalf("expected one audit event, got %d", len(audit.events))
+	}
+
+	event := audit.events[0]
+	if event.ActorType != "admin" || event.Actor != "admin@example.com" || event.RequestID != "req-42" {
+		t.Fatalf("unexpected actor or request %+v", event)
+	}
+	if !strings.Contains(string(event.Before), `"name":"Go"`) || !strings.Contains(string(event.After), `"name":"Golang"`) {
+		t.Fatalf("unexpected snapshots %s -> %s", event.Before, event.After)
+	}
+}
+
+func TestRecordAudit_LeavesMissingSnapshotsEmpty(t *testing.T) {
+	audit := &stubAuditLog{}
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), audit: func(context.Context) auditLog { return audit }}
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/tags/4", nil)
+	app.recordAudit(req.Context(), "tag", "deleted", 4, data.Tag{ID: 4}, nil)
+
+	event := audit.events[0]
+	if event.ActorType != "anonymous" || event.Actor != "" {
+		t.Fatalf("expected an anonymous actor without credentials, got %+v", event)
+	}
+	if event.Before == nil || event.After != nil {
+		t.Fatalf("expected only a before snapshot, got %s -> %s", event.Before, event.After)
+	}
+}
+
+func TestIdentifyActor_IgnoresInvalidTokens(t *testing.T) {
+	audit := &stubAuditLog{}
+	app := &application{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		audit:    func(context.Context) auditLog { return audit },
+		sessions: newSessionManager(time.Hour),
+	}
+
+	handler := app.identifyActor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.recordAudit(r.Context(), "tag", "deleted", 4, data.Tag{ID: 4}, nil)
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/tags/4", nil)
+	req.Header.Set("Authorization", "Bearer made-up-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if event := audit.events[0]; event.ActorType != "anonymous" || event.Actor != "" {
+		t.Fatalf("expected an unknown token to be anonymous, got %+v", event)
+	}
+}
+
+func TestRecordAudit_FailureRollsBackTheChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	models := data.NewModels(db, log.New(io.Discard, "", 0))
+	app := &application{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:   models,
+		audit:    func(ctx context.Context) auditLog { return models.WithContext(ctx).AuditEvents },
+		sessions: newSessionManager(time.Hour),
+	}
+	token, _, err := app.sessions.create("admin@example.com")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO item_notes`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery(`INSERT INTO audit_events`).
+		WithArgs("admin", "admin@example.com", sqlmock.AnyArg(), "item_note", int64(9), "created", nil, sqlmock.AnyArg()).
+		WillReturnError(errors.New("permission denied for table audit_events"))
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/item-notes", strings.NewReader(`{"noteId": 3, "itemId": 5, "itemType": "project"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	app.identifyActor(http.HandlerFunc(app.createItemNoteHandler)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
@@ -10,20 +10,26 @@ import (
 	"time"
 )
 
+// adminSession records who logged in with a token and when the token stops being valid.
+type adminSession struct {
+	email  string
+	expiry time.Time
+}
+
 type sessionManager struct {
 	mu       sync.RWMutex
-	sessions map[string]time.Time
+	sessions map[string]adminSession
 	ttl      time.Duration
 }
 
 func newSessionManager(ttl time.Duration) *sessionManager {
 	return &sessionManager{
-		sessions: make(map[string]time.Time),
+		sessions: make(map[string]adminSession),
 		ttl:      ttl,
 	}
 }
 
-func (sm *sessionManager) create() (string, time.Time, error) {
+func (sm *sessionManager) create(email string) (string, time.Time, error) {
 	sm.cleanupExpired()
 
 	tokenBytes := make([]byte, 32)
@@ -35,33 +41,39 @@ func (sm *sessionManager) create() (string, time.Time, error) {
 	expiry := time.Now().Add(sm.ttl)
 
 	sm.mu.Lock()
-	sm.sessions[token] = expiry
+	sm.sessions[token] = adminSession{email: email, expiry: expiry}
 	sm.mu.Unlock()
 
 	return token, expiry, nil
 }
 
 func (sm *sessionManager) validate(token string) bool {
+	_, ok := sm.lookup(token)
+	return ok
+}
+
+// lookup returns the email of the admin a token was issued to, if the token is still valid.
+func (sm *sessionManager) lookup(token string) (string, bool) {
 	if token == "" {
-		return false
+		return "", false
 	}
 
 	sm.mu.RLock()
-	expiry, ok := sm.sessions[token]
+	session, ok := sm.sessions[token]
 	sm.mu.RUnlock()
 
 	if !ok {
-		return false
+		return "", false
 	}
 
-	if time.Now().After(expiry) {
+	if time.Now().After(session.expiry) {
 		sm.mu.Lock()
 		delete(sm.sessions, token)
 		sm.mu.Unlock()
-		return false
+		return "", false
 	}
 
-	return true
+	return session.email, true
 }
 
 func (sm *sessionManager) revoke(token string) {
@@ -74,12 +86,28 @@ func (sm *sessionManager) revoke(token string) {
 	sm.mu.Unlock()
 }
 
+// active reports how many sessions have not expired.
+func (sm *sessionManager) active() int {
+	now := time.Now()
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	count := 0
+	for _, session := range sm.sessions {
+		if !now.After(session.expiry) {
+			count++
+		}
+	}
+	return count
+}
+
 func (sm *sessionManager) cleanupExpired() {
 	now := time.Now()
 
 	sm.mu.Lock()
-	for token, expiry := range sm.sessions {
-		if now.After(expiry) {
+	for token, session := range sm.sessions {
+		if now.After(session.expiry) {
 			delete(sm.sessions, token)
 		}
 	}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 25
+	defaultConnMaxLifetime = time.Hour
+	defaultConnMaxIdleTime = 15 * time.Minute
+	defaultConnectTimeout  = 30 * time.Second
+
+	// connectBackoff is the wait after the first failed ping at startup, doubled after each
+	// further failure up to maxConnectBackoff.
+	connectBackoff    = 500 * time.Millisecond
+	maxConnectBackoff = 5 * time.Second
+	// pingTimeout bounds a single ping, so a database that accepts connections but never
+	// answers does not use up the whole connect timeout in one attempt.
+	pingTimeout = 5 * time.Second
+)
+
+// poolEnv names the environment variables that stand in for the pool flags when those are not
+// given on the command line.
+var poolEnv = map[string]string{
+	"db-max-open-conns":     "WEBSITE_DB_MAX_OPEN_CONNS",
+	"db-max-idle-conns":     "WEBSITE_DB_MAX_IDLE_CONNS",
+	"db-conn-max-lifetime":  "WEBSITE_DB_CONN_MAX_LIFETIME",
+	"db-conn-max-idle-time": "WEBSITE_DB_CONN_MAX_IDLE_TIME",
+	"db-connect-timeout":    "WEBSITE_DB_CONNECT_TIMEOUT",
+}
+
+// setFlagsFromEnv sets each flag in vars to the value of its environment variable, if that is
+// set, so that the flag's own parser validates it. Call it before fs.Parse so the command line
+// still takes precedence.
+func setFlagsFromEnv(fs *flag.FlagSet, vars map[string]string) error {
+	for name, key := range vars {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+	return nil
+}
+
+// openDB returns a connection pool for dsn with the configured limits. It does not connect;
+// see waitForDB.
+func openDB(cfg config, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.db.maxOpenConns)
+	db.SetMaxIdleConns(cfg.db.maxIdleConns)
+	db.SetConnMaxLifetime(cfg.db.connMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.db.connMaxIdleTime)
+
+	return db, nil
+}
+
+// waitForDB pings db until it answers or timeout passes, backing off between attempts, so the
+// server can start alongside a database that is still booting.
+func waitForDB(db *sql.DB, timeout time.Duration, logf func(format string, v ...any)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backoff := connectBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancelPing := context.WithTimeout(ctx, pingTimeout)
+		err := db.PingContext(pingCtx)
+		cancelPing()
+		if err == nil {
+			return nil
+		}
+
+		logf("database is not ready (attempt %d): %s", attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database did not become ready within %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// poolStats is the part of sql.DBStats reported by the healthcheck.
+type poolStats struct {
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDuration       string `json:"waitDuration"`
+	MaxIdleClosed      int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64  `json:"maxLifetimeClosed"`
+}
+
+func newPoolStats(stats sql.DBStats) poolStats {
+	return poolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration.String(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
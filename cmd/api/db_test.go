@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWaitForDB_RetriesUntilTheDatabaseAnswers(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("the database system is starting up"))
+	mock.ExpectPing()
+
+	var attempts int
+	logf := func(format string, v ...any) { attempts++ }
+
+	if err := waitForDB(db, time.Minute, logf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 failed attempt to be logged, got %d", attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWaitForDB_GivesUpAfterTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	refused := errors.New("connection refused")
+	mock.ExpectPing().WillReturnError(refused)
+
+	start := time.Now()
+	err = waitForDB(db, 50*time.Millisecond, func(string, ...any) {})
+	if !errors.Is(err, refused) {
+		t.Fatalf("expected the last ping error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= connectBackoff {
+		t.Fatalf("expected to give up at the timeout rather than after backing off, took %s", elapsed)
+	}
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	newFlags := func() (*flag.FlagSet, *int, *time.Duration) {
+		fs := flag.NewFlagSet("api", flag.ContinueOnError)
+		open := fs.Int("db-max-open-conns", defaultMaxOpenConns, "")
+		lifetime := fs.Duration("db-conn-max-lifetime", defaultConnMaxLifetime, "")
+		return fs, open, lifetime
+	}
+
+	t.Setenv("WEBSITE_DB_MAX_OPEN_CONNS", "50")
+	t.Setenv("WEBSITE_DB_CONN_MAX_LIFETIME", "30m")
+
+	fs, open, lifetime := newFlags()
+	if err := setFlagsFromEnv(fs, poolEnv); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := fs.Parse([]string{"-db-max-open-conns", "10"}); err != nil {
+		t.Fatalf("unexpected error parsing flags: %s", err)
+	}
+
+	if *open != 10 || *lifetime != 30*time.Minute {
+		t.Fatalf("expected the command line to override the environment, got %d and %s", *open, *lifetime)
+	}
+
+	t.Setenv("WEBSITE_DB_CONN_MAX_LIFETIME", "forever")
+	fs, _, _ = newFlags()
+	if err := setFlagsFromEnv(fs, poolEnv); err == nil {
+		t.Fatalf("expected an invalid duration to be rejected")
+	}
+}
+
+func TestHealthcheck_ReportsPoolStats(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	app := &application{config: config{env: "test"}, db: db}
+
+	rr := httptest.NewRecorder()
+	app.healthcheck(rr, httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil))
+
+	var body struct {
+		Status   string          `json:"status"`
+		Database *poolStats      `json:"database"`
+		Replica  json.RawMessage `json:"replica"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if body.Status != "available" || body.Database == nil || body.Database.MaxOpenConnections != 7 {
+		t.Fatalf("unexpected healthcheck %s", rr.Body.String())
+	}
+	if body.Replica != nil {
+		t.Fatalf("expected no replica stats without a replica, got %s", body.Replica)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api.etin.dev/internal/data"
+	"github.com/lib/pq"
+)
+
+// readAssetLinkPath extracts the content type and item ID shared by every gallery route.
+func readAssetLinkPath(r *http.Request) (data.ItemType, int64, bool) {
+	contentType := r.PathValue("contentType")
+	itemID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if contentType == "" || err != nil || itemID < 1 {
+		return "", 0, false
+	}
+
+	return data.ItemType(strings.ToLower(contentType)), itemID, true
+}
+
+func (app *application) getAssetLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	itemType, itemID, ok := readAssetLinkPath(r)
+	if !ok {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	links, err := app.getModels(r).AssetLinks.GetForItem(itemType, itemID)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidItemType) {
+			app.writeError(w, http.StatusBadRequest)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Error retrieving assets", "itemType", itemType, "itemId", itemID, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"assets": links})
+}
+
+func (app *application) createAssetLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	itemType, itemID, ok := readAssetLinkPath(r)
+	if !ok {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		AssetID int64  `json:"assetId"`
+		Caption string `json:"caption"`
+		AltText string `json:"altText"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse asset link payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	if input.AssetID < 1 {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	asset, err := app.getModels(r).Assets.Get(input.AssetID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Error retrieving asset", "assetId", input.AssetID, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	before := gallerySnapshot(app.getModels(r), itemType, itemID)
+	link := &data.AssetLink{
+		ItemType: itemType,
+		ItemID:   itemID,
+		AssetID:  input.AssetID,
+		Caption:  input.Caption,
+		AltText:  input.AltText,
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.AssetLinks.Attach(link); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), string(itemType), "asset_attached", itemID, before, gallerySnapshot(tx, itemType, itemID))
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		if status, handled := assetLinkErrorStatus(err); handled {
+			app.writeError(w, status)
+			return
+		}
+		app.logPostgresError(r.Context(), "Could not attach asset", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	link.Asset = asset
+	app.writeJSON(w, http.StatusCreated, envelope{"asset": link})
+}
+
+func (app *application) updateAssetLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	itemType, itemID, ok := readAssetLinkPath(r)
+	if !ok {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	assetID, err := strconv.ParseInt(r.PathValue("assetId"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Caption string `json:"caption"`
+		AltText string `json:"altText"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse asset link payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	before := gallerySnapshot(app.getModels(r), itemType, itemID)
+	link := &data.AssetLink{
+		ItemType: itemType,
+		ItemID:   itemID,
+		AssetID:  assetID,
+		Caption:  input.Caption,
+		AltText:  input.AltText,
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.AssetLinks.Update(link); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), string(itemType), "asset_updated", itemID, before, gallerySnapshot(tx, itemType, itemID))
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		if status, handled := assetLinkErrorStatus(err); handled {
+			app.writeError(w, status)
+			return
+		}
+		app.logPostgresError(r.Context(), "Could not update asset link", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"asset": link})
+}
+
+func (app *application) reorderAssetLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	itemType, itemID, ok := readAssetLinkPath(r)
+	if !ok {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		AssetIDs []int64 `json:"assetIds"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse asset order payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	before := gallerySnapshot(app.getModels(r), itemType, itemID)
+	var links []*data.AssetLink
+	err := app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.AssetLinks.Reorder(itemType, itemID, input.AssetIDs); err != nil {
+			return err
+		}
+
+		var err error
+		if links, err = tx.AssetLinks.GetForItem(itemType, itemID); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), string(itemType), "assets_reordered", itemID, before, links)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, data.ErrAssetOrderMismatch) {
+			app.writeErrorMessage(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if status, handled := assetLinkErrorStatus(err); handled {
+			app.writeError(w, status)
+			return
+		}
+		app.logPostgresError(r.Context(), "Could not reorder assets", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"assets": links})
+}
+
+func (app *application) deleteAssetLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	itemType, itemID, ok := readAssetLinkPath(r)
+	if !ok {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	assetID, err := strconv.ParseInt(r.PathValue("assetId"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	before := gallerySnapshot(app.getModels(r), itemType, itemID)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.AssetLinks.Detach(itemType, itemID, assetID); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), string(itemType), "asset_detached", itemID, before, gallerySnapshot(tx, itemType, itemID))
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		if status, handled := assetLinkErrorStatus(err); handled {
+			app.writeError(w, status)
+			return
+		}
+		app.logPostgresError(r.Context(), "Could not detach asset", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusNoContent, nil)
+}
+
+// gallerySnapshot reads an item's gallery for the audit log. Gallery changes are audited against
+// the item that owns them, so "who changed this role" also covers its images.
+func gallerySnapshot(models data.Models, itemType data.ItemType, itemID int64) any {
+	links, err := models.AssetLinks.GetForItem(itemType, itemID)
+	if err != nil {
+		return nil
+	}
+	return links
+}
+
+// assetLinkErrorStatus maps the errors a client can cause when managing galleries.
+func assetLinkErrorStatus(err error) (int, bool) {
+	if errors.Is(err, data.ErrInvalidItemType) {
+		return http.StatusBadRequest, true
+	}
+
+	if err.Error() == "record not found" {
+		return http.StatusNotFound, true
+	}
+
+	// The asset is already part of the gallery.
+	if isUniqueViolation(err) {
+		return http.StatusConflict, true
+	}
+
+	// The note, project or role being linked to does not exist.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return http.StatusNotFound, true
+	}
+
+	return 0, false
+}
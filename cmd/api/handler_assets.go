@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 
@@ -13,6 +18,7 @@ const maxAssetUploadBytes = 10 << 20 // 10 MiB
 
 type assetSaver interface {
 	Insert(*data.Asset) error
+	GetByContentHash(string) (*data.Asset, error)
 }
 
 func (app *application) getCreateAssetsHandler(w http.ResponseWriter, r *http.Request) {
@@ -24,7 +30,7 @@ func (app *application) getCreateAssetsHandler(w http.ResponseWriter, r *http.Re
 	r.Body = http.MaxBytesReader(w, r.Body, maxAssetUploadBytes)
 	if err := r.ParseMultipartForm(maxAssetUploadBytes); err != nil {
 		if app.logger != nil {
-			app.logger.Printf("parse multipart form: %v", err)
+			app.logger.WarnContext(r.Context(), "parse multipart form", "error", err)
 		}
 		var maxBytesErr *http.MaxBytesError
 		if errors.As(err, &maxBytesErr) {
@@ -50,10 +56,10 @@ func (app *application) getCreateAssetsHandler(w http.ResponseWriter, r *http.Re
 		}
 	}()
 
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
 		if app.logger != nil {
-			app.logger.Printf("read file part: %v", err)
+			app.logger.WarnContext(r.Context(), "read file part", "error", err)
 		}
 		if errors.Is(err, http.ErrMissingFile) {
 			app.writeError(w, http.StatusBadRequest)
@@ -65,13 +71,67 @@ func (app *application) getCreateAssetsHandler(w http.ResponseWriter, r *http.Re
 	}
 	defer file.Close()
 
-	result, err := app.assets.Upload(r.Context(), file, assets.UploadOptions{})
+	asset, status, err := app.ingestAsset(r.Context(), header.Filename, file, app.storeUpload)
+	if err != nil {
+		app.writeIngestError(w, r, status, err)
+		return
+	}
+
+	app.writeJSON(w, status, envelope{"asset": asset})
+	return
+}
+
+// storeAsset puts validated content into the asset backend and describes the result.
+type storeAsset func(ctx context.Context, content io.ReadSeeker, probe assets.Probe) (*assets.UploadResult, error)
+
+// storeUpload sends content to the asset backend with the configured variants.
+func (app *application) storeUpload(ctx context.Context, content io.ReadSeeker, _ assets.Probe) (*assets.UploadResult, error) {
+	return app.traceUpload(ctx, func(ctx context.Context) (*assets.UploadResult, error) {
+		return app.assets.Upload(ctx, content, assets.UploadOptions{Variants: app.config.assetVariants})
+	})
+}
+
+// ingestAsset validates, deduplicates, stores and records an uploaded file. It is shared by
+// multipart, chunked and direct-to-storage uploads, which differ only in how store puts the
+// content into the backend. The returned status is the one the caller should respond with: 201
+// for a new asset, 200 when the content was already stored.
+func (app *application) ingestAsset(ctx context.Context, filename string, file io.ReadSeeker, store storeAsset) (_ *data.Asset, status int, err error) {
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("hash asset: %w", err)
+	}
+	defer func() { app.metrics.observeUpload(size, status) }()
+
+	contentHash := hex.EncodeToString(hasher.Sum(nil))
+
+	content, probe, err := app.config.uploadPolicy.ValidateFile(filename, file)
 	if err != nil {
 		if app.logger != nil {
-			app.logger.Printf("upload asset: %v", err)
+			app.logger.WarnContext(ctx, "reject asset", "filename", filename, "error", err)
+		}
+		switch {
+		case errors.Is(err, assets.ErrUnsupportedType):
+			return nil, http.StatusUnsupportedMediaType, err
+		case errors.Is(err, assets.ErrInvalidFile):
+			return nil, http.StatusUnprocessableEntity, err
+		default:
+			return nil, http.StatusInternalServerError, err
 		}
-		app.writeError(w, http.StatusBadGateway)
-		return
+	}
+
+	saver := app.assetModel(ctx)
+	existing, err := saver.GetByContentHash(contentHash)
+	if err == nil {
+		return existing, http.StatusOK, nil
+	}
+	if err.Error() != "record not found" {
+		return nil, http.StatusInternalServerError, fmt.Errorf("look up asset by content hash: %w", err)
+	}
+
+	result, err := store(ctx, content, probe)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("upload asset: %w", err)
 	}
 
 	asset := &data.Asset{
@@ -83,16 +143,77 @@ func (app *application) getCreateAssetsHandler(w http.ResponseWriter, r *http.Re
 		Bytes:        int(result.Bytes),
 		Width:        result.Width,
 		Height:       result.Height,
+		ContentHash:  contentHash,
 	}
 
-	if err := app.assetModel.Insert(asset); err != nil {
-		if app.logger != nil {
-			app.logger.Printf("persist asset: %v", err)
+	for _, variant := range result.Variants {
+		asset.Variants = append(asset.Variants, data.AssetVariant{
+			Name:   variant.Name,
+			Format: variant.Format,
+			URL:    variant.URL,
+			Width:  variant.Width,
+			Height: variant.Height,
+			Bytes:  int(variant.Bytes),
+		})
+	}
+
+	err = app.inTx(ctx, func(ctx context.Context) error {
+		if err := app.assetModel(ctx).Insert(asset); err != nil {
+			return err
+		}
+		return app.recordAudit(ctx, "asset", "created", asset.ID, nil, asset)
+	})
+	if err != nil {
+		// A concurrent upload of the same file won the race; its row is the canonical copy.
+		if isUniqueViolation(err) {
+			if existing, lookupErr := saver.GetByContentHash(contentHash); lookupErr == nil {
+				if app.logger != nil {
+					app.logger.InfoContext(ctx, "asset was uploaded concurrently, discarding duplicate", "publicId", existing.PublicID, "duplicateId", asset.PublicID)
+				}
+				if direct, ok := app.assets.(assets.DirectUploader); ok {
+					app.discardDirectUpload(ctx, direct, assets.DirectUpload{
+						Key:          result.PublicID,
+						ResourceType: result.ResourceType,
+						Variants:     result.Variants,
+					})
+				}
+				return existing, http.StatusOK, nil
+			}
 		}
+
+		return nil, http.StatusInternalServerError, fmt.Errorf("persist asset: %w", err)
+	}
+
+	app.publishEvent(ctx, "asset", "created", asset.ID, asset)
+	return asset, http.StatusCreated, nil
+}
+
+// writeIngestError reports a failed ingestAsset. Validation failures are explained to the
+// client; anything else is logged and answered with the generic status text.
+func (app *application) writeIngestError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if status == http.StatusUnsupportedMediaType || status == http.StatusUnprocessableEntity {
+		app.writeErrorMessage(w, status, err.Error())
+		return
+	}
+
+	if app.logger != nil {
+		app.logger.ErrorContext(r.Context(), "ingest asset", "error", err)
+	}
+	app.writeError(w, status)
+}
+
+func (app *application) getAssetDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	groups, err := app.getModels(r).Assets.GetDuplicateGroups()
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Error retrieving duplicate assets", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
-	app.writeJSON(w, http.StatusCreated, envelope{"asset": asset})
-	return
+	app.writeJSON(w, http.StatusOK, envelope{"duplicates": groups})
 }
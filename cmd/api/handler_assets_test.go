@@ -3,10 +3,14 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"image"
+	"image/png"
 	"io"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -15,14 +19,20 @@ import (
 
 	"api.etin.dev/internal/assets"
 	"api.etin.dev/internal/data"
+	"github.com/lib/pq"
 )
 
 type stubUploader struct {
-	result *assets.UploadResult
-	err    error
+	result  *assets.UploadResult
+	err     error
+	options assets.UploadOptions
+	calls   int
 }
 
-func (s *stubUploader) Upload(_ context.Context, file io.Reader, _ assets.UploadOptions) (*assets.UploadResult, error) {
+func (s *stubUploader) Upload(_ context.Context, file io.Reader, options assets.UploadOptions) (*assets.UploadResult, error) {
+	s.options = options
+	s.calls++
+
 	if s.err != nil {
 		return nil, s.err
 	}
@@ -57,23 +67,58 @@ func (s *stubAssetSaver) Insert(asset *data.Asset) error {
 	return nil
 }
 
+func (s *stubAssetSaver) GetByContentHash(hash string) (*data.Asset, error) {
+	for _, asset := range s.saved {
+		if asset.ContentHash == hash {
+			copy := *asset
+			return &copy, nil
+		}
+	}
+
+	return nil, errors.New("record not found")
+}
+
+// racingAssetSaver behaves as if another upload of the same file is recorded between the
+// duplicate check and the insert.
+type racingAssetSaver struct {
+	winner  *data.Asset
+	lookups int
+}
+
+func (s *racingAssetSaver) Insert(*data.Asset) error {
+	return &pq.Error{Code: "23505"}
+}
+
+func (s *racingAssetSaver) GetByContentHash(string) (*data.Asset, error) {
+	s.lookups++
+	if s.lookups == 1 {
+		return nil, errors.New("record not found")
+	}
+	return s.winner, nil
+}
+
 func newAuthenticatedApp(t *testing.T, uploader assets.Uploader, saver assetSaver) (*application, string) {
 	t.Helper()
 
 	sm := newSessionManager(time.Hour)
-	token, expiry, err := sm.create()
+	token, expiry, err := sm.create("admin@example.com")
 	if err != nil {
 		t.Fatalf("failed to seed session token: %v", err)
 	}
 
-	sm.sessions[token] = expiry.Add(time.Hour)
+	sm.sessions[token] = adminSession{email: "admin@example.com", expiry: expiry.Add(time.Hour)}
 
 	app := &application{
-		logger:     log.New(io.Discard, "", 0),
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
 		assets:     uploader,
-		assetModel: saver,
+		assetModel: func(context.Context) assetSaver { return saver },
 		sessions:   sm,
 	}
+	app.config.uploadPolicy = assets.ValidationPolicy{
+		AllowedTypes: assets.ParseAllowedTypes(assets.DefaultAllowedTypes),
+		MaxWidth:     assets.DefaultMaxDimension,
+		MaxHeight:    assets.DefaultMaxDimension,
+	}
 
 	return app, token
 }
@@ -81,11 +126,17 @@ func newAuthenticatedApp(t *testing.T, uploader assets.Uploader, saver assetSave
 func createMultipartRequest(t *testing.T, token string, payload []byte) (*http.Request, *httptest.ResponseRecorder) {
 	t.Helper()
 
+	return createMultipartFileRequest(t, token, "upload.png", payload)
+}
+
+func createMultipartFileRequest(t *testing.T, token, filename string, payload []byte) (*http.Request, *httptest.ResponseRecorder) {
+	t.Helper()
+
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	if payload != nil {
-		part, err := writer.CreateFormFile("file", "test.bin")
+		part, err := writer.CreateFormFile("file", filename)
 		if err != nil {
 			t.Fatalf("create form file: %v", err)
 		}
@@ -106,6 +157,17 @@ func createMultipartRequest(t *testing.T, token string, payload []byte) (*http.R
 	return req, rr
 }
 
+func pngPayload(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
 func TestGetCreateAssetsHandler_Success(t *testing.T) {
 	uploader := &stubUploader{result: &assets.UploadResult{
 		AssetID:      "asset123",
@@ -124,7 +186,7 @@ func TestGetCreateAssetsHandler_Success(t *testing.T) {
 
 	app, token := newAuthenticatedApp(t, uploader, saver)
 
-	req, rr := createMultipartRequest(t, token, []byte("hello world"))
+	req, rr := createMultipartRequest(t, token, pngPayload(t, 10, 10))
 
 	app.getCreateAssetsHandler(rr, req)
 
@@ -153,6 +215,93 @@ func TestGetCreateAssetsHandler_Success(t *testing.T) {
 	}
 }
 
+func TestGetCreateAssetsHandler_BindsSaverToRequestContext(t *testing.T) {
+	saver := &stubAssetSaver{}
+	app, token := newAuthenticatedApp(t, &stubUploader{}, saver)
+
+	var bound []context.Context
+	app.assetModel = func(ctx context.Context) assetSaver {
+		bound = append(bound, ctx)
+		return saver
+	}
+
+	req, rr := createMultipartRequest(t, token, pngPayload(t, 10, 10))
+	req = req.WithContext(context.WithValue(req.Context(), requestIdKey, "req-7"))
+	app.getCreateAssetsHandler(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d; got %d", http.StatusCreated, rr.Code)
+	}
+	if len(bound) == 0 {
+		t.Fatal("expected the saver to be bound to the request")
+	}
+	for _, ctx := range bound {
+		if ctx.Value(requestIdKey) != "req-7" {
+			t.Fatalf("expected the saver to run with the request context")
+		}
+	}
+}
+
+func TestGetCreateAssetsHandler_DiscardsLoserOfConcurrentUpload(t *testing.T) {
+	uploader := &stubDirectUploader{stubUploader: stubUploader{result: &assets.UploadResult{
+		PublicID:     "portfolio/copy",
+		ResourceType: "image",
+		Variants:     []assets.VariantResult{{Name: "thumb", Format: "png", Bytes: 10}},
+	}}}
+	saver := &racingAssetSaver{winner: &data.Asset{ID: 3, PublicID: "portfolio/original"}}
+	app, token := newAuthenticatedApp(t, uploader, saver)
+
+	req, rr := createMultipartRequest(t, token, pngPayload(t, 10, 10))
+	app.getCreateAssetsHandler(rr, req)
+
+	if rr.Code != http.StatusOK || !bytes.Contains(rr.Body.Bytes(), []byte("portfolio/original")) {
+		t.Fatalf("expected the winning asset, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(uploader.deleted) != 1 || uploader.deleted[0] != "portfolio/copy" {
+		t.Fatalf("expected the duplicate to be deleted from storage, got %v", uploader.deleted)
+	}
+}
+
+func TestGetCreateAssetsHandler_PersistsVariants(t *testing.T) {
+	uploader := &stubUploader{result: &assets.UploadResult{
+		PublicID:     "public123",
+		Format:       "png",
+		ResourceType: "image",
+		SecureURL:    "https://example.com/resource.png",
+		Width:        1200,
+		Height:       800,
+		Variants: []assets.VariantResult{
+			{Name: "thumb", Format: "png", URL: "https://example.com/resource_thumb.png", Width: 320, Height: 320, Bytes: 2048},
+		},
+	}}
+
+	saver := &stubAssetSaver{}
+
+	app, token := newAuthenticatedApp(t, uploader, saver)
+	app.config.assetVariants = []assets.Variant{{Name: "thumb", Width: 320, Height: 320, Crop: true}}
+
+	req, rr := createMultipartRequest(t, token, pngPayload(t, 10, 10))
+
+	app.getCreateAssetsHandler(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d; got %d", http.StatusCreated, rr.Code)
+	}
+
+	if len(uploader.options.Variants) != 1 || uploader.options.Variants[0].Name != "thumb" {
+		t.Fatalf("expected configured variants to be passed to the uploader, got %+v", uploader.options.Variants)
+	}
+
+	if len(saver.saved) != 1 || len(saver.saved[0].Variants) != 1 {
+		t.Fatalf("expected variant to be persisted with the asset")
+	}
+
+	variant := saver.saved[0].Variants[0]
+	if variant.URL != "https://example.com/resource_thumb.png" || variant.Width != 320 || variant.Bytes != 2048 {
+		t.Fatalf("unexpected persisted variant %+v", variant)
+	}
+}
+
 func TestGetCreateAssetsHandler_MissingFile(t *testing.T) {
 	uploader := &stubUploader{result: &assets.UploadResult{}}
 	saver := &stubAssetSaver{}
@@ -196,7 +345,7 @@ func TestGetCreateAssetsHandler_UploadFailure(t *testing.T) {
 	saver := &stubAssetSaver{}
 	app, token := newAuthenticatedApp(t, uploader, saver)
 
-	req, rr := createMultipartRequest(t, token, []byte("hello world"))
+	req, rr := createMultipartRequest(t, token, pngPayload(t, 10, 10))
 
 	app.getCreateAssetsHandler(rr, req)
 
@@ -208,3 +357,85 @@ func TestGetCreateAssetsHandler_UploadFailure(t *testing.T) {
 		t.Fatalf("expected no assets to be saved")
 	}
 }
+
+func TestGetCreateAssetsHandler_RejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		payload  []byte
+		status   int
+	}{
+		{"unsupported type", "notes.txt", []byte("hello world"), http.StatusUnsupportedMediaType},
+		{"html disguised as image", "banner.png", []byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType},
+		{"mismatched extension", "banner.gif", pngPayload(t, 10, 10), http.StatusUnprocessableEntity},
+		{"dimensions too large", "banner.png", pngPayload(t, 60, 10), http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader := &stubUploader{}
+			saver := &stubAssetSaver{}
+			app, token := newAuthenticatedApp(t, uploader, saver)
+			app.config.uploadPolicy.MaxWidth = 50
+
+			req, rr := createMultipartFileRequest(t, token, tt.filename, tt.payload)
+
+			app.getCreateAssetsHandler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d; got %d (%s)", tt.status, rr.Code, rr.Body.String())
+			}
+
+			if uploader.calls != 0 || len(saver.saved) != 0 {
+				t.Fatalf("expected rejected file not to reach the upload backend")
+			}
+		})
+	}
+}
+
+func TestGetCreateAssetsHandler_ReturnsExistingAssetForDuplicateContent(t *testing.T) {
+	uploader := &stubUploader{result: &assets.UploadResult{
+		PublicID:     "public123",
+		Format:       "png",
+		ResourceType: "image",
+		SecureURL:    "https://example.com/resource.png",
+	}}
+	saver := &stubAssetSaver{}
+	app, token := newAuthenticatedApp(t, uploader, saver)
+
+	payload := pngPayload(t, 10, 10)
+	expectedHash := sha256.Sum256(payload)
+
+	req, rr := createMultipartRequest(t, token, payload)
+	app.getCreateAssetsHandler(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d; got %d", http.StatusCreated, rr.Code)
+	}
+
+	if saver.saved[0].ContentHash != hex.EncodeToString(expectedHash[:]) {
+		t.Fatalf("expected content hash to be stored, got %q", saver.saved[0].ContentHash)
+	}
+
+	req, rr = createMultipartFileRequest(t, token, "copy.png", payload)
+	app.getCreateAssetsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d for duplicate upload; got %d", http.StatusOK, rr.Code)
+	}
+
+	if uploader.calls != 1 || len(saver.saved) != 1 {
+		t.Fatalf("expected duplicate not to be uploaded or saved again, got %d uploads and %d rows", uploader.calls, len(saver.saved))
+	}
+
+	var response struct {
+		Asset data.Asset `json:"asset"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if response.Asset.ID != saver.saved[0].ID {
+		t.Fatalf("expected existing asset %d, got %d", saver.saved[0].ID, response.Asset.ID)
+	}
+}
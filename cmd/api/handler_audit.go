@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"api.etin.dev/internal/data"
+)
+
+// getAuditEventsHandler lists audit events newest first. from and to are RFC 3339 timestamps
+// bounding occurredAt; from is inclusive and to exclusive.
+func (app *application) getAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	filters := data.AuditEventFilters{CursorFilters: data.CursorFilters{Limit: 50}}
+
+	qs := r.URL.Query()
+	if cursor := qs.Get("cursor"); cursor != "" {
+		if _, err := strconv.ParseInt(cursor, 10, 64); err != nil {
+			app.writeError(w, http.StatusBadRequest)
+			return
+		}
+		filters.Cursor = cursor
+	}
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err == nil && l > 0 {
+			filters.Limit = min(l, 200)
+		}
+	}
+
+	filters.Resource = qs.Get("resource")
+	filters.Actor = qs.Get("actor")
+
+	if resourceID := qs.Get("resourceId"); resourceID != "" {
+		id, err := strconv.ParseInt(resourceID, 10, 64)
+		if err != nil || id < 1 {
+			app.writeError(w, http.StatusBadRequest)
+			return
+		}
+		filters.ResourceID = id
+	}
+
+	for param, dest := range map[string]*time.Time{"from": &filters.From, "to": &filters.To} {
+		value := qs.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			app.writeErrorMessage(w, http.StatusBadRequest, param+" must be an RFC 3339 timestamp")
+			return
+		}
+		*dest = t
+	}
+
+	events, metadata, err := app.getModels(r).AuditEvents.GetAll(filters)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not list audit events", "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"events": events, "metadata": metadata})
+}
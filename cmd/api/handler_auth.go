@@ -13,7 +13,7 @@ func (app *application) adminLoginHandler(w http.ResponseWriter, r *http.Request
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("admin login: could not parse credentials: %v", err)
+		app.logger.WarnContext(r.Context(), "admin login: could not parse credentials", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -26,12 +26,13 @@ func (app *application) adminLoginHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	token, expiresAt, err := app.sessions.create()
+	token, expiresAt, err := app.sessions.create(email)
 	if err != nil {
-		app.logger.Printf("admin login: could not create session: %v", err)
+		app.logger.ErrorContext(r.Context(), "admin login: could not create session", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
+	app.metrics.observeSessionCreated()
 
 	app.writeJSON(w, http.StatusOK, envelope{
 		"token":     token,
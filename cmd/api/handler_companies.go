@@ -2,6 +2,7 @@ package main
 
 import (
 	"api.etin.dev/internal/data"
+	"errors"
 	"net/http"
 	"strconv"
 )
@@ -9,7 +10,7 @@ import (
 func (app *application) getCompaniesHandler(w http.ResponseWriter, r *http.Request) {
 	companies, err := app.getModels(r).Companies.GetAll()
 	if err != nil {
-		app.logger.Printf("Error getting all companies, error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error getting all companies", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -32,7 +33,7 @@ func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Requ
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Print(err)
+		app.logger.WarnContext(r.Context(), "Could not parse input", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -43,12 +44,22 @@ func (app *application) createCompanyHandler(w http.ResponseWriter, r *http.Requ
 		Description: &input.Description,
 	}
 
-	err = app.getModels(r).Companies.Insert(company)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Companies.Insert(company); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "company", "created", company.ID, nil, company)
+	})
 	if err != nil {
-		app.logger.Print(err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not create company", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
+	app.publishEvent(r.Context(), "company", "created", company.ID, company)
 	app.writeJSON(w, http.StatusOK, envelope{"company": company})
 	return
 }
@@ -61,7 +72,7 @@ func (app *application) getCompanyHandler(w http.ResponseWriter, r *http.Request
 	}
 	company, err := app.getModels(r).Companies.Get(id)
 	if err != nil {
-		app.logger.Printf("Error getting company with ID: %d, error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Error getting company", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -83,11 +94,13 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 
 	company, err := app.getModels(r).Companies.Get(id)
 	if err != nil {
-		app.logger.Printf("Error getting company with ID: %d, error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Error getting company", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
+	before := *company
+
 	var input struct {
 		Name        *string `json:"name"`
 		Icon        *string `json:"icon"`
@@ -96,7 +109,7 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Could not parse input. Error: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse input", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -113,12 +126,22 @@ func (app *application) updateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		company.Description = input.Description
 	}
 
-	err = app.getModels(r).Companies.Update(company)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Companies.Update(company); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "company", "updated", company.ID, before, company)
+	})
 	if err != nil {
-		app.logger.Printf("Could not update company with ID: %d. Error: %s", id, err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not update company", "id", id, "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
+	app.publishEvent(r.Context(), "company", "updated", company.ID, company)
 	app.writeJSON(w, http.StatusAccepted, envelope{"company": company})
 	return
 }
@@ -136,16 +159,26 @@ func (app *application) deleteCompanyHandler(w http.ResponseWriter, r *http.Requ
 
 	company, err := app.getModels(r).Companies.Get(id)
 	if err != nil {
-		app.logger.Printf("Error getting company with ID: %d, error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Error getting company", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
-	err = app.getModels(r).Companies.Delete(company)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Companies.Delete(company); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "company", "deleted", company.ID, company, nil)
+	})
 	if err != nil {
-		app.logger.Printf("Could not delete company with ID: %d. Error: %s", id, err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not delete company", "id", id, "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
+	app.publishEvent(r.Context(), "company", "deleted", company.ID, company)
 	app.writeJSON(w, http.StatusNoContent, nil)
 	return
 }
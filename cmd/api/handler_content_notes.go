@@ -40,7 +40,7 @@ func (app *application) getCreateContentNoteHandler(w http.ResponseWriter, r *ht
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
-		app.logger.Printf("Could not parse content note payload: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse content note payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -60,36 +60,46 @@ func (app *application) getCreateContentNoteHandler(w http.ResponseWriter, r *ht
 		note.PublishedAt = &t
 	}
 
-	// Create the note
-	if err := app.getModels(r).Notes.Insert(note); err != nil {
-		app.logger.Printf("Could not create note: %s", err)
-		app.writeError(w, http.StatusInternalServerError)
-		return
-	}
-
-	// Link the note to the item
 	itemNote := &data.ItemNote{
-		NoteID:   note.ID,
 		ItemID:   itemID,
 		ItemType: string(itemType),
 	}
 
-	if err := app.getModels(r).ItemNotes.Insert(itemNote); err != nil {
-		// Rollback note creation? Or just log?
-		// Since we don't have transaction helper readily available across models without passing DB,
-		// we should probably try to delete the note.
-		_ = app.getModels(r).Notes.Delete(note.ID)
+	// Create the note and link it to the item together, so a failed link leaves no orphaned note.
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Notes.Insert(note); err != nil {
+			return err
+		}
+
+		itemNote.NoteID = note.ID
+		if err := tx.ItemNotes.Insert(itemNote); err != nil {
+			return err
+		}
 
+		if err := app.recordAudit(tx.Context(), "note", "created", note.ID, nil, note); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "item_note", "created", itemNote.ID, nil, itemNote)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		if errors.Is(err, data.ErrInvalidItemType) {
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
 
-		app.logger.Printf("Could not create item note association: %s", err)
+		app.logger.ErrorContext(r.Context(), "Could not create note", "itemType", itemType, "itemId", itemID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
+	app.publishEvent(r.Context(), "note", "created", note.ID, note)
+	if isPublished(note.PublishedAt) {
+		app.publishEvent(r.Context(), "note", "published", note.ID, note)
+	}
 	app.writeJSON(w, http.StatusCreated, envelope{"note": note})
 }
 
@@ -136,7 +146,7 @@ func (app *application) getContentNotesHandler(w http.ResponseWriter, r *http.Re
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
-		app.logger.Printf("Error fetching notes for item: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching notes for item", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -192,7 +202,7 @@ func (app *application) getAllContentNotesHandler(w http.ResponseWriter, r *http
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
-		app.logger.Printf("Error fetching notes for content type: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching notes for content type", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
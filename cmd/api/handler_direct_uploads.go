@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"api.etin.dev/internal/assets"
+)
+
+// presignedUploadResponse tells the admin UI how to send a file straight to the storage backend.
+type presignedUploadResponse struct {
+	Key       string            `json:"key"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+func (app *application) presignAssetHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	direct, ok := app.assets.(assets.DirectUploader)
+	if !ok {
+		app.writeErrorMessage(w, http.StatusNotImplemented, "the asset backend does not support direct uploads")
+		return
+	}
+
+	var input struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"contentType"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse presign payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	filename := parseUploadFilename(input.Filename)
+	mediaType, _, err := mime.ParseMediaType(input.ContentType)
+	if filename == "" || err != nil {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// The declared type is only a first filter; the file itself is checked on confirmation.
+	if !app.config.uploadPolicy.Allows(mediaType) {
+		app.writeErrorMessage(w, http.StatusUnsupportedMediaType, fmt.Sprintf("%s: %s is not allowed", assets.ErrUnsupportedType, mediaType))
+		return
+	}
+
+	presigned, err := direct.PresignUpload(r.Context(), assets.PresignOptions{
+		Filename:    filename,
+		ContentType: input.ContentType,
+		Expires:     app.config.uploads.presignExpiry,
+	})
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not presign upload", "error", err)
+		app.writeError(w, http.StatusBadGateway)
+		return
+	}
+
+	app.uploads.expectDirect(presigned.DirectUpload, filename, presigned.ExpiresAt)
+
+	app.writeJSON(w, http.StatusCreated, envelope{"upload": presignedUploadResponse{
+		Key:       presigned.Key,
+		Method:    presigned.Method,
+		URL:       presigned.URL,
+		Headers:   presigned.Headers,
+		Fields:    presigned.Fields,
+		ExpiresAt: presigned.ExpiresAt,
+	}})
+}
+
+func (app *application) confirmAssetHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	direct, ok := app.assets.(assets.DirectUploader)
+	if !ok {
+		app.writeErrorMessage(w, http.StatusNotImplemented, "the asset backend does not support direct uploads")
+		return
+	}
+
+	var input struct {
+		Key string `json:"key"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse confirm payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	pending, err := app.uploads.claimDirect(strings.TrimSpace(input.Key))
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	body, err := direct.FetchUpload(r.Context(), pending.upload)
+	if err != nil {
+		app.uploads.restoreDirect(pending)
+		if errors.Is(err, assets.ErrObjectNotFound) {
+			app.writeErrorMessage(w, http.StatusConflict, "the file has not been uploaded to storage yet")
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not fetch direct upload", "key", pending.upload.Key, "error", err)
+		app.writeError(w, http.StatusBadGateway)
+		return
+	}
+
+	file, err := app.uploads.spool(body)
+	body.Close()
+	if err != nil {
+		if errors.Is(err, errUploadTooLarge) {
+			app.discardDirectUpload(r.Context(), direct, pending.upload)
+			app.writeErrorMessage(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		app.uploads.restoreDirect(pending)
+		app.logger.ErrorContext(r.Context(), "Could not read direct upload", "key", pending.upload.Key, "error", err)
+		app.writeError(w, http.StatusBadGateway)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	asset, status, err := app.ingestAsset(r.Context(), pending.filename, file, func(ctx context.Context, content io.ReadSeeker, probe assets.Probe) (*assets.UploadResult, error) {
+		if probe.Format != "svg" {
+			return direct.DescribeUpload(ctx, pending.upload, content, assets.UploadOptions{Variants: app.config.assetVariants})
+		}
+
+		// Storage holds the document as the client sent it, so the sanitised copy is uploaded
+		// through the API instead and the original removed.
+		result, err := app.storeUpload(ctx, content, probe)
+		if err == nil {
+			app.discardDirectUpload(ctx, direct, pending.upload)
+		}
+		return result, err
+	})
+
+	switch {
+	case err != nil && status >= http.StatusInternalServerError:
+		// The object stays in storage so the confirmation can be retried.
+		app.uploads.restoreDirect(pending)
+	case err != nil, status == http.StatusOK:
+		// Rejected files and copies of existing assets are not kept.
+		app.discardDirectUpload(r.Context(), direct, pending.upload)
+	}
+
+	if err != nil {
+		app.writeIngestError(w, r, status, err)
+		return
+	}
+
+	app.writeJSON(w, status, envelope{"asset": asset})
+}
+
+// discardDirectUpload deletes a stored object that will not become an asset. Failures
+// only leave an orphaned object behind, so they are logged rather than reported.
+func (app *application) discardDirectUpload(ctx context.Context, direct assets.DirectUploader, upload assets.DirectUpload) {
+	if err := direct.DeleteUpload(ctx, upload); err != nil && app.logger != nil {
+		app.logger.ErrorContext(ctx, "Could not delete direct upload", "key", upload.Key, "error", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"api.etin.dev/internal/assets"
+)
+
+// stubDirectUploader keeps directly uploaded objects in memory.
+type stubDirectUploader struct {
+	stubUploader
+	objects   map[string][]byte
+	described int
+	deleted   []string
+}
+
+func (s *stubDirectUploader) PresignUpload(_ context.Context, options assets.PresignOptions) (*assets.PresignedUpload, error) {
+	return &assets.PresignedUpload{
+		DirectUpload: assets.DirectUpload{Key: "direct/" + options.Filename},
+		Method:       http.MethodPut,
+		URL:          "https://storage.example.com/direct/" + options.Filename,
+		Headers:      map[string]string{"Content-Type": options.ContentType},
+		ExpiresAt:    time.Now().Add(options.Expires),
+	}, nil
+}
+
+func (s *stubDirectUploader) FetchUpload(_ context.Context, upload assets.DirectUpload) (io.ReadCloser, error) {
+	object, ok := s.objects[upload.Key]
+	if !ok {
+		return nil, assets.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(object)), nil
+}
+
+func (s *stubDirectUploader) DescribeUpload(_ context.Context, upload assets.DirectUpload, _ io.ReadSeeker, _ assets.UploadOptions) (*assets.UploadResult, error) {
+	s.described++
+	return &assets.UploadResult{PublicID: upload.Key, URL: "https://storage.example.com/" + upload.Key, Format: "png"}, nil
+}
+
+func (s *stubDirectUploader) DeleteUpload(_ context.Context, upload assets.DirectUpload) error {
+	s.deleted = append(s.deleted, upload.Key)
+	delete(s.objects, upload.Key)
+	return nil
+}
+
+func presignDirectUpload(t *testing.T, app *application, token, filename string) {
+	t.Helper()
+
+	body := []byte(`{"filename": "` + filename + `", "contentType": "image/png"}`)
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/assets/presign", body, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+}
+
+func TestDirectUpload_Confirm(t *testing.T) {
+	uploader := &stubDirectUploader{objects: map[string][]byte{}}
+	saver := &stubAssetSaver{nextID: 3}
+	app, token := newUploadApp(t, uploader, saver)
+
+	presignDirectUpload(t, app, token, "banner.png")
+
+	confirm := []byte(`{"key": "direct/banner.png"}`)
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/assets/confirm", confirm, nil)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected confirming a missing object to return %d, got %d", http.StatusConflict, rr.Code)
+	}
+
+	uploader.objects["direct/banner.png"] = pngPayload(t, 16, 9)
+
+	rr = serveUploadRequest(app, token, http.MethodPost, "/v1/assets/confirm", confirm, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	if uploader.described != 1 || uploader.calls != 0 || len(saver.saved) != 1 {
+		t.Fatalf("expected the object to be described rather than uploaded, got %d describes, %d uploads, %d saved", uploader.described, uploader.calls, len(saver.saved))
+	}
+	if saver.saved[0].PublicID != "direct/banner.png" || saver.saved[0].ContentHash == "" {
+		t.Fatalf("unexpected saved asset %+v", saver.saved[0])
+	}
+	if len(uploader.deleted) != 0 {
+		t.Fatalf("expected the confirmed object to be kept, deleted %v", uploader.deleted)
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodPost, "/v1/assets/confirm", confirm, nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected a second confirmation to return %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestDirectUpload_ConfirmDeletesRejectedAndDuplicateObjects(t *testing.T) {
+	uploader := &stubDirectUploader{objects: map[string][]byte{}}
+	saver := &stubAssetSaver{nextID: 3}
+	app, token := newUploadApp(t, uploader, saver)
+
+	presignDirectUpload(t, app, token, "fake.png")
+	uploader.objects["direct/fake.png"] = []byte("<html><script>alert(1)</script></html>")
+
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/assets/confirm", []byte(`{"key": "direct/fake.png"}`), nil)
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status %d, got %d", http.StatusUnsupportedMediaType, rr.Code)
+	}
+
+	payload := pngPayload(t, 8, 8)
+	presignDirectUpload(t, app, token, "first.png")
+	presignDirectUpload(t, app, token, "second.png")
+	uploader.objects["direct/first.png"] = payload
+	uploader.objects["direct/second.png"] = payload
+
+	serveUploadRequest(app, token, http.MethodPost, "/v1/assets/confirm", []byte(`{"key": "direct/first.png"}`), nil)
+	rr = serveUploadRequest(app, token, http.MethodPost, "/v1/assets/confirm", []byte(`{"key": "direct/second.png"}`), nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected a duplicate to return %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	expected := []string{"direct/fake.png", "direct/second.png"}
+	if len(uploader.deleted) != len(expected) || uploader.deleted[0] != expected[0] || uploader.deleted[1] != expected[1] {
+		t.Fatalf("expected %v to be deleted, got %v", expected, uploader.deleted)
+	}
+}
+
+func TestDirectUpload_AbandonedUploadsAreDeleted(t *testing.T) {
+	uploader := &stubDirectUploader{objects: map[string][]byte{}}
+	app, token := newUploadApp(t, uploader, &stubAssetSaver{})
+	app.config.uploads.presignExpiry = 10 * time.Minute
+
+	presignDirectUpload(t, app, token, "banner.png")
+	uploader.objects["direct/banner.png"] = pngPayload(t, 16, 9)
+
+	// Still within the grace period after the signed parameters expired.
+	app.uploads.now = func() time.Time { return time.Now().Add(20 * time.Minute) }
+	if removed, abandoned := app.uploads.expire(); removed != 0 || len(abandoned) != 0 {
+		t.Fatalf("expected the upload to still be confirmable, got %d removed", removed)
+	}
+
+	// Well before the chunked session TTL, which no longer applies to direct uploads.
+	app.uploads.now = func() time.Time { return time.Now().Add(30 * time.Minute) }
+	removed, abandoned := app.uploads.expire()
+	if removed != 1 || len(abandoned) != 1 || abandoned[0].Key != "direct/banner.png" {
+		t.Fatalf("expected the abandoned upload to expire, got %d removed, %v", removed, abandoned)
+	}
+
+	app.uploads.discardAbandoned(abandoned, uploader.DeleteUpload, t.Logf)
+	if _, ok := uploader.objects["direct/banner.png"]; ok || len(uploader.deleted) != 1 {
+		t.Fatalf("expected the abandoned object to be deleted, got %v", uploader.deleted)
+	}
+}
+
+func TestDirectUpload_PresignRequiresSupportingBackend(t *testing.T) {
+	app, token := newUploadApp(t, &stubUploader{}, &stubAssetSaver{})
+
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/assets/presign", []byte(`{"filename": "a.png", "contentType": "image/png"}`), nil)
+	if rr.Code != http.StatusNotImplemented {
+		t.Fatalf("expected status %d, got %d", http.StatusNotImplemented, rr.Code)
+	}
+}
+
+func TestDirectUpload_PresignRejectsDisallowedTypes(t *testing.T) {
+	app, token := newUploadApp(t, &stubDirectUploader{}, &stubAssetSaver{})
+
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/assets/presign", []byte(`{"filename": "a.exe", "contentType": "application/x-msdownload"}`), nil)
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status %d, got %d", http.StatusUnsupportedMediaType, rr.Code)
+	}
+}
@@ -12,7 +12,7 @@ import (
 func (app *application) getItemNotesHandler(w http.ResponseWriter, r *http.Request) {
 	itemNotes, err := app.getModels(r).ItemNotes.GetAll()
 	if err != nil {
-		app.logger.Printf("Error retrieving item note associations: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving item note associations", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -33,7 +33,7 @@ func (app *application) createItemNoteHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
-		app.logger.Printf("Could not parse item note association payload: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse item note association payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -49,13 +49,23 @@ func (app *application) createItemNoteHandler(w http.ResponseWriter, r *http.Req
 		ItemType: strings.ToLower(input.ItemType),
 	}
 
-	if err := app.getModels(r).ItemNotes.Insert(itemNote); err != nil {
+	err := app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.ItemNotes.Insert(itemNote); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "item_note", "created", itemNote.ID, nil, itemNote)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		if errors.Is(err, data.ErrInvalidItemType) {
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
 
-		app.logger.Printf("Could not create item note association: %s", err)
+		app.logger.ErrorContext(r.Context(), "Could not create item note association", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -72,7 +82,7 @@ func (app *application) getItemNoteHandler(w http.ResponseWriter, r *http.Reques
 
 	itemNote, err := app.getModels(r).ItemNotes.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve item note association %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve item note association", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -94,11 +104,13 @@ func (app *application) updateItemNoteHandler(w http.ResponseWriter, r *http.Req
 
 	itemNote, err := app.getModels(r).ItemNotes.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve item note association %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve item note association", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
 
+	before := *itemNote
+
 	var input struct {
 		NoteID   *int64  `json:"noteId"`
 		ItemID   *int64  `json:"itemId"`
@@ -106,7 +118,7 @@ func (app *application) updateItemNoteHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
-		app.logger.Printf("Could not parse item note association update payload: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse item note association update payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -123,13 +135,23 @@ func (app *application) updateItemNoteHandler(w http.ResponseWriter, r *http.Req
 		itemNote.ItemType = strings.ToLower(*input.ItemType)
 	}
 
-	if err := app.getModels(r).ItemNotes.Update(itemNote); err != nil {
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.ItemNotes.Update(itemNote); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "item_note", "updated", itemNote.ID, before, itemNote)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		if errors.Is(err, data.ErrInvalidItemType) {
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
 
-		app.logger.Printf("Could not update item note association %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not update item note association", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -149,8 +171,25 @@ func (app *application) deleteItemNoteHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if err := app.getModels(r).ItemNotes.Delete(id); err != nil {
-		app.logger.Printf("Could not delete item note association %d: %s", id, err)
+	itemNote, err := app.getModels(r).ItemNotes.Get(id)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not retrieve item note association", "id", id, "error", err)
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.ItemNotes.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "item_note", "deleted", id, itemNote, nil)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not delete item note association", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -201,7 +240,7 @@ func (app *application) getNotesForItemHandler(w http.ResponseWriter, r *http.Re
 			return
 		}
 
-		app.logger.Printf("Could not retrieve notes for %s item %d: %s", itemTypeStr, itemID, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve notes for item", "itemType", itemTypeStr, "itemId", itemID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,7 +12,7 @@ import (
 func (app *application) getNotesHandler(w http.ResponseWriter, r *http.Request) {
 	notes, err := app.getModels(r).Notes.GetAll()
 	if err != nil {
-		app.logger.Printf("Error retrieving notes: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving notes", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -34,7 +35,7 @@ func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Could not parse request body: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse request body", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -57,13 +58,26 @@ func (app *application) createNoteHandler(w http.ResponseWriter, r *http.Request
 		PublishedAt: publishedAt,
 	}
 
-	err = app.getModels(r).Notes.Insert(note)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Notes.Insert(note); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "note", "created", note.ID, nil, note)
+	})
 	if err != nil {
-		app.logger.Printf("Could not create note: %s", err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not create note", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
 
+	app.publishEvent(r.Context(), "note", "created", note.ID, note)
+	if isPublished(note.PublishedAt) {
+		app.publishEvent(r.Context(), "note", "published", note.ID, note)
+	}
 	app.writeJSON(w, http.StatusCreated, envelope{"note": note})
 }
 
@@ -76,7 +90,7 @@ func (app *application) getNoteHandler(w http.ResponseWriter, r *http.Request) {
 
 	note, err := app.getModels(r).Notes.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve note %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve note", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -98,11 +112,13 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 
 	note, err := app.getModels(r).Notes.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve note %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve note", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
 
+	before := *note
+
 	var input struct {
 		Title       *string    `json:"title"`
 		Subtitle    *string    `json:"subtitle"`
@@ -112,11 +128,13 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Could not parse request body: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse request body", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
 
+	wasPublished := isPublished(note.PublishedAt)
+
 	if input.Title != nil {
 		note.Title = *input.Title
 	}
@@ -134,13 +152,26 @@ func (app *application) updateNoteHandler(w http.ResponseWriter, r *http.Request
 		note.PublishedAt = &t
 	}
 
-	err = app.getModels(r).Notes.Update(note)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Notes.Update(note); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "note", "updated", note.ID, before, note)
+	})
 	if err != nil {
-		app.logger.Printf("Could not update note %d: %s", id, err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not update note", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
+	app.publishEvent(r.Context(), "note", "updated", note.ID, note)
+	if !wasPublished && isPublished(note.PublishedAt) {
+		app.publishEvent(r.Context(), "note", "published", note.ID, note)
+	}
 	app.writeJSON(w, http.StatusOK, envelope{"note": note})
 }
 
@@ -156,12 +187,35 @@ func (app *application) deleteNoteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.getModels(r).Notes.Delete(id)
+	note, err := app.getModels(r).Notes.Get(id)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Notes.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "note", "deleted", id, note, nil)
+	})
 	if err != nil {
-		app.logger.Printf("Could not delete note %d: %s", id, err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not delete note", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
 
+	app.publishEvent(r.Context(), "note", "deleted", id, note)
 	app.writeJSON(w, http.StatusNoContent, envelope{"note": nil})
 }
+
+// isPublished reports whether a note with the given publication date is visible publicly.
+// Notes scheduled for later are published by the clock rather than by a request, so no event
+// is sent when they go live.
+func isPublished(publishedAt *time.Time) bool {
+	return publishedAt != nil && !publishedAt.After(time.Now())
+}
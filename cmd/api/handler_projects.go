@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,7 +12,7 @@ import (
 func (app *application) getProjectsHandler(w http.ResponseWriter, r *http.Request) {
 	projects, err := app.getModels(r).Projects.GetAll()
 	if err != nil {
-		app.logger.Printf("Error retrieving projects. Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving projects", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +36,7 @@ func (app *application) createProjectHandler(w http.ResponseWriter, r *http.Requ
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Error parsing project payload. Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error parsing project payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -48,13 +49,23 @@ func (app *application) createProjectHandler(w http.ResponseWriter, r *http.Requ
 		ImageURL:    input.ImageURL,
 	}
 
-	err = app.getModels(r).Projects.Insert(project)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Projects.Insert(project); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "project", "created", project.ID, nil, project)
+	})
 	if err != nil {
-		app.logger.Printf("Error creating project. Error: %s", err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Error creating project", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
 
+	app.publishEvent(r.Context(), "project", "created", project.ID, project)
 	app.writeJSON(w, http.StatusCreated, envelope{"project": project})
 }
 
@@ -67,7 +78,7 @@ func (app *application) getProjectHandler(w http.ResponseWriter, r *http.Request
 
 	project, err := app.getModels(r).Projects.Get(id)
 	if err != nil {
-		app.logger.Printf("Error retrieving project with ID %d. Error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving project", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -89,11 +100,13 @@ func (app *application) updateProjectHandler(w http.ResponseWriter, r *http.Requ
 
 	project, err := app.getModels(r).Projects.Get(id)
 	if err != nil {
-		app.logger.Printf("Error retrieving project with ID %d for update. Error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving project for update", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
 
+	before := *project
+
 	var input struct {
 		StartDate   *time.Time `json:"startDate"`
 		EndDate     *time.Time `json:"endDate"`
@@ -104,7 +117,7 @@ func (app *application) updateProjectHandler(w http.ResponseWriter, r *http.Requ
 
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Error parsing project update payload. Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error parsing project update payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -129,13 +142,23 @@ func (app *application) updateProjectHandler(w http.ResponseWriter, r *http.Requ
 		project.ImageURL = input.ImageURL
 	}
 
-	err = app.getModels(r).Projects.Update(project)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Projects.Update(project); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "project", "updated", project.ID, before, project)
+	})
 	if err != nil {
-		app.logger.Printf("Error updating project with ID %d. Error: %s", id, err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Error updating project", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
+	app.publishEvent(r.Context(), "project", "updated", project.ID, project)
 	app.writeJSON(w, http.StatusOK, envelope{"project": project})
 }
 
@@ -151,12 +174,29 @@ func (app *application) deleteProjectHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.getModels(r).Projects.Delete(id)
+	project, err := app.getModels(r).Projects.Get(id)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Projects.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "project", "deleted", id, project, nil)
+	})
 	if err != nil {
-		app.logger.Printf("Error deleting project with ID %d. Error: %s", id, err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Error deleting project", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
 
+	app.publishEvent(r.Context(), "project", "deleted", id, project)
+
 	app.writeJSON(w, http.StatusNoContent, envelope{"project": nil})
 }
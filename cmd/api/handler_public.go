@@ -2,8 +2,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,47 +29,79 @@ type publicRelatedItem struct {
 }
 
 type publicNote struct {
-	ID           int64               `json:"id"`
-	PublishedAt  string              `json:"publishedAt"`
-	Title        string              `json:"title"`
-	Slug         string              `json:"slug"`
-	Preview      string              `json:"preview"`
-	Body         string              `json:"body"`
-	IsFeatured   bool                `json:"isFeatured"`
-	Tags         []publicTag         `json:"tags"`
-	RelatedItems []publicRelatedItem `json:"relatedItems,omitempty"`
-	RelatedNotes []publicNote        `json:"relatedNotes,omitempty"`
+	ID           int64                `json:"id"`
+	PublishedAt  string               `json:"publishedAt"`
+	Title        string               `json:"title"`
+	Slug         string               `json:"slug"`
+	Preview      string               `json:"preview"`
+	Body         string               `json:"body"`
+	IsFeatured   bool                 `json:"isFeatured"`
+	Tags         []publicTag          `json:"tags"`
+	RelatedItems []publicRelatedItem  `json:"relatedItems,omitempty"`
+	RelatedNotes []publicNote         `json:"relatedNotes,omitempty"`
+	Images       []publicImage        `json:"images,omitempty"`
+	Gallery      []publicGalleryImage `json:"gallery,omitempty"`
 }
 
 type publicProject struct {
-	ID           int64        `json:"id"`
-	StartDate    string       `json:"startDate"`
-	EndDate      *string      `json:"endDate"`
-	Title        string       `json:"title"`
-	Image        string       `json:"image"`
-	Slug         string       `json:"slug"`
-	Status       *publicTag   `json:"status"`
-	Description  string       `json:"description"`
-	Technologies []string     `json:"technologies"`
-	Notes        []publicNote `json:"notes"`
+	ID           int64                `json:"id"`
+	StartDate    string               `json:"startDate"`
+	EndDate      *string              `json:"endDate"`
+	Title        string               `json:"title"`
+	Image        string               `json:"image"`
+	ImageSet     *publicImage         `json:"imageSet,omitempty"`
+	Gallery      []publicGalleryImage `json:"gallery,omitempty"`
+	Slug         string               `json:"slug"`
+	Status       *publicTag           `json:"status"`
+	Description  string               `json:"description"`
+	Technologies []string             `json:"technologies"`
+	Notes        []publicNote         `json:"notes"`
 }
 
 type publicRole struct {
-	RoleID      int64        `json:"roleId"`
-	StartDate   string       `json:"startDate"`
-	EndDate     *string      `json:"endDate"`
-	Title       string       `json:"title"`
-	Subtitle    *string      `json:"subtitle"`
-	Company     string       `json:"company"`
-	CompanyIcon string       `json:"companyIcon"`
-	Slug        string       `json:"slug"`
-	Description string       `json:"description"`
-	Skills      []string     `json:"skills"`
-	Notes       []publicNote `json:"notes"`
+	RoleID      int64                `json:"roleId"`
+	StartDate   string               `json:"startDate"`
+	EndDate     *string              `json:"endDate"`
+	Title       string               `json:"title"`
+	Subtitle    *string              `json:"subtitle"`
+	Company     string               `json:"company"`
+	CompanyIcon string               `json:"companyIcon"`
+	Slug        string               `json:"slug"`
+	Description string               `json:"description"`
+	Skills      []string             `json:"skills"`
+	Notes       []publicNote         `json:"notes"`
+	Gallery     []publicGalleryImage `json:"gallery,omitempty"`
+}
+
+// publicImage describes an uploaded image along with its variants. Srcset holds a ready-made
+// srcset attribute value for each available format.
+type publicImage struct {
+	URL      string               `json:"url"`
+	Width    int                  `json:"width"`
+	Height   int                  `json:"height"`
+	Variants []publicImageVariant `json:"variants"`
+	Srcset   map[string]string    `json:"srcset"`
+}
+
+// publicGalleryImage is an image attached to a note, project or role gallery.
+type publicGalleryImage struct {
+	publicImage
+	Caption string `json:"caption"`
+	AltText string `json:"altText"`
+}
+
+type publicImageVariant struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
 }
 
 var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
 
+var markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+
 func (app *application) fetchRelatedItems(r *http.Request, notes []*data.Note) (map[int64][]publicRelatedItem, error) {
 	if len(notes) == 0 {
 		return make(map[int64][]publicRelatedItem), nil
@@ -153,14 +187,14 @@ func (app *application) fetchRelatedItems(r *http.Request, notes []*data.Note) (
 func (app *application) getPublicNotesHandler(w http.ResponseWriter, r *http.Request) {
 	notes, err := app.getModels(r).Notes.GetAllPublished()
 	if err != nil {
-		app.logger.Printf("Error retrieving notes: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving notes", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 	if err != nil {
-		app.logger.Printf("Error fetching related items: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -170,7 +204,7 @@ func (app *application) getPublicNotesHandler(w http.ResponseWriter, r *http.Req
 	for _, note := range notes {
 		tags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 		if err != nil {
-			app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -179,13 +213,24 @@ func (app *application) getPublicNotesHandler(w http.ResponseWriter, r *http.Req
 		response = append(response, buildPublicNote(note, tags, relatedItems, nil))
 	}
 
+	app.attachImages(r, nil, response)
+
+	noteIDs := make([]int64, len(response))
+	for i, note := range response {
+		noteIDs[i] = note.ID
+	}
+	galleries := app.fetchGalleries(r, data.ItemTypeNotes, noteIDs)
+	for i := range response {
+		response[i].Gallery = galleries[response[i].ID]
+	}
+
 	app.writeJSON(w, http.StatusOK, envelope{"notes": response})
 }
 
 func (app *application) getPublicProjectsHandler(w http.ResponseWriter, r *http.Request) {
 	projects, err := app.getModels(r).Projects.GetAll()
 	if err != nil {
-		app.logger.Printf("Error retrieving projects: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving projects", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -195,21 +240,21 @@ func (app *application) getPublicProjectsHandler(w http.ResponseWriter, r *http.
 	for _, project := range projects {
 		tags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeProjects, project.ID)
 		if err != nil {
-			app.logger.Printf("Error retrieving tags for project %d: %s", project.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving tags for project", "projectId", project.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
 
 		notes, _, err := app.getModels(r).ItemNotes.GetNotesForItem(string(data.ItemTypeProjects), project.ID, data.CursorFilters{OnlyPublished: true})
 		if err != nil {
-			app.logger.Printf("Error retrieving notes for project %d: %s", project.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving notes for project", "projectId", project.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
 
 		relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 		if err != nil {
-			app.logger.Printf("Error fetching related items: %s", err)
+			app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -218,7 +263,7 @@ func (app *application) getPublicProjectsHandler(w http.ResponseWriter, r *http.
 		for _, note := range notes {
 			noteTags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 			if err != nil {
-				app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+				app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 				app.writeError(w, http.StatusInternalServerError)
 				return
 			}
@@ -229,13 +274,24 @@ func (app *application) getPublicProjectsHandler(w http.ResponseWriter, r *http.
 		response = append(response, buildPublicProject(project, tags, publicNotes))
 	}
 
+	app.attachImages(r, response, nil)
+
+	projectIDs := make([]int64, len(response))
+	for i, project := range response {
+		projectIDs[i] = project.ID
+	}
+	galleries := app.fetchGalleries(r, data.ItemTypeProjects, projectIDs)
+	for i := range response {
+		response[i].Gallery = galleries[response[i].ID]
+	}
+
 	app.writeJSON(w, http.StatusOK, envelope{"projects": response})
 }
 
 func (app *application) getPublicRolesHandler(w http.ResponseWriter, r *http.Request) {
 	roles, err := app.getModels(r).Roles.GetAll()
 	if err != nil {
-		app.logger.Printf("Error retrieving roles: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving roles", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -245,14 +301,14 @@ func (app *application) getPublicRolesHandler(w http.ResponseWriter, r *http.Req
 	for _, role := range roles {
 		notes, _, err := app.getModels(r).ItemNotes.GetNotesForItem(string(data.ItemTypeRoles), role.ID, data.CursorFilters{OnlyPublished: true})
 		if err != nil {
-			app.logger.Printf("Error retrieving notes for role %d: %s", role.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving notes for role", "roleId", role.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
 
 		relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 		if err != nil {
-			app.logger.Printf("Error fetching related items: %s", err)
+			app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -261,7 +317,7 @@ func (app *application) getPublicRolesHandler(w http.ResponseWriter, r *http.Req
 		for _, note := range notes {
 			noteTags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 			if err != nil {
-				app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+				app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 				app.writeError(w, http.StatusInternalServerError)
 				return
 			}
@@ -272,6 +328,15 @@ func (app *application) getPublicRolesHandler(w http.ResponseWriter, r *http.Req
 		response = append(response, buildPublicRole(role, publicNotes))
 	}
 
+	roleIDs := make([]int64, len(response))
+	for i, role := range response {
+		roleIDs[i] = role.RoleID
+	}
+	galleries := app.fetchGalleries(r, data.ItemTypeRoles, roleIDs)
+	for i := range response {
+		response[i].Gallery = galleries[response[i].RoleID]
+	}
+
 	app.writeJSON(w, http.StatusOK, envelope{"roles": response})
 }
 
@@ -364,14 +429,14 @@ func (app *application) getPublicNotesForContentHandler(w http.ResponseWriter, r
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
-		app.logger.Printf("Error retrieving notes for %s item %d: %s", itemType, itemID, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving notes for item", "itemType", itemType, "itemId", itemID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 	if err != nil {
-		app.logger.Printf("Error fetching related items: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -380,7 +445,7 @@ func (app *application) getPublicNotesForContentHandler(w http.ResponseWriter, r
 	for _, note := range notes {
 		noteTags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 		if err != nil {
-			app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -434,14 +499,14 @@ func (app *application) getPublicAllNotesForContentHandler(w http.ResponseWriter
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
-		app.logger.Printf("Error retrieving notes for content type %s: %s", itemType, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving notes for content type", "itemType", itemType, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 	if err != nil {
-		app.logger.Printf("Error fetching related items: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -450,7 +515,7 @@ func (app *application) getPublicAllNotesForContentHandler(w http.ResponseWriter
 	for _, note := range notes {
 		noteTags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 		if err != nil {
-			app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -540,6 +605,146 @@ func (app *application) getRelatedNotes(r *http.Request, note *data.Note, tags [
 	return result, nil
 }
 
+// attachImages adds responsive image metadata to projects and notes whose images were uploaded
+// through the asset pipeline. Failures are logged rather than returned because the plain image
+// URLs in the response remain usable without it.
+func (app *application) attachImages(r *http.Request, projects []publicProject, notes []publicNote) {
+	urls := make([]string, 0)
+	for _, project := range projects {
+		if project.Image != "" {
+			urls = append(urls, project.Image)
+		}
+		for _, note := range project.Notes {
+			urls = append(urls, noteImageURLs(note.Body)...)
+		}
+	}
+	for _, note := range notes {
+		urls = append(urls, noteImageURLs(note.Body)...)
+	}
+
+	if len(urls) == 0 {
+		return
+	}
+
+	found, err := app.getModels(r).Assets.GetByURLs(urls)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Error retrieving image assets", "error", err)
+		return
+	}
+
+	images := make(map[string]*publicImage, len(found)*2)
+	for _, asset := range found {
+		image := buildPublicImage(asset)
+		images[asset.URL] = image
+		images[asset.SecureURL] = image
+	}
+
+	noteImages := func(note *publicNote) {
+		for _, url := range noteImageURLs(note.Body) {
+			if image, ok := images[url]; ok {
+				note.Images = append(note.Images, *image)
+			}
+		}
+	}
+
+	for i := range projects {
+		projects[i].ImageSet = images[projects[i].Image]
+		for j := range projects[i].Notes {
+			noteImages(&projects[i].Notes[j])
+		}
+	}
+	for i := range notes {
+		noteImages(&notes[i])
+	}
+}
+
+// fetchGalleries loads the galleries of several items of the same type. Like attachImages it
+// logs failures and returns what it has, as galleries are supplementary to the payload.
+func (app *application) fetchGalleries(r *http.Request, itemType data.ItemType, itemIDs []int64) map[int64][]publicGalleryImage {
+	galleries := make(map[int64][]publicGalleryImage)
+	if len(itemIDs) == 0 {
+		return galleries
+	}
+
+	links, err := app.getModels(r).AssetLinks.GetForItems(itemType, itemIDs)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Error retrieving galleries", "itemType", itemType, "error", err)
+		return galleries
+	}
+
+	for itemID, itemLinks := range links {
+		for _, link := range itemLinks {
+			galleries[itemID] = append(galleries[itemID], publicGalleryImage{
+				publicImage: *buildPublicImage(link.Asset),
+				Caption:     link.Caption,
+				AltText:     link.AltText,
+			})
+		}
+	}
+
+	return galleries
+}
+
+// noteImageURLs returns the distinct image URLs referenced by markdown image syntax.
+func noteImageURLs(body string) []string {
+	matches := markdownImagePattern.FindAllStringSubmatch(body, -1)
+	urls := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+
+	for _, match := range matches {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			urls = append(urls, match[1])
+		}
+	}
+
+	return urls
+}
+
+func buildPublicImage(asset *data.Asset) *publicImage {
+	image := &publicImage{
+		URL:      asset.SecureURL,
+		Width:    asset.Width,
+		Height:   asset.Height,
+		Variants: make([]publicImageVariant, 0, len(asset.Variants)),
+		Srcset:   make(map[string]string),
+	}
+
+	candidates := make(map[string][]publicImageVariant)
+	if asset.Width > 0 {
+		candidates[asset.Format] = append(candidates[asset.Format], publicImageVariant{URL: asset.SecureURL, Width: asset.Width})
+	}
+
+	for _, variant := range asset.Variants {
+		converted := publicImageVariant{
+			Name:   variant.Name,
+			Format: variant.Format,
+			URL:    variant.URL,
+			Width:  variant.Width,
+			Height: variant.Height,
+		}
+		image.Variants = append(image.Variants, converted)
+		candidates[variant.Format] = append(candidates[variant.Format], converted)
+	}
+
+	for format, entries := range candidates {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Width < entries[j].Width })
+
+		parts := make([]string, 0, len(entries))
+		seenWidths := make(map[int]bool, len(entries))
+		for _, entry := range entries {
+			if entry.Width == 0 || seenWidths[entry.Width] {
+				continue
+			}
+			seenWidths[entry.Width] = true
+			parts = append(parts, fmt.Sprintf("%s %dw", entry.URL, entry.Width))
+		}
+		image.Srcset[format] = strings.Join(parts, ", ")
+	}
+
+	return image
+}
+
 func buildPublicNote(note *data.Note, tags []*data.Tag, relatedItems []publicRelatedItem, relatedNotes []publicNote) publicNote {
 	publishedAt := ""
 	if note.PublishedAt != nil {
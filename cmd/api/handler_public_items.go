@@ -28,28 +28,28 @@ func (app *application) getPublicProjectHandler(w http.ResponseWriter, r *http.R
 			app.writeError(w, http.StatusNotFound)
 			return
 		}
-		app.logger.Printf("Error retrieving project: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving project", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	tags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeProjects, project.ID)
 	if err != nil {
-		app.logger.Printf("Error retrieving tags for project %d: %s", project.ID, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving tags for project", "projectId", project.ID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	notes, _, err := app.getModels(r).ItemNotes.GetNotesForItem(string(data.ItemTypeProjects), project.ID, data.CursorFilters{OnlyPublished: true})
 	if err != nil {
-		app.logger.Printf("Error retrieving notes for project %d: %s", project.ID, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving notes for project", "projectId", project.ID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 	if err != nil {
-		app.logger.Printf("Error fetching related items: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -58,7 +58,7 @@ func (app *application) getPublicProjectHandler(w http.ResponseWriter, r *http.R
 	for _, note := range notes {
 		noteTags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 		if err != nil {
-			app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -66,7 +66,11 @@ func (app *application) getPublicProjectHandler(w http.ResponseWriter, r *http.R
 		publicNotes = append(publicNotes, buildPublicNote(note, noteTags, relatedItems, nil))
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"project": buildPublicProject(project, tags, publicNotes)})
+	response := []publicProject{buildPublicProject(project, tags, publicNotes)}
+	app.attachImages(r, response, nil)
+	response[0].Gallery = app.fetchGalleries(r, data.ItemTypeProjects, []int64{project.ID})[project.ID]
+
+	app.writeJSON(w, http.StatusOK, envelope{"project": response[0]})
 }
 
 func (app *application) getPublicNoteHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +91,7 @@ func (app *application) getPublicNoteHandler(w http.ResponseWriter, r *http.Requ
 			app.writeError(w, http.StatusNotFound)
 			return
 		}
-		app.logger.Printf("Error retrieving note: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving note", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -99,14 +103,14 @@ func (app *application) getPublicNoteHandler(w http.ResponseWriter, r *http.Requ
 
 	tags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 	if err != nil {
-		app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	relatedItemsMap, err := app.fetchRelatedItems(r, []*data.Note{note})
 	if err != nil {
-		app.logger.Printf("Error retrieving related items: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving related items", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -114,12 +118,16 @@ func (app *application) getPublicNoteHandler(w http.ResponseWriter, r *http.Requ
 
 	relatedNotes, err := app.getRelatedNotes(r, note, tags)
 	if err != nil {
-		app.logger.Printf("Error retrieving related notes: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving related notes", "error", err)
 		// We can still proceed without related notes
 		relatedNotes = []publicNote{}
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"note": buildPublicNote(note, tags, relatedItems, relatedNotes)})
+	response := []publicNote{buildPublicNote(note, tags, relatedItems, relatedNotes)}
+	app.attachImages(r, nil, response)
+	response[0].Gallery = app.fetchGalleries(r, data.ItemTypeNotes, []int64{note.ID})[note.ID]
+
+	app.writeJSON(w, http.StatusOK, envelope{"note": response[0]})
 }
 
 func (app *application) getPublicRoleHandler(w http.ResponseWriter, r *http.Request) {
@@ -140,21 +148,21 @@ func (app *application) getPublicRoleHandler(w http.ResponseWriter, r *http.Requ
 			app.writeError(w, http.StatusNotFound)
 			return
 		}
-		app.logger.Printf("Error retrieving role: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving role", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	notes, _, err := app.getModels(r).ItemNotes.GetNotesForItem(string(data.ItemTypeRoles), role.ID, data.CursorFilters{OnlyPublished: true})
 	if err != nil {
-		app.logger.Printf("Error retrieving notes for role %d: %s", role.ID, err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving notes for role", "roleId", role.ID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
 
 	relatedItemsMap, err := app.fetchRelatedItems(r, notes)
 	if err != nil {
-		app.logger.Printf("Error fetching related items: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error fetching related items", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -163,7 +171,7 @@ func (app *application) getPublicRoleHandler(w http.ResponseWriter, r *http.Requ
 	for _, note := range notes {
 		noteTags, err := app.getModels(r).TagItems.GetTagsForItem(data.ItemTypeNotes, note.ID)
 		if err != nil {
-			app.logger.Printf("Error retrieving tags for note %d: %s", note.ID, err)
+			app.logger.ErrorContext(r.Context(), "Error retrieving tags for note", "noteId", note.ID, "error", err)
 			app.writeError(w, http.StatusInternalServerError)
 			return
 		}
@@ -171,5 +179,8 @@ func (app *application) getPublicRoleHandler(w http.ResponseWriter, r *http.Requ
 		publicNotes = append(publicNotes, buildPublicNote(note, noteTags, relatedItems, nil))
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"role": buildPublicRole(role, publicNotes)})
+	response := buildPublicRole(role, publicNotes)
+	response.Gallery = app.fetchGalleries(r, data.ItemTypeRoles, []int64{role.ID})[role.ID]
+
+	app.writeJSON(w, http.StatusOK, envelope{"role": response})
 }
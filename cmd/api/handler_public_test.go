@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 	"log"
+	"log/slog"
 	"os"
 
 	"api.etin.dev/internal/data"
@@ -24,7 +25,7 @@ func TestGetPublicNotesForContentHandler(t *testing.T) {
 	models := data.NewModels(db, logger)
 
 	app := &application{
-		logger: logger,
+		logger: slog.New(slog.NewTextHandler(os.Stdout, nil)),
 		models: models,
 	}
 
@@ -43,7 +44,7 @@ func TestGetPublicNotesForContentHandler(t *testing.T) {
 				AddRow(projectID, time.Now(), time.Now(), nil, time.Now(), nil, "Title", slug, "Desc", "img.jpg"))
 
 		// Expect GetNotesForItem
-		mock.ExpectQuery(`SELECT notes.id, .* FROM item_notes .*`).
+		mock.ExpectQuery(`SELECT notes.id AS id, .* FROM item_notes .*`).
 			WithArgs("projects", projectID, sqlmock.AnyArg()).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"}))
 
@@ -67,7 +68,7 @@ func TestGetPublicNotesForContentHandler(t *testing.T) {
 				AddRow(projectID, time.Now(), time.Now(), nil, time.Now(), nil, "Title", "slug", "Desc", "img.jpg"))
 
 		// Expect GetNotesForItem
-		mock.ExpectQuery(`SELECT notes.id, .* FROM item_notes .*`).
+		mock.ExpectQuery(`SELECT notes.id AS id, .* FROM item_notes .*`).
 			WithArgs("projects", projectID, sqlmock.AnyArg()).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"}))
 
@@ -117,3 +118,111 @@ func TestGetPublicNotesForContentHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestNoteImageURLs(t *testing.T) {
+	body := "Intro ![diagram](https://cdn.example.com/a.png) and ![a photo](<https://cdn.example.com/b.jpg> \"title\")\n![again](https://cdn.example.com/a.png)"
+
+	urls := noteImageURLs(body)
+	expected := []string{"https://cdn.example.com/a.png", "https://cdn.example.com/b.jpg"}
+
+	if len(urls) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, urls)
+	}
+	for i := range expected {
+		if urls[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, urls)
+		}
+	}
+}
+
+func TestBuildPublicImage(t *testing.T) {
+	asset := &data.Asset{
+		SecureURL: "https://cdn.example.com/hero.png",
+		Format:    "png",
+		Width:     1600,
+		Height:    900,
+		Variants: []data.AssetVariant{
+			{Name: "card", Format: "png", URL: "https://cdn.example.com/hero_card.png", Width: 800, Height: 450},
+			{Name: "thumb", Format: "png", URL: "https://cdn.example.com/hero_thumb.png", Width: 320, Height: 320},
+			{Name: "card", Format: "webp", URL: "https://cdn.example.com/hero_card.webp", Width: 800, Height: 450},
+		},
+	}
+
+	image := buildPublicImage(asset)
+
+	if len(image.Variants) != 3 {
+		t.Fatalf("expected 3 variants, got %d", len(image.Variants))
+	}
+
+	expectedPNG := "https://cdn.example.com/hero_thumb.png 320w, https://cdn.example.com/hero_card.png 800w, https://cdn.example.com/hero.png 1600w"
+	if image.Srcset["png"] != expectedPNG {
+		t.Fatalf("unexpected png srcset %q", image.Srcset["png"])
+	}
+
+	if image.Srcset["webp"] != "https://cdn.example.com/hero_card.webp 800w" {
+		t.Fatalf("unexpected webp srcset %q", image.Srcset["webp"])
+	}
+}
+
+func TestGetModels_RoutesPublicReadsToReplica(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer primary.Close()
+
+	replicaDB, replicaMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer replicaDB.Close()
+
+	logger := log.New(os.Stdout, "", 0)
+	app := &application{
+		logger:   slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		models:   data.NewModels(primary, logger).WithReplica(data.NewReplica(replicaDB, replicaDB)),
+		sessions: newSessionManager(time.Hour),
+	}
+
+	token, _, err := app.sessions.create("admin@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error creating session: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		token   string
+		replica bool
+	}{
+		{name: "anonymous public read", method: http.MethodGet, path: "/public/v1/roles", replica: true},
+		{name: "admin public read", method: http.MethodGet, path: "/public/v1/roles", token: token},
+		{name: "invalid token public read", method: http.MethodGet, path: "/public/v1/roles", token: "expired", replica: true},
+		{name: "admin read", method: http.MethodGet, path: "/v1/roles", token: token},
+		{name: "admin write", method: http.MethodPost, path: "/v1/roles", token: token},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := primaryMock
+			if tt.replica {
+				mock = replicaMock
+			}
+			mock.ExpectQuery(`SELECT (.+) FROM tags WHERE deletedAt IS NULL AND id = \$1`).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			app.getModels(req).Tags.Get(1)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("query was not sent where expected (replica %t): %s", tt.replica, err)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,7 +12,7 @@ import (
 func (app *application) getRolesHandler(w http.ResponseWriter, r *http.Request) {
 	roles, err := app.getModels(r).Roles.GetAll()
 	if err != nil {
-		app.logger.Printf("Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving roles", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +35,7 @@ func (app *application) createRoleHandler(w http.ResponseWriter, r *http.Request
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Print(err)
+		app.logger.WarnContext(r.Context(), "Could not parse input", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -48,12 +48,22 @@ func (app *application) createRoleHandler(w http.ResponseWriter, r *http.Request
 		Description: input.Description,
 		Skills:      input.Skills,
 	}
-	err = app.getModels(r).Roles.Insert(role)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Roles.Insert(role); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "role", "created", role.ID, nil, role)
+	})
 	if err != nil {
-		app.logger.Printf("Error: %s", err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not create role", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
+	app.publishEvent(r.Context(), "role", "created", role.ID, role)
 	app.writeJSON(w, http.StatusCreated, envelope{"role": role})
 }
 
@@ -65,7 +75,7 @@ func (app *application) getRoleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	role, err := app.getModels(r).Roles.Get(id)
 	if err != nil {
-		app.logger.Printf("A problem fetching roleid: %d Error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "A problem fetching role", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -84,10 +94,11 @@ func (app *application) updateRoleHandler(w http.ResponseWriter, r *http.Request
 	}
 	role, err := app.getModels(r).Roles.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve model. Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve model", "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
+	before := *role
 	var input struct {
 		StartDate   *time.Time `json:"startDate"`
 		EndDate     *time.Time `json:"endDate"`
@@ -99,7 +110,7 @@ func (app *application) updateRoleHandler(w http.ResponseWriter, r *http.Request
 	}
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Could not parse input. Error: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse input", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -124,12 +135,22 @@ func (app *application) updateRoleHandler(w http.ResponseWriter, r *http.Request
 	if len(input.Skills) > 0 {
 		role.Skills = input.Skills
 	}
-	err = app.getModels(r).Roles.Update(role)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Roles.Update(role); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "role", "updated", role.ID, before, role)
+	})
 	if err != nil {
-		app.logPostgresError(fmt.Sprintf("Could not update role %d", id), err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logPostgresError(r.Context(), "Could not update role", err, "id", id)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
+	app.publishEvent(r.Context(), "role", "updated", role.ID, role)
 	app.writeJSON(w, http.StatusOK, envelope{"role": role})
 }
 
@@ -143,10 +164,25 @@ func (app *application) deleteRoleHandler(w http.ResponseWriter, r *http.Request
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
-	err = app.getModels(r).Roles.Delete(id)
+	role, err := app.getModels(r).Roles.Get(id)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Roles.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "role", "deleted", id, role, nil)
+	})
 	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
+	app.publishEvent(r.Context(), "role", "deleted", id, role)
 	app.writeJSON(w, http.StatusNoContent, envelope{"role": nil})
 }
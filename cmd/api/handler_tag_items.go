@@ -12,7 +12,7 @@ import (
 func (app *application) getTagItemsHandler(w http.ResponseWriter, r *http.Request) {
 	tagItems, err := app.getModels(r).TagItems.GetAll()
 	if err != nil {
-		app.logger.Printf("Error retrieving tag associations: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving tag associations", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -33,7 +33,7 @@ func (app *application) createTagItemHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
-		app.logger.Printf("Could not parse tag association payload: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse tag association payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -49,13 +49,23 @@ func (app *application) createTagItemHandler(w http.ResponseWriter, r *http.Requ
 		ItemType: data.ItemType(strings.ToLower(input.ItemType)),
 	}
 
-	if err := app.getModels(r).TagItems.Insert(tagItem); err != nil {
+	err := app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.TagItems.Insert(tagItem); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "tagged_item", "created", tagItem.ID, nil, tagItem)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		if errors.Is(err, data.ErrInvalidItemType) {
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
 
-		app.logger.Printf("Could not create tag association: %s", err)
+		app.logger.ErrorContext(r.Context(), "Could not create tag association", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -72,7 +82,7 @@ func (app *application) getTagItemHandler(w http.ResponseWriter, r *http.Request
 
 	tagItem, err := app.getModels(r).TagItems.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve tag association %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve tag association", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -94,11 +104,13 @@ func (app *application) updateTagItemHandler(w http.ResponseWriter, r *http.Requ
 
 	tagItem, err := app.getModels(r).TagItems.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve tag association %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve tag association", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
 
+	before := *tagItem
+
 	var input struct {
 		TagID    *int64  `json:"tagId"`
 		ItemID   *int64  `json:"itemId"`
@@ -106,7 +118,7 @@ func (app *application) updateTagItemHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
-		app.logger.Printf("Could not parse tag association update payload: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse tag association update payload", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -123,13 +135,23 @@ func (app *application) updateTagItemHandler(w http.ResponseWriter, r *http.Requ
 		tagItem.ItemType = data.ItemType(strings.ToLower(*input.ItemType))
 	}
 
-	if err := app.getModels(r).TagItems.Update(tagItem); err != nil {
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.TagItems.Update(tagItem); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "tagged_item", "updated", tagItem.ID, before, tagItem)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		if errors.Is(err, data.ErrInvalidItemType) {
 			app.writeError(w, http.StatusBadRequest)
 			return
 		}
 
-		app.logger.Printf("Could not update tag association %d: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "Could not update tag association", "id", id, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -149,8 +171,25 @@ func (app *application) deleteTagItemHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := app.getModels(r).TagItems.Delete(id); err != nil {
-		app.logger.Printf("Could not delete tag association %d: %s", id, err)
+	tagItem, err := app.getModels(r).TagItems.Get(id)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not retrieve tag association", "id", id, "error", err)
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.TagItems.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "tagged_item", "deleted", id, tagItem, nil)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not delete tag association", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -185,7 +224,7 @@ func (app *application) getTagsForItemHandler(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		app.logger.Printf("Could not retrieve tags for %s item %d: %s", itemTypeStr, itemID, err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve tags for item", "itemType", itemTypeStr, "itemId", itemID, "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
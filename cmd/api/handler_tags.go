@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -11,7 +11,7 @@ import (
 func (app *application) getTagsHandler(w http.ResponseWriter, r *http.Request) {
 	tags, err := app.getModels(r).Tags.GetAll()
 	if err != nil {
-		app.logger.Printf("Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Error retrieving tags", "error", err)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
@@ -31,7 +31,7 @@ func (app *application) createTagHandler(w http.ResponseWriter, r *http.Request)
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Print(err)
+		app.logger.WarnContext(r.Context(), "Could not parse input", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -41,12 +41,22 @@ func (app *application) createTagHandler(w http.ResponseWriter, r *http.Request)
 		Icon:  input.Icon,
 		Theme: input.Theme,
 	}
-	err = app.getModels(r).Tags.Insert(tag)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Tags.Insert(tag); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "tag", "created", tag.ID, nil, tag)
+	})
 	if err != nil {
-		app.logger.Printf("Error: %s", err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not create tag", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
+	app.publishEvent(r.Context(), "tag", "created", tag.ID, tag)
 	app.writeJSON(w, http.StatusCreated, envelope{"tag": tag})
 }
 
@@ -58,7 +68,7 @@ func (app *application) getTagHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	tag, err := app.getModels(r).Tags.Get(id)
 	if err != nil {
-		app.logger.Printf("A problem fetching tag id: %d Error: %s", id, err)
+		app.logger.ErrorContext(r.Context(), "A problem fetching tag", "id", id, "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
@@ -77,10 +87,11 @@ func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request)
 	}
 	tag, err := app.getModels(r).Tags.Get(id)
 	if err != nil {
-		app.logger.Printf("Could not retrieve model. Error: %s", err)
+		app.logger.ErrorContext(r.Context(), "Could not retrieve model", "error", err)
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
+	before := *tag
 	var input struct {
 		Name  *string `json:"name"`
 		Slug  *string `json:"slug"`
@@ -89,7 +100,7 @@ func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request)
 	}
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Printf("Could not parse input. Error: %s", err)
+		app.logger.WarnContext(r.Context(), "Could not parse input", "error", err)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
@@ -105,12 +116,22 @@ func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request)
 	if input.Theme != nil {
 		tag.Theme = input.Theme
 	}
-	err = app.getModels(r).Tags.Update(tag)
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Tags.Update(tag); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "tag", "updated", tag.ID, before, tag)
+	})
 	if err != nil {
-		app.logPostgresError(fmt.Sprintf("Could not update tag %d", id), err)
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logPostgresError(r.Context(), "Could not update tag", err, "id", id)
 		app.writeError(w, http.StatusInternalServerError)
 		return
 	}
+	app.publishEvent(r.Context(), "tag", "updated", tag.ID, tag)
 	app.writeJSON(w, http.StatusOK, envelope{"tag": tag})
 }
 
@@ -124,10 +145,25 @@ func (app *application) deleteTagHandler(w http.ResponseWriter, r *http.Request)
 		app.writeError(w, http.StatusBadRequest)
 		return
 	}
-	err = app.getModels(r).Tags.Delete(id)
+	tag, err := app.getModels(r).Tags.Get(id)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.Tags.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "tag", "deleted", id, tag, nil)
+	})
 	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
 		app.writeError(w, http.StatusNotFound)
 		return
 	}
+	app.publishEvent(r.Context(), "tag", "deleted", id, tag)
 	app.writeJSON(w, http.StatusNoContent, envelope{"tag": nil})
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// uploadChunkContentType is the media type chunks are sent with, borrowed from the tus protocol.
+const uploadChunkContentType = "application/offset+octet-stream"
+
+func (app *application) createUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Filename string `json:"filename"`
+		Length   int64  `json:"length"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse upload payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	filename := parseUploadFilename(input.Filename)
+	if filename == "" || input.Length < 1 {
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	session, err := app.uploads.create(filename, input.Length)
+	if err != nil {
+		if errors.Is(err, errUploadTooLarge) {
+			app.writeErrorMessage(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not create upload", "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/uploads/"+session.ID)
+	writeUploadHeaders(w, session)
+	app.writeJSON(w, http.StatusCreated, envelope{"upload": session})
+}
+
+// getUploadHandler also answers HEAD requests, which resuming clients use to read Upload-Offset.
+func (app *application) getUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	session, err := app.uploads.get(r.PathValue("id"))
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeUploadHeaders(w, session)
+	app.writeJSON(w, http.StatusOK, envelope{"upload": session})
+}
+
+func (app *application) appendUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != uploadChunkContentType {
+		app.writeErrorMessage(w, http.StatusUnsupportedMediaType, "chunks must be sent as "+uploadChunkContentType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		app.writeErrorMessage(w, http.StatusBadRequest, "Upload-Offset header must be a non-negative integer")
+		return
+	}
+
+	checksum, err := parseUploadChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		app.writeErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := app.uploads.appendChunk(r.PathValue("id"), offset, r.Body, checksum)
+	if session.ID != "" {
+		writeUploadHeaders(w, session)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errUploadNotFound):
+			app.writeError(w, http.StatusNotFound)
+		case errors.Is(err, errUploadBusy), errors.Is(err, errUploadOffset):
+			app.writeErrorMessage(w, http.StatusConflict, err.Error())
+		case errors.Is(err, errUploadTooLarge):
+			app.writeErrorMessage(w, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, errUploadChecksum):
+			app.writeErrorMessage(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			// Usually the client went away mid-chunk; the bytes that did arrive are kept.
+			app.logger.ErrorContext(r.Context(), "Could not append to upload", "id", r.PathValue("id"), "error", err)
+			app.writeError(w, http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) completeUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	session, file, err := app.uploads.open(r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, errUploadNotFound):
+			app.writeError(w, http.StatusNotFound)
+		case errors.Is(err, errUploadBusy), errors.Is(err, errUploadIncomplete):
+			app.writeErrorMessage(w, http.StatusConflict, err.Error())
+		default:
+			app.logger.ErrorContext(r.Context(), "Could not open upload", "id", r.PathValue("id"), "error", err)
+			app.writeError(w, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	asset, status, err := app.ingestAsset(r.Context(), session.Filename, file, app.storeUpload)
+	file.Close()
+
+	// Failures on our side or the backend's can be retried against the same session; a file
+	// that was stored or rejected is finished with.
+	if err != nil && status >= http.StatusInternalServerError {
+		if releaseErr := app.uploads.release(session); releaseErr != nil {
+			app.logger.ErrorContext(r.Context(), "Could not save upload", "id", session.ID, "error", releaseErr)
+		}
+	} else {
+		app.uploads.discard(session)
+	}
+
+	if err != nil {
+		app.writeIngestError(w, r, status, err)
+		return
+	}
+
+	app.writeJSON(w, status, envelope{"asset": asset})
+}
+
+func (app *application) deleteUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	if err := app.uploads.remove(r.PathValue("id")); err != nil {
+		if errors.Is(err, errUploadBusy) {
+			app.writeErrorMessage(w, http.StatusConflict, err.Error())
+			return
+		}
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	app.writeJSON(w, http.StatusNoContent, nil)
+}
+
+func writeUploadHeaders(w http.ResponseWriter, session uploadSession) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.Length, 10))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// parseUploadChecksum reads an optional "Upload-Checksum: <algorithm> <base64 digest>" header.
+func parseUploadChecksum(header string) (*chunkChecksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	algorithm, encoded, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, errors.New("Upload-Checksum header must be an algorithm and a base64 digest")
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("Upload-Checksum digest must be base64 encoded")
+	}
+
+	checksum := &chunkChecksum{expected: expected}
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		checksum.hash = sha256.New()
+	case "sha1":
+		checksum.hash = sha1.New()
+	default:
+		return nil, fmt.Errorf("Upload-Checksum algorithm %q is not supported, use sha256 or sha1", algorithm)
+	}
+
+	return checksum, nil
+}
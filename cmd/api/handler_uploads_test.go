@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"api.etin.dev/internal/assets"
+)
+
+func newUploadApp(t *testing.T, uploader assets.Uploader, saver assetSaver) (*application, string) {
+	t.Helper()
+
+	app, token := newAuthenticatedApp(t, uploader, saver)
+
+	uploads, err := newUploadManager(t.TempDir(), time.Hour, 1<<20)
+	if err != nil {
+		t.Fatalf("create upload manager: %v", err)
+	}
+	app.uploads = uploads
+
+	return app, token
+}
+
+func serveUploadRequest(app *application, token, method, target string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	return rr
+}
+
+func createUploadSession(t *testing.T, app *application, token string, length int) string {
+	t.Helper()
+
+	body := []byte(`{"filename": "talk.png", "length": ` + strconv.Itoa(length) + `}`)
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/uploads", body, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Upload uploadSession `json:"upload"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if location := rr.Header().Get("Location"); location != "/v1/uploads/"+resp.Upload.ID {
+		t.Fatalf("unexpected location %q", location)
+	}
+
+	return resp.Upload.ID
+}
+
+func chunkHeaders(offset int, checksum string) map[string]string {
+	headers := map[string]string{
+		"Content-Type":  uploadChunkContentType,
+		"Upload-Offset": strconv.Itoa(offset),
+	}
+	if checksum != "" {
+		headers["Upload-Checksum"] = checksum
+	}
+	return headers
+}
+
+func sha256Checksum(chunk []byte) string {
+	sum := sha256.Sum256(chunk)
+	return "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestChunkedUpload_Complete(t *testing.T) {
+	uploader := &stubUploader{result: &assets.UploadResult{URL: "https://cdn.example.com/talk.png", PublicID: "talk"}}
+	saver := &stubAssetSaver{nextID: 7}
+	app, token := newUploadApp(t, uploader, saver)
+
+	payload := pngPayload(t, 40, 20)
+	id := createUploadSession(t, app, token, len(payload))
+
+	split := len(payload) / 2
+	rr := serveUploadRequest(app, token, http.MethodPatch, "/v1/uploads/"+id, payload[:split], chunkHeaders(0, sha256Checksum(payload[:split])))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Upload-Offset"); got != strconv.Itoa(split) {
+		t.Fatalf("expected offset %d after first chunk, got %s", split, got)
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodHead, "/v1/uploads/"+id, nil, nil)
+	if rr.Code != http.StatusOK || rr.Header().Get("Upload-Offset") != strconv.Itoa(split) {
+		t.Fatalf("unexpected HEAD response %d with offset %q", rr.Code, rr.Header().Get("Upload-Offset"))
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodPost, "/v1/uploads/"+id+"/complete", nil, nil)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected incomplete upload to conflict, got %d", rr.Code)
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodPatch, "/v1/uploads/"+id, payload[split:], chunkHeaders(split, ""))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodPost, "/v1/uploads/"+id+"/complete", nil, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	if uploader.calls != 1 || len(saver.saved) != 1 {
+		t.Fatalf("expected one upload and one saved asset, got %d and %d", uploader.calls, len(saver.saved))
+	}
+
+	sum := sha256.Sum256(payload)
+	if saver.saved[0].ContentHash != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected content hash %q", saver.saved[0].ContentHash)
+	}
+
+	if _, err := app.uploads.get(id); !errors.Is(err, errUploadNotFound) {
+		t.Fatalf("expected completed session to be removed, got %v", err)
+	}
+}
+
+func TestChunkedUpload_RejectsMismatchedChunks(t *testing.T) {
+	app, token := newUploadApp(t, &stubUploader{}, &stubAssetSaver{})
+
+	payload := pngPayload(t, 8, 8)
+	id := createUploadSession(t, app, token, len(payload))
+
+	rr := serveUploadRequest(app, token, http.MethodPatch, "/v1/uploads/"+id, payload[:10], chunkHeaders(0, sha256Checksum([]byte("other"))))
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected checksum mismatch to return %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+	if got := rr.Header().Get("Upload-Offset"); got != "0" {
+		t.Fatalf("expected rejected chunk to be rolled back, offset is %s", got)
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodPatch, "/v1/uploads/"+id, payload[:10], chunkHeaders(5, ""))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected offset mismatch to return %d, got %d", http.StatusConflict, rr.Code)
+	}
+
+	oversized := append(append([]byte{}, payload...), 0)
+	rr = serveUploadRequest(app, token, http.MethodPatch, "/v1/uploads/"+id, oversized, chunkHeaders(0, ""))
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected oversized chunk to return %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodPatch, "/v1/uploads/"+id, payload, map[string]string{"Upload-Offset": "0"})
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected missing chunk content type to return %d, got %d", http.StatusUnsupportedMediaType, rr.Code)
+	}
+
+	session, err := app.uploads.get(id)
+	if err != nil || session.Offset != 0 {
+		t.Fatalf("expected session to be untouched, got %+v, %v", session, err)
+	}
+}
+
+func TestChunkedUpload_RejectsOversizedSession(t *testing.T) {
+	app, token := newUploadApp(t, &stubUploader{}, &stubAssetSaver{})
+
+	rr := serveUploadRequest(app, token, http.MethodPost, "/v1/uploads", []byte(`{"filename": "talk.mp4", "length": 2097152}`), nil)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+	}
+}
+
+func TestChunkedUpload_Delete(t *testing.T) {
+	app, token := newUploadApp(t, &stubUploader{}, &stubAssetSaver{})
+
+	id := createUploadSession(t, app, token, 100)
+	session, _ := app.uploads.get(id)
+
+	rr := serveUploadRequest(app, token, http.MethodDelete, "/v1/uploads/"+id, nil, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	if _, err := os.Stat(session.path); !os.IsNotExist(err) {
+		t.Fatalf("expected upload data to be deleted, stat returned %v", err)
+	}
+
+	rr = serveUploadRequest(app, token, http.MethodGet, "/v1/uploads/"+id, nil, nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestUploadManager_ExpiresAbandonedSessions(t *testing.T) {
+	dir := t.TempDir()
+	leftover := dir + "/stale" + uploadPartSuffix
+	if err := os.WriteFile(leftover, []byte("partial"), 0o600); err != nil {
+		t.Fatalf("write leftover: %v", err)
+	}
+
+	manager, err := newUploadManager(dir, time.Hour, 1<<20)
+	if err != nil {
+		t.Fatalf("create upload manager: %v", err)
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Fatalf("expected leftover from a previous run to be removed")
+	}
+
+	now := time.Now()
+	manager.now = func() time.Time { return now }
+
+	abandoned, _ := manager.create("old.pdf", 10)
+	active, _ := manager.create("new.pdf", 10)
+
+	now = now.Add(50 * time.Minute)
+	if _, err := manager.appendChunk(active.ID, 0, bytes.NewReader([]byte("12345")), nil); err != nil {
+		t.Fatalf("append chunk: %v", err)
+	}
+
+	now = now.Add(20 * time.Minute)
+	if removed, _ := manager.expire(); removed != 1 {
+		t.Fatalf("expected one session to expire, got %d", removed)
+	}
+
+	if _, err := manager.get(abandoned.ID); !errors.Is(err, errUploadNotFound) {
+		t.Fatalf("expected abandoned session to be gone, got %v", err)
+	}
+	if _, err := os.Stat(abandoned.path); !os.IsNotExist(err) {
+		t.Fatalf("expected abandoned data to be deleted")
+	}
+	if session, err := manager.get(active.ID); err != nil || session.Offset != 5 {
+		t.Fatalf("expected active session to survive, got %+v, %v", session, err)
+	}
+}
+
+func TestUploadManager_RestoresSessionsAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := newUploadManager(dir, time.Hour, 1<<20)
+	if err != nil {
+		t.Fatalf("create upload manager: %v", err)
+	}
+
+	now := time.Now()
+	manager.now = func() time.Time { return now }
+
+	stale, _ := manager.create("old.pdf", 10)
+	now = now.Add(50 * time.Minute)
+	session, _ := manager.create("talk.pdf", 10)
+	if _, err := manager.appendChunk(session.ID, 0, bytes.NewReader([]byte("12345")), nil); err != nil {
+		t.Fatalf("append chunk: %v", err)
+	}
+
+	// Simulate a chunk that was being written when the process stopped.
+	file, err := os.OpenFile(session.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open upload file: %v", err)
+	}
+	file.Write([]byte("67"))
+	file.Close()
+
+	restarted, err := newUploadManager(dir, time.Hour, 1<<20)
+	if err != nil {
+		t.Fatalf("restart upload manager: %v", err)
+	}
+	restarted.now = func() time.Time { return now.Add(20 * time.Minute) }
+
+	restored, err := restarted.get(session.ID)
+	if err != nil || restored.Filename != "talk.pdf" || restored.Length != 10 || restored.Offset != 5 {
+		t.Fatalf("expected the session to be restored, got %+v, %v", restored, err)
+	}
+	if info, err := os.Stat(restored.path); err != nil || info.Size() != 5 {
+		t.Fatalf("expected the unrecorded chunk to be cut off, got %v, %v", info, err)
+	}
+
+	if _, err := restarted.appendChunk(session.ID, 5, bytes.NewReader([]byte("67890")), nil); err != nil {
+		t.Fatalf("append chunk after restart: %v", err)
+	}
+
+	_, file, err = restarted.open(session.ID)
+	if err != nil {
+		t.Fatalf("open restored upload: %v", err)
+	}
+	contents, _ := io.ReadAll(file)
+	file.Close()
+	if string(contents) != "1234567890" {
+		t.Fatalf("unexpected upload contents %q", contents)
+	}
+
+	if _, err := restarted.get(stale.ID); !errors.Is(err, errUploadNotFound) {
+		t.Fatalf("expected the expired session to be gone, got %v", err)
+	}
+	restarted.expire()
+	for _, path := range []string{stale.path, restarted.metaPath(stale.ID)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be deleted", path)
+		}
+	}
+}
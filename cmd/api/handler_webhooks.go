@@ -0,0 +1,406 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"api.etin.dev/internal/data"
+)
+
+func (app *application) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	filters := data.WebhookDeliveryFilters{CursorFilters: data.CursorFilters{Limit: 50}}
+
+	qs := r.URL.Query()
+	if cursor := qs.Get("cursor"); cursor != "" {
+		if _, err := strconv.ParseInt(cursor, 10, 64); err != nil {
+			app.writeError(w, http.StatusBadRequest)
+			return
+		}
+		filters.Cursor = cursor
+	}
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err == nil && l > 0 {
+			filters.Limit = min(l, 200)
+		}
+	}
+
+	if subscriptionID := qs.Get("subscriptionId"); subscriptionID != "" {
+		id, err := strconv.ParseInt(subscriptionID, 10, 64)
+		if err != nil || id < 1 {
+			app.writeError(w, http.StatusBadRequest)
+			return
+		}
+		filters.SubscriptionID = id
+	}
+
+	filters.Status = qs.Get("status")
+	switch filters.Status {
+	case "", data.DeliveryPending, data.DeliveryDelivering, data.DeliveryDelivered, data.DeliveryFailed, data.DeliverySuperseded:
+	default:
+		app.writeErrorMessage(w, http.StatusBadRequest, "unknown delivery status "+strconv.Quote(filters.Status))
+		return
+	}
+
+	deliveries, metadata, err := app.getModels(r).WebhookDeliveries.GetAll(filters)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not list webhook deliveries", "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries, "metadata": metadata})
+}
+
+func (app *application) getWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delivery, err := app.getModels(r).WebhookDeliveries.Get(id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not get webhook delivery", "id", id, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"delivery": delivery})
+}
+
+// replayWebhookDeliveryHandler queues a fresh delivery for a failed one. It is sent to the
+// currently configured URL, which may differ from the one the original delivery used.
+func (app *application) replayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	var delivery *data.WebhookDelivery
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if delivery, err = tx.WebhookDeliveries.Replay(id, app.config.deployWebhook); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "webhook_delivery", "replayed", id, nil, delivery)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errAuditFailed):
+			app.writeError(w, http.StatusInternalServerError)
+		case errors.Is(err, data.ErrDeliveryNotReplayable):
+			app.writeErrorMessage(w, http.StatusConflict, err.Error())
+		case errors.Is(err, data.ErrDeployWebhookDisabled):
+			app.writeErrorMessage(w, http.StatusServiceUnavailable, err.Error())
+		case err.Error() == "record not found":
+			app.writeError(w, http.StatusNotFound)
+		default:
+			app.logger.ErrorContext(r.Context(), "Could not replay webhook delivery", "id", id, "error", err)
+			app.writeError(w, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusAccepted, envelope{"delivery": delivery})
+}
+
+func (app *application) getWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	subscriptions, err := app.getModels(r).WebhookSubscriptions.GetAll()
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not list webhook subscriptions", "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"webhooks": subscriptions, "eventTypes": data.WebhookEventTypes})
+}
+
+// createWebhookHandler registers a subscription and returns its signing secret, which cannot
+// be read back later.
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		URL         string   `json:"url"`
+		Events      []string `json:"events"`
+		Description string   `json:"description"`
+		Active      *bool    `json:"active"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse webhook payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	subscription := &data.WebhookSubscription{
+		URL:         strings.TrimSpace(input.URL),
+		Events:      input.Events,
+		Description: input.Description,
+		Active:      input.Active == nil || *input.Active,
+	}
+
+	if err := validateWebhookSubscription(subscription); err != nil {
+		app.writeErrorMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "Could not generate webhook secret", "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+	subscription.Secret = secret
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.WebhookSubscriptions.Insert(subscription); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "webhook", "created", subscription.ID, nil, redactWebhookSecret(subscription))
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not create webhook subscription", "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/webhooks/%d", subscription.ID))
+	app.writeJSON(w, http.StatusCreated, envelope{"webhook": subscription})
+}
+
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	subscription, err := app.getModels(r).WebhookSubscriptions.Get(id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not get webhook subscription", "id", id, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"webhook": subscription})
+}
+
+// updateWebhookHandler changes a subscription. Setting rotateSecret replaces the signing secret
+// and returns the new one.
+func (app *application) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	subscription, err := app.getModels(r).WebhookSubscriptions.Get(id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not get webhook subscription", "id", id, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	before := *subscription
+
+	var input struct {
+		URL          *string  `json:"url"`
+		Events       []string `json:"events"`
+		Description  *string  `json:"description"`
+		Active       *bool    `json:"active"`
+		RotateSecret bool     `json:"rotateSecret"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.logger.WarnContext(r.Context(), "Could not parse webhook payload", "error", err)
+		app.writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	if input.URL != nil {
+		subscription.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.Events != nil {
+		subscription.Events = input.Events
+	}
+	if input.Description != nil {
+		subscription.Description = *input.Description
+	}
+	if input.Active != nil {
+		subscription.Active = *input.Active
+	}
+
+	if err := validateWebhookSubscription(subscription); err != nil {
+		app.writeErrorMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if input.RotateSecret {
+		subscription.Secret, err = newWebhookSecret()
+		if err != nil {
+			app.logger.ErrorContext(r.Context(), "Could not generate webhook secret", "error", err)
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.WebhookSubscriptions.Update(subscription); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "webhook", "updated", id, before, redactWebhookSecret(subscription))
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not update webhook subscription", "id", id, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"webhook": subscription})
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.isRequestAuthenticated(r) {
+		app.writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	subscription, err := app.getModels(r).WebhookSubscriptions.Get(id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not get webhook subscription", "id", id, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	err = app.getModels(r).RunInTx(r.Context(), func(tx data.Models) error {
+		if err := tx.WebhookSubscriptions.Delete(id); err != nil {
+			return err
+		}
+		return app.recordAudit(tx.Context(), "webhook", "deleted", id, subscription, nil)
+	})
+	if err != nil {
+		if errors.Is(err, errAuditFailed) {
+			app.writeError(w, http.StatusInternalServerError)
+			return
+		}
+		if err.Error() == "record not found" {
+			app.writeError(w, http.StatusNotFound)
+			return
+		}
+		app.logger.ErrorContext(r.Context(), "Could not delete webhook subscription", "id", id, "error", err)
+		app.writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	app.writeJSON(w, http.StatusNoContent, nil)
+}
+
+// redactWebhookSecret copies a subscription without its signing secret, which must not end up
+// in the audit log.
+func redactWebhookSecret(subscription *data.WebhookSubscription) data.WebhookSubscription {
+	redacted := *subscription
+	redacted.Secret = ""
+	return redacted
+}
+
+func validateWebhookSubscription(subscription *data.WebhookSubscription) error {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	if len(subscription.Events) == 0 {
+		return errors.New("events must list at least one event type")
+	}
+
+	for _, eventType := range subscription.Events {
+		if !data.IsWebhookEventType(eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -8,11 +8,17 @@ import (
 )
 
 func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) {
-	data := map[string]string{
+	data := map[string]any{
 		"status":      "available",
 		"environment": app.config.env,
 		"version":     version.Number,
 	}
+	if app.db != nil {
+		data["database"] = newPoolStats(app.db.Stats())
+	}
+	if app.replicaDB != nil {
+		data["replica"] = newPoolStats(app.replicaDB.Stats())
+	}
 	j, err := json.Marshal(data)
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
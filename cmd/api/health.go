@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"api.etin.dev/internal/assets"
+	"api.etin.dev/internal/version"
+)
+
+const (
+	// readinessTimeout bounds the whole readiness check, so a hanging dependency is reported as
+	// down rather than holding up the probe.
+	readinessTimeout = 2 * time.Second
+	// webhookBacklogLimit is how long a due webhook delivery may wait before the dispatcher is
+	// considered stuck.
+	webhookBacklogLimit = 15 * time.Minute
+)
+
+// Overall readiness. A server is degraded when only non-critical components are down, and
+// still takes traffic.
+const (
+	healthReady       = "ready"
+	healthDegraded    = "degraded"
+	healthUnavailable = "unavailable"
+)
+
+// healthCheck checks one dependency, returning details worth reporting either way. A critical
+// dependency being down makes the server unavailable.
+type healthCheck struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) (any, error)
+}
+
+// componentHealth is the outcome of a healthCheck, as reported by the readiness endpoint.
+type componentHealth struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+	Details   any     `json:"details,omitempty"`
+}
+
+// healthFailure is a failed check whose reason is safe to show to anyone who can reach the
+// endpoint. Other errors, which may name hosts or addresses, are only logged.
+type healthFailure struct {
+	reason string
+	err    error
+}
+
+func (f *healthFailure) Error() string {
+	if f.err == nil {
+		return f.reason
+	}
+	return f.reason + ": " + f.err.Error()
+}
+
+func (f *healthFailure) Unwrap() error { return f.err }
+
+// liveHandler reports that the process is up and serving. It checks no dependencies, so that an
+// outage elsewhere does not get the server restarted.
+func (app *application) liveHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeJSON(w, http.StatusOK, envelope{
+		"status":  "alive",
+		"version": version.Number,
+	})
+}
+
+// readyHandler checks every dependency concurrently and reports each one's status and latency.
+// It responds 503 when a critical dependency is down, so that load balancers stop sending
+// traffic until it recovers.
+func (app *application) readyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := app.healthChecks()
+	results := make([]componentHealth, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = app.runHealthCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	status := healthReady
+	components := map[string]componentHealth{}
+	for i, check := range checks {
+		result := results[i]
+		components[check.name] = result
+		if result.Status == "up" {
+			continue
+		}
+		if check.critical {
+			status = healthUnavailable
+		} else if status == healthReady {
+			status = healthDegraded
+		}
+	}
+
+	code := http.StatusOK
+	if status == healthUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+
+	app.writeJSON(w, code, envelope{
+		"status":     status,
+		"version":    version.Number,
+		"components": components,
+	})
+}
+
+func (app *application) runHealthCheck(ctx context.Context, check healthCheck) componentHealth {
+	start := time.Now()
+	details, err := check.check(ctx)
+	result := componentHealth{
+		Status:    "up",
+		Critical:  check.critical,
+		LatencyMs: milliseconds(time.Since(start)),
+		Details:   details,
+	}
+	if err == nil {
+		return result
+	}
+
+	app.logger.WarnContext(ctx, "health check failed", "component", check.name, "critical", check.critical, "error", err)
+
+	result.Status = "down"
+	var failure *healthFailure
+	switch {
+	case errors.As(err, &failure):
+		result.Error = failure.reason
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Error = "timed out"
+	default:
+		result.Error = "check failed"
+	}
+	return result
+}
+
+// healthChecks lists the dependencies the readiness endpoint checks.
+func (app *application) healthChecks() []healthCheck {
+	checks := []healthCheck{
+		{name: "database", critical: true, check: app.checkDatabase},
+		{name: "migrations", critical: true, check: app.checkMigrations},
+		{name: "assets", check: app.checkAssets},
+		{name: "webhooks", check: app.checkWebhooks},
+	}
+	if app.replicaDB != nil {
+		checks = append(checks, healthCheck{name: "replica", check: app.checkReplica})
+	}
+	return checks
+}
+
+func (app *application) checkDatabase(ctx context.Context) (any, error) {
+	if app.db == nil {
+		return nil, &healthFailure{reason: "not configured"}
+	}
+	if err := app.db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return newPoolStats(app.db.Stats()), nil
+}
+
+func (app *application) checkReplica(ctx context.Context) (any, error) {
+	if err := app.replicaDB.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return newPoolStats(app.replicaDB.Stats()), nil
+}
+
+// checkMigrations fails when the database schema is behind the one this build expects.
+func (app *application) checkMigrations(ctx context.Context) (any, error) {
+	schema, err := app.models.WithContext(ctx).Schema.Version()
+	if err != nil {
+		return nil, err
+	}
+	if !schema.Current() {
+		return schema, &healthFailure{reason: fmt.Sprintf("schema is at version %d of %d", schema.Version, schema.Latest)}
+	}
+	return schema, nil
+}
+
+// checkAssets reports the configured asset backend. Its credentials are checked when the
+// uploader is created, so this does not call out to the backend on every probe.
+func (app *application) checkAssets(ctx context.Context) (any, error) {
+	if app.assets == nil {
+		return nil, &healthFailure{reason: "no asset backend is configured"}
+	}
+	_, direct := app.assets.(assets.DirectUploader)
+	return map[string]any{
+		"backend":       app.config.assetBackend,
+		"directUploads": direct,
+	}, nil
+}
+
+// checkWebhooks fails when due deliveries have been waiting longer than webhookBacklogLimit,
+// which means the dispatcher has stopped or cannot keep up.
+func (app *application) checkWebhooks(ctx context.Context) (any, error) {
+	backlog, err := app.models.WithContext(ctx).WebhookDeliveries.Backlog()
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{
+		"due":             backlog.Due,
+		"oldestOverdueMs": milliseconds(backlog.OldestOverdue),
+	}
+	if backlog.OldestOverdue > webhookBacklogLimit {
+		return details, &healthFailure{reason: fmt.Sprintf("oldest due delivery is %s overdue", backlog.OldestOverdue.Round(time.Second))}
+	}
+	return details, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"api.etin.dev/internal/data"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type readyResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentHealth `json:"components"`
+}
+
+func newHealthApp(t *testing.T) (*application, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// The checks run concurrently, so their statements arrive in any order.
+	mock.MatchExpectationsInOrder(false)
+
+	app := &application{
+		config: config{assetBackend: "cloudinary"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.NewModels(db, log.New(io.Discard, "", 0)),
+		db:     db,
+		assets: &stubUploader{},
+	}
+	return app, mock
+}
+
+// schemaRows returns the columns that mark every migration in internal/data/schema.go as
+// applied, leaving out the tables in skip.
+func schemaRows(skip ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"table_name", "column_name"})
+	for _, column := range []string{
+		"tagged_items.itemtype", "projects.imageurl", "notes.publishedat", "assets.publicid",
+		"asset_variants.assetid", "assets.contenthash", "role_assets.alttext", "roles.slug",
+		"webhook_delivery_attempts.deliveryid", "webhook_deliveries.payload", "audit_events.requestid",
+	} {
+		table, name, _ := strings.Cut(column, ".")
+		if !slices.Contains(skip, table) {
+			rows.AddRow(table, name)
+		}
+	}
+	return rows
+}
+
+func expectBacklog(mock sqlmock.Sqlmock, due int, overdueSeconds float64) {
+	mock.ExpectQuery(`FROM webhook_deliveries WHERE status = 'pending' AND nextAttemptAt <= NOW\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "overdue"}).AddRow(due, overdueSeconds))
+}
+
+func getReady(t *testing.T, app *application) (int, readyResponse, string) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	app.readyHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/health/ready", nil))
+
+	var body readyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error decoding response %q: %s", rr.Body.String(), err)
+	}
+	return rr.Code, body, rr.Body.String()
+}
+
+func TestLiveHandler(t *testing.T) {
+	app := &application{}
+
+	rr := httptest.NewRecorder()
+	app.liveHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/health/live", nil))
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status": "alive"`) {
+		t.Fatalf("unexpected response %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestReadyHandler_Ready(t *testing.T) {
+	app, mock := newHealthApp(t)
+	mock.ExpectPing()
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnRows(schemaRows())
+	expectBacklog(mock, 2, 30)
+
+	code, body, raw := getReady(t, app)
+	if code != http.StatusOK || body.Status != healthReady {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	for _, name := range []string{"database", "migrations", "assets", "webhooks"} {
+		if component, ok := body.Components[name]; !ok || component.Status != "up" {
+			t.Errorf("expected %s to be up, got %+v", name, component)
+		}
+	}
+	if _, ok := body.Components["replica"]; ok {
+		t.Errorf("expected no replica check without a replica")
+	}
+	if !body.Components["database"].Critical || body.Components["assets"].Critical {
+		t.Errorf("unexpected criticality %+v", body.Components)
+	}
+	if details, _ := body.Components["assets"].Details.(map[string]any); details["backend"] != "cloudinary" || details["directUploads"] != false {
+		t.Errorf("unexpected asset details %v", body.Components["assets"].Details)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestReadyHandler_UnavailableWhenDatabaseIsDown(t *testing.T) {
+	app, mock := newHealthApp(t)
+	cause := errors.New("dial tcp 10.0.0.5:5432: connect: connection refused")
+	mock.ExpectPing().WillReturnError(cause)
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnError(cause)
+	mock.ExpectQuery(`FROM webhook_deliveries`).WillReturnError(cause)
+
+	code, body, raw := getReady(t, app)
+	if code != http.StatusServiceUnavailable || body.Status != healthUnavailable {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	if database := body.Components["database"]; database.Status != "down" || database.Error != "check failed" {
+		t.Errorf("unexpected database health %+v", database)
+	}
+	if strings.Contains(raw, "10.0.0.5") {
+		t.Errorf("expected the cause to be kept out of the response, got %s", raw)
+	}
+}
+
+func TestReadyHandler_UnavailableWhenSchemaIsBehind(t *testing.T) {
+	app, mock := newHealthApp(t)
+	mock.ExpectPing()
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnRows(schemaRows("audit_events"))
+	expectBacklog(mock, 0, 0)
+
+	code, body, raw := getReady(t, app)
+	if code != http.StatusServiceUnavailable || body.Status != healthUnavailable {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	if migrations := body.Components["migrations"]; migrations.Status != "down" || migrations.Error != "schema is at version 10 of 11" {
+		t.Errorf("unexpected migrations health %+v", migrations)
+	}
+}
+
+func TestReadyHandler_DegradedByNonCriticalComponents(t *testing.T) {
+	app, mock := newHealthApp(t)
+	app.assets = nil
+	mock.ExpectPing()
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnRows(schemaRows())
+	expectBacklog(mock, 4, 3600)
+
+	code, body, raw := getReady(t, app)
+	if code != http.StatusOK || body.Status != healthDegraded {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	if webhooks := body.Components["webhooks"]; webhooks.Status != "down" || webhooks.Error != "oldest due delivery is 1h0m0s overdue" {
+		t.Errorf("unexpected webhook health %+v", webhooks)
+	}
+	if assets := body.Components["assets"]; assets.Status != "down" || assets.Error != "no asset backend is configured" {
+		t.Errorf("unexpected asset health %+v", assets)
+	}
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
-	"strings"
 
 	"github.com/lib/pq"
 )
@@ -30,6 +30,12 @@ func (app *application) writeError(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
 
+// writeErrorMessage responds with a specific explanation rather than the generic status text,
+// for errors the client can act on.
+func (app *application) writeErrorMessage(w http.ResponseWriter, status int, message string) {
+	http.Error(w, message, status)
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -58,37 +64,38 @@ func (app *application) isRequestAuthenticated(r *http.Request) bool {
 	return app.sessions.validate(token)
 }
 
-func (app *application) logPostgresError(context string, err error) {
+// isUniqueViolation reports whether err was caused by a unique constraint in Postgres.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// logPostgresError logs err under msg along with args, spelling out the table, column and
+// constraint involved when it came from Postgres.
+func (app *application) logPostgresError(ctx context.Context, msg string, err error, args ...any) {
 	if app.logger == nil || err == nil {
 		return
 	}
 
 	pqErr, ok := err.(*pq.Error)
 	if !ok {
-		app.logger.Printf("%s: %v", context, err)
+		app.logger.ErrorContext(ctx, msg, append(args, "error", err)...)
 		return
 	}
 
-	details := []string{string(pqErr.Code)}
-	if pqErr.Table != "" {
-		details = append(details, "table="+pqErr.Table)
-	}
-	if pqErr.Column != "" {
-		details = append(details, "column="+pqErr.Column)
-	}
-	if pqErr.Constraint != "" {
-		details = append(details, "constraint="+pqErr.Constraint)
-	}
-	if pqErr.Detail != "" {
-		details = append(details, "detail="+pqErr.Detail)
-	}
-	if pqErr.Where != "" {
-		details = append(details, "where="+pqErr.Where)
+	args = append(args, "error", pqErr.Message, "code", string(pqErr.Code))
+	for _, field := range []struct{ key, value string }{
+		{"table", pqErr.Table},
+		{"column", pqErr.Column},
+		{"constraint", pqErr.Constraint},
+		{"detail", pqErr.Detail},
+		{"where", pqErr.Where},
+		{"internal_query", pqErr.InternalQuery},
+	} {
+		if field.value != "" {
+			args = append(args, field.key, field.value)
+		}
 	}
 
-	app.logger.Printf("%s: %s (%s)", context, pqErr.Message, strings.Join(details, ", "))
-
-	if pqErr.InternalQuery != "" {
-		app.logger.Printf("%s: internal query=%s", context, pqErr.InternalQuery)
-	}
+	app.logger.ErrorContext(ctx, msg, args...)
 }
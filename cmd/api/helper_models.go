@@ -1,22 +1,33 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 
 	"api.etin.dev/internal/data"
 )
 
 func (app *application) getModels(r *http.Request) data.Models {
-	id, ok := r.Context().Value(requestIdKey).(string)
-	if !ok {
-		id = "unknown"
+	// Model loggers cannot pass a context, so their records are bound to the request's to carry
+	// its ID, route, principal and trace.
+	handler := app.logger.Handler()
+	if _, ok := r.Context().Value(requestIdKey).(string); !ok {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("request_id", "unknown")})
 	}
+	newLogger := slog.NewLogLogger(boundHandler{handler, r.Context()}, slog.LevelInfo)
 
-	newLogger := log.New(app.logger.Writer(), fmt.Sprintf("[%s] ", id), app.logger.Flags())
-
-	models := app.models
+	// Queries run with the request context so they stop if the client goes away.
+	models := app.models.WithContext(r.Context())
+	if isPublicRead(r) {
+		ctx := r.Context()
+		if app.isRequestAuthenticated(r) {
+			// Admins previewing the site must see their own edits, which the replica may
+			// not have yet.
+			ctx = data.ReadYourWrites(ctx)
+		}
+		models = app.models.ReadOnly(ctx)
+	}
 	models.Roles.Logger = newLogger
 	models.Companies.Logger = newLogger
 	models.Notes.Logger = newLogger
@@ -25,6 +36,17 @@ func (app *application) getModels(r *http.Request) data.Models {
 	models.TagItems.Logger = newLogger
 	models.ItemNotes.Logger = newLogger
 	models.Assets.Logger = newLogger
+	models.AssetLinks.Logger = newLogger
+	models.WebhookDeliveries.Logger = newLogger
+	models.WebhookSubscriptions.Logger = newLogger
+	models.AuditEvents.Logger = newLogger
+	models.Schema.Logger = newLogger
 
 	return models
 }
+
+// isPublicRead reports whether r is a read of the public API, which may be served from the
+// read replica.
+func isPublicRead(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) && strings.HasPrefix(r.URL.Path, "/public/")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const routeKey contextKey = "route"
+
+// newLogger returns a logger writing records of at least level to w, formatted as "text" or
+// "json". Records logged with a request's context carry its ID, route, principal and trace.
+func newLogger(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds the attributes of the request in a record's context, if any.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := ctx.Value(requestIdKey).(string); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if route, ok := ctx.Value(routeKey).(string); ok {
+		record.AddAttrs(slog.String("route", route))
+	}
+	if actor, ok := ctx.Value(actorKey).(requestActor); ok {
+		record.AddAttrs(slog.String("principal", actor.kind+":"+actor.id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// boundHandler logs every record with ctx, whatever context it is given. It lets loggers that
+// cannot pass a context, such as a *log.Logger, still carry the attributes of a request.
+type boundHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h boundHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(h.ctx, level)
+}
+
+func (h boundHandler) Handle(_ context.Context, record slog.Record) error {
+	return h.Handler.Handle(h.ctx, record)
+}
+
+func (h boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return boundHandler{h.Handler.WithAttrs(attrs), h.ctx}
+}
+
+func (h boundHandler) WithGroup(name string) slog.Handler {
+	return boundHandler{h.Handler.WithGroup(name), h.ctx}
+}
+
+// resolveRoute stores the pattern of the route mux will send the request to, such as
+// GET /v1/notes/{id}, in the request context for logging and metrics. Requests matching no
+// route are given "unmatched".
+func (app *application) resolveRoute(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := context.WithValue(r.Context(), routeKey, route)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestRoute returns the route pattern stored by resolveRoute.
+func requestRoute(r *http.Request) string {
+	if route, ok := r.Context().Value(routeKey).(string); ok {
+		return route
+	}
+	return "unmatched"
+}
+
+// printf adapts logger to the Printf-style callbacks taken by background tasks such as the
+// upload janitor, logging each line at level.
+func printf(logger *slog.Logger, level slog.Level) func(format string, v ...any) {
+	return func(format string, v ...any) {
+		logger.Log(context.Background(), level, fmt.Sprintf(format, v...))
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger_AddsRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("unexpected error creating logger: %s", err)
+	}
+
+	ctx := context.WithValue(context.Background(), requestIdKey, "req-1")
+	ctx = context.WithValue(ctx, routeKey, "GET /v1/notes/{id}")
+	ctx = context.WithValue(ctx, actorKey, requestActor{kind: "admin", id: "admin@example.com"})
+
+	logger.With("component", "test").ErrorContext(ctx, "Could not retrieve note", "id", 7)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %s", buf.String(), err)
+	}
+
+	for key, expected := range map[string]any{
+		"level":      "ERROR",
+		"msg":        "Could not retrieve note",
+		"id":         float64(7),
+		"component":  "test",
+		"request_id": "req-1",
+		"route":      "GET /v1/notes/{id}",
+		"principal":  "admin:admin@example.com",
+	} {
+		if record[key] != expected {
+			t.Errorf("expected %s to be %v, got %v", key, expected, record[key])
+		}
+	}
+}
+
+func TestNewLogger_RejectsUnknownFormat(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, slog.LevelInfo, "xml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestEnableCORS_LogsOriginAtDebug(t *testing.T) {
+	for _, tt := range []struct {
+		level  slog.Level
+		logged bool
+	}{
+		{level: slog.LevelInfo, logged: false},
+		{level: slog.LevelDebug, logged: true},
+	} {
+		var buf bytes.Buffer
+		logger, err := newLogger(&buf, tt.level, "text")
+		if err != nil {
+			t.Fatalf("unexpected error creating logger: %s", err)
+		}
+		app := &application{logger: logger}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://example.com")
+		app.enableCORS(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(httptest.NewRecorder(), req)
+
+		if logged := strings.Contains(buf.String(), "origin=https://example.com"); logged != tt.logged {
+			t.Errorf("at level %s: expected origin logged to be %t, got %q", tt.level, tt.logged, buf.String())
+		}
+	}
+}
+
+func TestResolveRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/notes/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	var route string
+	handler := (&application{}).resolveRoute(mux, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route = requestRoute(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/notes/3", nil))
+	if route != "GET /v1/notes/{id}" {
+		t.Errorf("expected the route pattern, got %q", route)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if route != "unmatched" {
+		t.Errorf("expected unmatched, got %q", route)
+	}
+}
+
+func TestGetModels_LoggerCarriesRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("unexpected error creating logger: %s", err)
+	}
+	app := &application{logger: logger}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/notes", nil)
+	ctx := context.WithValue(r.Context(), requestIdKey, "req-1")
+	ctx = context.WithValue(ctx, routeKey, "POST /v1/notes")
+	ctx = context.WithValue(ctx, actorKey, requestActor{kind: "admin", id: "admin@example.com"})
+
+	app.getModels(r.WithContext(ctx)).Notes.Logger.Println("Could not insert note")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %s", buf.String(), err)
+	}
+
+	for key, expected := range map[string]any{
+		"msg":        "Could not insert note",
+		"request_id": "req-1",
+		"route":      "POST /v1/notes",
+		"principal":  "admin:admin@example.com",
+	} {
+		if record[key] != expected {
+			t.Errorf("expected %s to be %v, got %v", key, expected, record[key])
+		}
+	}
+}
@@ -1,28 +1,74 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"api.etin.dev/internal/assets"
 	"api.etin.dev/internal/data"
+	"api.etin.dev/pkg/querybuilder"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type config struct {
 	port          int
 	env           string
 	dsn           string
+	replicaDSN    string
 	adminEmail    string
 	adminPassword string
 	deployWebhook string
-	cors          struct {
+	webhooks      struct {
+		debounce    time.Duration
+		maxWait     time.Duration
+		backoff     time.Duration
+		maxAttempts int
+	}
+	assetBackend  string
+	assetVariants []assets.Variant
+	uploadPolicy  assets.ValidationPolicy
+	uploads       struct {
+		dir           string
+		maxBytes      int64
+		sessionTTL    time.Duration
+		presignExpiry time.Duration
+	}
+	db struct {
+		maxOpenConns       int
+		maxIdleConns       int
+		connMaxLifetime    time.Duration
+		connMaxIdleTime    time.Duration
+		connectTimeout     time.Duration
+		slowQueryThreshold time.Duration
+		logQueries         bool
+		statementCache     int
+	}
+	log struct {
+		level  slog.Level
+		format string
+	}
+	metrics struct {
+		addr  string
+		token string
+	}
+	tracing struct {
+		endpoint string
+		headers  string
+	}
+	cors struct {
 		trustedOrigins []string
 	}
 	cloudinary struct {
@@ -31,38 +77,116 @@ type config struct {
 		apiSecret string
 		folder    string
 	}
+	s3 struct {
+		bucket          string
+		prefix          string
+		region          string
+		endpoint        string
+		accessKeyID     string
+		secretAccessKey string
+		usePathStyle    bool
+		publicBaseURL   string
+	}
+	shutdownTimeout time.Duration
 }
 
 type application struct {
 	config     config
-	logger     *log.Logger
+	logger     *slog.Logger
 	models     data.Models
-	assetModel assetSaver
+	db         *sql.DB
+	replicaDB  *sql.DB
+	assetModel func(ctx context.Context) assetSaver
 	assets     assets.Uploader
 	swagger    []byte
 	sessions   *sessionManager
+	uploads    *uploadManager
+	webhooks   *webhookDispatcher
+	metrics    *appMetrics
+	tracer     trace.Tracer
+	audit      func(ctx context.Context) auditLog
+	transact   func(ctx context.Context, fn func(ctx context.Context) error) error
 	httpClient *http.Client
 }
 
 func main() {
+	if err := run(); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// run starts the server and blocks until it has shut down, returning rather than exiting so
+// that the database pools and background tasks are closed on the way out.
+func run() error {
 	var cfg config
 
-	var corsTrustedOrigins string
+	var corsTrustedOrigins, logLevel string
+	var assetVariants, assetVariantFormats, assetAllowedTypes string
 
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "dev", "Environment (dev|stage|prod)")
 	flag.StringVar(&cfg.dsn, "dsn", os.Getenv("WEBSITE_DB_DSN"), "PostgreSQL DSN")
+	flag.StringVar(&cfg.replicaDSN, "dsn-replica", os.Getenv("WEBSITE_DB_REPLICA_DSN"), "Optional PostgreSQL DSN of a read replica for public reads")
+	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", defaultMaxOpenConns, "Maximum open connections per database pool (0 is unlimited)")
+	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", defaultMaxIdleConns, "Maximum idle connections kept per database pool")
+	flag.DurationVar(&cfg.db.connMaxLifetime, "db-conn-max-lifetime", defaultConnMaxLifetime, "Longest a database connection is reused (0 is unlimited)")
+	flag.DurationVar(&cfg.db.connMaxIdleTime, "db-conn-max-idle-time", defaultConnMaxIdleTime, "Longest a database connection stays idle before it is closed (0 is unlimited)")
+	flag.DurationVar(&cfg.db.connectTimeout, "db-connect-timeout", defaultConnectTimeout, "How long to keep retrying the database at startup")
+	flag.DurationVar(&cfg.db.slowQueryThreshold, "db-slow-query-threshold", defaultSlowQueryThreshold, "Log queries that take at least this long (0 disables)")
+	flag.BoolVar(&cfg.db.logQueries, "db-log-queries", os.Getenv("WEBSITE_DB_LOG_QUERIES") == "true", "Log every query along with its arguments")
+	flag.IntVar(&cfg.db.statementCache, "db-statement-cache", 0, "Number of prepared statements to keep for reuse (0 disables)")
 	flag.StringVar(&cfg.adminEmail, "admin-email", os.Getenv("WEBSITE_ADMIN_EMAIL"), "Admin login email")
 	flag.StringVar(&cfg.adminPassword, "admin-password", os.Getenv("WEBSITE_ADMIN_PASSWORD"), "Admin login password")
+	flag.StringVar(&logLevel, "log-level", envOrDefault("WEBSITE_LOG_LEVEL", "info"), "Least severe level logged (debug|info|warn|error)")
+	flag.StringVar(&cfg.log.format, "log-format", envOrDefault("WEBSITE_LOG_FORMAT", "text"), "Log output format (text|json)")
+	flag.StringVar(&cfg.metrics.addr, "metrics-addr", os.Getenv("WEBSITE_METRICS_ADDR"), "Optional separate address, such as 127.0.0.1:9090, to serve /metrics on instead of the API port")
+	flag.StringVar(&cfg.metrics.token, "metrics-token", os.Getenv("WEBSITE_METRICS_TOKEN"), "Optional bearer token required to read /metrics")
+	flag.StringVar(&cfg.tracing.endpoint, "otlp-endpoint", os.Getenv("WEBSITE_OTLP_ENDPOINT"), "Optional OTLP/HTTP traces URL, such as http://localhost:4318/v1/traces, to export traces to")
+	flag.StringVar(&cfg.tracing.headers, "otlp-headers", os.Getenv("WEBSITE_OTLP_HEADERS"), "Comma separated key=value headers sent with exported traces")
 	flag.StringVar(&corsTrustedOrigins, "cors-trusted-origins", os.Getenv("WEBSITE_CORS_TRUSTED_ORIGINS"), "Space separated list of trusted CORS origins")
+	flag.StringVar(&cfg.assetBackend, "asset-backend", envOrDefault("WEBSITE_ASSET_BACKEND", "cloudinary"), "Asset storage backend (cloudinary|s3)")
+	flag.StringVar(&assetVariants, "asset-variants", envOrDefault("WEBSITE_ASSET_VARIANTS", assets.DefaultVariantSpec), "Comma separated image variants as name=WIDTHxHEIGHT[:fill|:fit]")
+	flag.StringVar(&assetVariantFormats, "asset-variant-formats", envOrDefault("WEBSITE_ASSET_VARIANT_FORMATS", assets.DefaultVariantFormats), "Comma separated alternative formats rendered for each variant (none unless set for the s3 backend, which can only encode jpeg, png and gif)")
+	flag.StringVar(&assetAllowedTypes, "asset-allowed-types", envOrDefault("WEBSITE_ASSET_ALLOWED_TYPES", assets.DefaultAllowedTypes), "Comma separated media types accepted for upload (type/* allows a family)")
+	flag.IntVar(&cfg.uploadPolicy.MaxWidth, "asset-max-width", assets.DefaultMaxDimension, "Maximum pixel width of uploaded images (0 disables)")
+	flag.IntVar(&cfg.uploadPolicy.MaxHeight, "asset-max-height", assets.DefaultMaxDimension, "Maximum pixel height of uploaded images (0 disables)")
+	flag.StringVar(&cfg.uploads.dir, "upload-dir", envOrDefault("WEBSITE_UPLOAD_DIR", filepath.Join(os.TempDir(), "website-uploads")), "Directory holding chunked uploads in progress")
+	flag.Int64Var(&cfg.uploads.maxBytes, "upload-max-bytes", defaultUploadMaxBytes, "Maximum length of a chunked upload in bytes")
+	flag.DurationVar(&cfg.uploads.sessionTTL, "upload-session-ttl", defaultUploadSessionTTL, "How long an idle chunked upload is kept before it is discarded")
+	flag.DurationVar(&cfg.uploads.presignExpiry, "asset-presign-expiry", assets.DefaultPresignExpiry, "How long signed direct-to-storage upload parameters remain valid")
 	flag.StringVar(&cfg.cloudinary.cloudName, "cloudinary-cloud-name", os.Getenv("WEBSITE_CLOUDINARY_CLOUD_NAME"), "Cloudinary cloud name")
 	flag.StringVar(&cfg.cloudinary.apiKey, "cloudinary-api-key", os.Getenv("WEBSITE_CLOUDINARY_API_KEY"), "Cloudinary API key")
 	flag.StringVar(&cfg.cloudinary.apiSecret, "cloudinary-api-secret", os.Getenv("WEBSITE_CLOUDINARY_API_SECRET"), "Cloudinary API secret")
 	flag.StringVar(&cfg.cloudinary.folder, "cloudinary-folder", os.Getenv("WEBSITE_CLOUDINARY_FOLDER"), "Optional Cloudinary folder for uploads")
+	flag.StringVar(&cfg.s3.bucket, "s3-bucket", os.Getenv("WEBSITE_S3_BUCKET"), "S3 bucket for uploads")
+	flag.StringVar(&cfg.s3.prefix, "s3-prefix", os.Getenv("WEBSITE_S3_PREFIX"), "Optional key prefix for S3 uploads")
+	flag.StringVar(&cfg.s3.region, "s3-region", os.Getenv("WEBSITE_S3_REGION"), "S3 region (defaults to us-east-1)")
+	flag.StringVar(&cfg.s3.endpoint, "s3-endpoint", os.Getenv("WEBSITE_S3_ENDPOINT"), "Optional S3-compatible endpoint URL")
+	flag.StringVar(&cfg.s3.accessKeyID, "s3-access-key-id", os.Getenv("WEBSITE_S3_ACCESS_KEY_ID"), "S3 access key ID")
+	flag.StringVar(&cfg.s3.secretAccessKey, "s3-secret-access-key", os.Getenv("WEBSITE_S3_SECRET_ACCESS_KEY"), "S3 secret access key")
+	flag.BoolVar(&cfg.s3.usePathStyle, "s3-path-style", os.Getenv("WEBSITE_S3_PATH_STYLE") == "true", "Use path-style S3 addressing")
+	flag.StringVar(&cfg.s3.publicBaseURL, "s3-public-url", os.Getenv("WEBSITE_S3_PUBLIC_URL"), "Optional public base URL for S3 objects")
 	flag.StringVar(&cfg.deployWebhook, "deploy-webhook-url", os.Getenv("WEBSITE_DEPLOY_WEBHOOK_URL"), "Optional URL to trigger frontend deployments")
+	flag.DurationVar(&cfg.webhooks.debounce, "deploy-webhook-debounce", defaultWebhookDebounce, "Quiet period after a change before the deploy webhook is called")
+	flag.DurationVar(&cfg.webhooks.maxWait, "deploy-webhook-max-wait", defaultWebhookMaxWait, "Longest a deploy webhook call is postponed by further changes")
+	flag.DurationVar(&cfg.webhooks.backoff, "deploy-webhook-backoff", defaultWebhookBackoff, "Delay before the first deploy webhook retry, doubled after every failure")
+	flag.IntVar(&cfg.webhooks.maxAttempts, "deploy-webhook-max-attempts", defaultWebhookMaxAttempts, "Attempts made to deliver a deploy webhook call before giving up")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "How long in-flight requests and background tasks get to finish on SIGINT or SIGTERM")
+
+	if err := setFlagsFromEnv(flag.CommandLine, poolEnv); err != nil {
+		return err
+	}
 	flag.Parse()
 
-	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	if err := cfg.log.level.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("invalid -log-level: %w", err)
+	}
+	logger, err := newLogger(os.Stdout, cfg.log.level, cfg.log.format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
 
 	cfg.cors.trustedOrigins = parseTrustedOrigins(corsTrustedOrigins)
 	if len(cfg.cors.trustedOrigins) == 0 {
@@ -73,52 +197,148 @@ func main() {
 		cfg.cors.trustedOrigins[i] = normalizeOrigin(origin)
 	}
 
+	// The S3 backend encodes variants itself and cannot produce the default webp and avif
+	// copies, so it only renders alternative formats that are asked for.
+	formatsSet := os.Getenv("WEBSITE_ASSET_VARIANT_FORMATS") != ""
+	flag.Visit(func(f *flag.Flag) { formatsSet = formatsSet || f.Name == "asset-variant-formats" })
+	if cfg.assetBackend == "s3" && !formatsSet {
+		assetVariantFormats = ""
+	}
+
+	cfg.assetVariants, err = assets.ParseVariants(assetVariants, assetVariantFormats)
+	if err != nil {
+		return err
+	}
+	if cfg.assetBackend == "s3" {
+		if err := assets.CheckLocalVariants(cfg.assetVariants); err != nil {
+			return fmt.Errorf("invalid -asset-variant-formats for the s3 asset backend: %w", err)
+		}
+	}
+
+	cfg.uploadPolicy.AllowedTypes = assets.ParseAllowedTypes(assetAllowedTypes)
+
 	if cfg.adminEmail == "" || cfg.adminPassword == "" {
-		logger.Fatal("Admin credentials must be provided")
+		return errors.New("Admin credentials must be provided")
 	}
 
-	if cfg.cloudinary.cloudName == "" {
-		logger.Fatal("Cloudinary cloud name must be provided")
+	db, err := openDB(cfg, cfg.dsn)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	if cfg.cloudinary.apiKey == "" {
-		logger.Fatal("Cloudinary API key must be provided")
+	if err := waitForDB(db, cfg.db.connectTimeout, printf(logger, slog.LevelWarn)); err != nil {
+		return err
 	}
 
-	if cfg.cloudinary.apiSecret == "" {
-		logger.Fatal("Cloudinary API secret must be provided")
+	logger.Info("database connection pool established")
+
+	uploader, err := newUploader(cfg)
+	if err != nil {
+		return err
 	}
 
-	db, err := openDB(cfg.dsn)
+	uploads, err := newUploadManager(cfg.uploads.dir, cfg.uploads.sessionTTL, cfg.uploads.maxBytes)
 	if err != nil {
-		logger.Fatal(err)
+		return err
+	}
+	var discardDirect func(context.Context, assets.DirectUpload) error
+	if direct, ok := uploader.(assets.DirectUploader); ok {
+		discardDirect = direct.DeleteUpload
+	}
+	background := []func(){uploads.startJanitor(uploadJanitorInterval, discardDirect, printf(logger, slog.LevelInfo))}
+
+	var executor querybuilder.Executor = db
+	if cfg.db.statementCache > 0 {
+		statements := querybuilder.NewStatementCache(db, cfg.db.statementCache)
+		defer statements.Close()
+		executor = statements
 	}
-	defer db.Close()
 
-	logger.Printf("database connection pool established")
+	metrics := newAppMetrics()
+	metrics.watchPool("primary", db)
 
-	uploader, err := assets.NewCloudinaryUploader(
-		cfg.cloudinary.cloudName,
-		cfg.cloudinary.apiKey,
-		cfg.cloudinary.apiSecret,
-		cfg.cloudinary.folder,
-	)
+	tracerProvider, err := newTracerProvider(context.Background(), cfg, logger)
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 
-	models := data.NewModels(db, logger)
+	hooks := append(queryHooks(cfg, logger), metrics.queryHook())
+	var tracer trace.Tracer
+	if tracerProvider != nil {
+		// Deferred so that spans ended while shutting down are exported too.
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			tracerProvider.Shutdown(ctx)
+		}()
+
+		tracer = tracerProvider.Tracer(tracingServiceName)
+		hooks = append(hooks, queryTracer(tracer))
+	}
+	models := data.NewModels(executor, slog.NewLogLogger(logger.Handler(), slog.LevelInfo), hooks...)
+
+	var replicaDB *sql.DB
+	if cfg.replicaDSN != "" {
+		replicaDB, err = openDB(cfg, cfg.replicaDSN)
+		if err != nil {
+			return err
+		}
+		defer replicaDB.Close()
+
+		var replicaExecutor querybuilder.Executor = replicaDB
+		if cfg.db.statementCache > 0 {
+			statements := querybuilder.NewStatementCache(replicaDB, cfg.db.statementCache)
+			defer statements.Close()
+			replicaExecutor = statements
+		}
+
+		// An unreachable replica is not fatal; public reads go to the primary until it answers.
+		replica := data.NewReplica(replicaExecutor, replicaDB)
+		if err := replica.Check(context.Background()); err != nil {
+			logger.Warn("Read replica is unavailable, reading from the primary", "error", err)
+		} else {
+			logger.Info("read replica connection pool established")
+		}
+		background = append(background, replica.Monitor(replicaCheckInterval, printf(logger, slog.LevelWarn)))
+
+		models = models.WithReplica(replica)
+		metrics.watchPool("replica", replicaDB)
+	}
 
 	app := &application{
 		config:     cfg,
 		logger:     logger,
 		models:     models,
-		assetModel: models.Assets,
+		db:         db,
+		replicaDB:  replicaDB,
+		assetModel: func(ctx context.Context) assetSaver { return models.WithContext(ctx).Assets },
 		assets:     uploader,
 		swagger:    embeddedSwagger,
 		sessions:   newSessionManager(24 * time.Hour),
+		uploads:    uploads,
+		audit:      func(ctx context.Context) auditLog { return models.WithContext(ctx).AuditEvents },
+		transact:   models.Transact,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		metrics:    metrics,
+		tracer:     tracer,
+	}
+	metrics.watchSessions(app.sessions)
+
+	app.webhooks = &webhookDispatcher{
+		outbox:      func(ctx context.Context) webhookOutbox { return models.WithContext(ctx).WebhookDeliveries },
+		url:         cfg.deployWebhook,
+		client:      app.httpClient,
+		logger:      logger,
+		debounce:    cfg.webhooks.debounce,
+		maxWait:     cfg.webhooks.maxWait,
+		backoff:     cfg.webhooks.backoff,
+		maxAttempts: cfg.webhooks.maxAttempts,
+		now:         time.Now,
+		metrics:     metrics,
+		tracer:      tracer,
 	}
+	background = append(background, app.webhooks.start(webhookPollInterval))
 
 	addr := fmt.Sprintf(":%d", cfg.port)
 
@@ -130,20 +350,99 @@ func main() {
 		WriteTimeout: 10 * time.Minute,
 	}
 
-	logger.Printf("starting %s server on %s", cfg.env, addr)
-	err = srv.ListenAndServe()
-	logger.Fatal(err)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.metrics.addr != "" {
+		stopMetrics, err := app.serveMetrics(cfg.metrics.addr)
+		if err != nil {
+			return err
+		}
+		background = append(background, stopMetrics)
+	} else if cfg.metrics.token == "" {
+		logger.Warn("Metrics are not served, set -metrics-addr or -metrics-token to expose them")
+	}
+
+	logger.Info("starting server", "env", cfg.env, "addr", addr)
+	return app.serve(ctx, srv, ln, cfg.shutdownTimeout, background...)
 }
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, err
+// replicaCheckInterval is how often the read replica is pinged to decide whether to read from it.
+const replicaCheckInterval = 5 * time.Second
+
+// defaultSlowQueryThreshold is how long a query may take before it is logged as slow.
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// queryHooks counts the queries run for each request and logs slow ones, or every one when
+// query logging is on, along with the request that ran them.
+func queryHooks(cfg config, logger *slog.Logger) []querybuilder.Hook {
+	hooks := []querybuilder.Hook{querybuilder.CountQueries}
+
+	threshold := cfg.db.slowQueryThreshold
+	if cfg.db.logQueries {
+		threshold = 0
+	} else if threshold <= 0 {
+		return hooks
+	}
+
+	level := slog.LevelWarn
+	if cfg.db.logQueries {
+		level = slog.LevelInfo
+	}
+	logf := func(ctx context.Context, format string, v ...any) {
+		logger.Log(ctx, level, fmt.Sprintf(format, v...))
 	}
-	if err = db.Ping(); err != nil {
-		return nil, err
+
+	return append(hooks, querybuilder.SlowQueryLogger(threshold, logf))
+}
+
+func newUploader(cfg config) (assets.Uploader, error) {
+	switch cfg.assetBackend {
+	case "cloudinary":
+		if cfg.cloudinary.cloudName == "" {
+			return nil, errors.New("Cloudinary cloud name must be provided")
+		}
+
+		if cfg.cloudinary.apiKey == "" {
+			return nil, errors.New("Cloudinary API key must be provided")
+		}
+
+		if cfg.cloudinary.apiSecret == "" {
+			return nil, errors.New("Cloudinary API secret must be provided")
+		}
+
+		return assets.NewCloudinaryUploader(
+			cfg.cloudinary.cloudName,
+			cfg.cloudinary.apiKey,
+			cfg.cloudinary.apiSecret,
+			cfg.cloudinary.folder,
+		)
+	case "s3":
+		return assets.NewS3Uploader(assets.S3Config{
+			Bucket:          cfg.s3.bucket,
+			Prefix:          cfg.s3.prefix,
+			Region:          cfg.s3.region,
+			Endpoint:        cfg.s3.endpoint,
+			AccessKeyID:     cfg.s3.accessKeyID,
+			SecretAccessKey: cfg.s3.secretAccessKey,
+			UsePathStyle:    cfg.s3.usePathStyle,
+			PublicBaseURL:   cfg.s3.publicBaseURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown asset backend %q", cfg.assetBackend)
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-	return db, nil
+	return fallback
 }
 
 func parseTrustedOrigins(input string) []string {
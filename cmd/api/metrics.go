@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api.etin.dev/internal/data"
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// appMetrics holds the metrics served at /metrics. Its methods do nothing on a nil receiver, so
+// handlers and tests can run without it.
+type appMetrics struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	queryDuration    *prometheus.HistogramVec
+	webhookAttempts  *prometheus.CounterVec
+	assetUploads     *prometheus.CounterVec
+	assetUploadBytes *prometheus.CounterVec
+	sessionsCreated  prometheus.Counter
+}
+
+func newAppMetrics() *appMetrics {
+	registry := prometheus.NewRegistry()
+	factory := promauto.With(registry)
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return &appMetrics{
+		registry: registry,
+		handler:  promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}),
+		requests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "http_request_duration_seconds",
+			Help: "Time taken to serve HTTP requests, by route pattern.",
+		}, []string{"method", "route"}),
+		queryDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "db_query_duration_seconds",
+			Help: "Time taken by database statements, by kind and outcome.",
+		}, []string{"statement", "outcome"}),
+		webhookAttempts: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "webhook_delivery_attempts_total",
+			Help: "Webhook delivery attempts, by kind and outcome.",
+		}, []string{"kind", "outcome"}),
+		assetUploads: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "asset_uploads_total",
+			Help: "Uploaded assets, by outcome.",
+		}, []string{"outcome"}),
+		assetUploadBytes: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "asset_upload_bytes_total",
+			Help: "Bytes of uploaded assets, by outcome.",
+		}, []string{"outcome"}),
+		sessionsCreated: factory.NewCounter(prometheus.CounterOpts{
+			Name: "admin_sessions_created_total",
+			Help: "Admin sessions created by logging in.",
+		}),
+	}
+}
+
+// observeRequest records a served request. route is the pattern it matched, so that paths with
+// IDs in them do not each get a series of their own.
+func (m *appMetrics) observeRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	if route == "" {
+		route = "unmatched"
+	}
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// queryHook returns a hook that records how long each statement took.
+func (m *appMetrics) queryHook() querybuilder.Hook {
+	return querybuilder.HookFunc(func(ctx context.Context, event querybuilder.QueryEvent) {
+		outcome := "ok"
+		if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+			outcome = "error"
+		}
+		m.queryDuration.WithLabelValues(statementKind(event.SQL), outcome).Observe(event.Duration.Seconds())
+	})
+}
+
+// statementKind is the lowercased first keyword of query, such as select or insert.
+func statementKind(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+
+	switch kind := strings.ToLower(fields[0]); kind {
+	case "select", "insert", "update", "delete", "with":
+		return kind
+	default:
+		return "other"
+	}
+}
+
+// observeWebhookAttempt records the outcome of a delivery attempt: delivered, retrying or failed.
+func (m *appMetrics) observeWebhookAttempt(delivery *data.WebhookDelivery, status string) {
+	if m == nil {
+		return
+	}
+
+	outcome := status
+	if status == data.DeliveryPending {
+		outcome = "retrying"
+	}
+	m.webhookAttempts.WithLabelValues(webhookKind(delivery), outcome).Inc()
+}
+
+// observeUpload records an uploaded asset of size bytes, given the status ingestAsset answered
+// with.
+func (m *appMetrics) observeUpload(size int64, status int) {
+	if m == nil {
+		return
+	}
+
+	var outcome string
+	switch {
+	case status == http.StatusCreated:
+		outcome = "stored"
+	case status == http.StatusOK:
+		outcome = "duplicate"
+	case status < 500:
+		outcome = "rejected"
+	default:
+		outcome = "failed"
+	}
+
+	m.assetUploads.WithLabelValues(outcome).Inc()
+	m.assetUploadBytes.WithLabelValues(outcome).Add(float64(size))
+}
+
+func (m *appMetrics) observeSessionCreated() {
+	if m == nil {
+		return
+	}
+	m.sessionsCreated.Inc()
+}
+
+// watchPool reports db's statistics as the go_sql_* metrics, labelled with pool as db_name.
+func (m *appMetrics) watchPool(pool string, db *sql.DB) {
+	if m == nil || db == nil {
+		return
+	}
+
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, pool))
+}
+
+// watchSessions reports how many admin sessions are active whenever metrics are collected.
+func (m *appMetrics) watchSessions(sessions *sessionManager) {
+	if m == nil || sessions == nil {
+		return
+	}
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "admin_sessions_active",
+		Help: "Admin sessions that have not expired or been revoked.",
+	}, func() float64 {
+		return float64(sessions.active())
+	}))
+}
+
+// recordMetrics records every request, labelled with the route pattern stored by resolveRoute.
+func (app *application) recordMetrics(next http.Handler) http.Handler {
+	if app.metrics == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := newStatusRecorder(w)
+		start := time.Now()
+
+		next.ServeHTTP(recorder, r)
+
+		app.metrics.observeRequest(r.Method, requestRoute(r), recorder.status, time.Since(start))
+	})
+}
+
+// metricsHandler serves the metrics, requiring the configured bearer token if there is one.
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.metrics == nil {
+		app.writeError(w, http.StatusNotFound)
+		return
+	}
+
+	if app.config.metrics.token != "" {
+		token, err := parseBearerToken(r.Header.Get("Authorization"))
+		if err != nil || !secureCompare(token, app.config.metrics.token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.writeError(w, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	app.metrics.handler.ServeHTTP(w, r)
+}
+
+// serveMetrics serves /metrics on its own at addr, so that it can be kept off the public port,
+// and returns a function that shuts that server down.
+func (app *application) serveMetrics(addr string) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", app.metricsHandler)
+	srv := &http.Server{Handler: mux, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			app.logger.Error("Metrics server stopped", "error", err)
+		}
+	}()
+
+	app.logger.Info("serving metrics", "addr", addr)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}, nil
+}
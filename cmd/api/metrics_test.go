@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api.etin.dev/internal/data"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func scrapeMetrics(t *testing.T, handler http.Handler, token string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.String()
+}
+
+func TestRecordMetrics_LabelsRequestsByRoutePattern(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), metrics: newAppMetrics()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "404" {
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("GET /metrics", app.metricsHandler)
+	handler := app.resolveRoute(mux, app.recordMetrics(mux))
+
+	for _, path := range []string{"/v1/notes/1", "/v1/notes/2", "/v1/notes/404", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	status, body := scrapeMetrics(t, handler, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="GET /v1/notes/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="GET /v1/notes/{id}",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="GET /v1/notes/{id}"} 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "/v1/notes/1") {
+		t.Errorf("expected raw paths to stay out of the labels:\n%s", body)
+	}
+}
+
+func TestMetricsHandler_RequiresTokenWhenConfigured(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), metrics: newAppMetrics()}
+	app.config.metrics.token = "scrape-secret"
+	handler := http.HandlerFunc(app.metricsHandler)
+
+	if status, _ := scrapeMetrics(t, handler, ""); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", status)
+	}
+	if status, _ := scrapeMetrics(t, handler, "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with the wrong token, got %d", status)
+	}
+	if status, _ := scrapeMetrics(t, handler, "scrape-secret"); status != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", status)
+	}
+}
+
+func TestAppMetrics_ReportsPoolsSessionsAndWebhooks(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	sessions := newSessionManager(time.Hour)
+	if _, _, err := sessions.create("admin@example.com"); err != nil {
+		t.Fatalf("unexpected error creating session: %s", err)
+	}
+
+	m := newAppMetrics()
+	m.watchPool("primary", db)
+	m.watchSessions(sessions)
+
+	subscription := int64(3)
+	m.observeWebhookAttempt(&data.WebhookDelivery{}, data.DeliveryDelivered)
+	m.observeWebhookAttempt(&data.WebhookDelivery{SubscriptionID: &subscription}, data.DeliveryPending)
+	m.observeUpload(2048, http.StatusCreated)
+
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), metrics: m}
+	_, body := scrapeMetrics(t, http.HandlerFunc(app.metricsHandler), "")
+
+	for _, line := range []string{
+		`go_sql_max_open_connections{db_name="primary"} 7`,
+		`admin_sessions_active 1`,
+		`webhook_delivery_attempts_total{kind="deploy",outcome="delivered"} 1`,
+		`webhook_delivery_attempts_total{kind="subscription",outcome="retrying"} 1`,
+		`asset_upload_bytes_total{outcome="stored"} 2048`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestRoutes_ServeMetricsOnThePublicPortOnlyWithAToken(t *testing.T) {
+	app := &application{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:  newAppMetrics(),
+		sessions: newSessionManager(time.Hour),
+	}
+
+	if status, _ := scrapeMetrics(t, app.routes(), ""); status != http.StatusNotFound {
+		t.Errorf("expected 404 without a token or metrics address, got %d", status)
+	}
+
+	app.config.metrics.addr = "127.0.0.1:9090"
+	app.config.metrics.token = "scrape-secret"
+	if status, _ := scrapeMetrics(t, app.routes(), "scrape-secret"); status != http.StatusNotFound {
+		t.Errorf("expected 404 on the public port when a metrics address is set, got %d", status)
+	}
+
+	app.config.metrics.addr = ""
+	if status, _ := scrapeMetrics(t, app.routes(), ""); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the token, got %d", status)
+	}
+	if status, _ := scrapeMetrics(t, app.routes(), "scrape-secret"); status != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", status)
+	}
+}
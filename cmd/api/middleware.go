@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	"api.etin.dev/pkg/querybuilder"
 	"github.com/google/uuid"
 )
 
@@ -29,32 +31,50 @@ func (app *application) requestID(next http.Handler) http.Handler {
 	})
 }
 
+// logRequest logs one record per request once it has been served, with its outcome and the
+// database work it took.
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id, ok := r.Context().Value(requestIdKey).(string)
-		if !ok {
-			id = "unknown"
-		}
-
-		app.logger.Printf("[%s] %s - %s %s %s", id, r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
-
-		next.ServeHTTP(w, r)
+		ctx, queries := querybuilder.WithQueryStats(r.Context())
+		recorder := newStatusRecorder(w)
+		start := time.Now()
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		app.logger.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"path", r.URL.RequestURI(),
+			"proto", r.Proto,
+			"remote_addr", r.RemoteAddr,
+			"status", recorder.status,
+			"duration_ms", milliseconds(time.Since(start)),
+			"bytes", recorder.bytes,
+			"queries", queries.Queries(),
+			"query_duration_ms", milliseconds(queries.Duration()),
+		)
 	})
 }
 
+// milliseconds returns d in milliseconds, to the microsecond, so durations read the same in
+// text and JSON logs.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Origin")
 		w.Header().Add("Vary", "Access-Control-Request-Method")
 		w.Header().Add("Vary", "Access-Control-Request-Headers")
 
-		app.logger.Printf("CORS origin: %s", r.Header.Get("Origin"))
+		app.logger.DebugContext(r.Context(), "CORS origin", "origin", r.Header.Get("Origin"))
 
 		if origin := r.Header.Get("Origin"); origin != "" {
 			if allowedOrigin, ok := app.getAllowedOrigin(origin); ok {
 				w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Upload-Offset, Upload-Checksum")
+				w.Header().Set("Access-Control-Expose-Headers", "Location, Upload-Offset, Upload-Length")
 				if allowedOrigin != "*" {
 					w.Header().Set("Access-Control-Allow-Credentials", "true")
 				}
@@ -78,6 +98,7 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 type statusRecorder struct {
 	http.ResponseWriter
 	status      int
+	bytes       int64
 	wroteHeader bool
 }
 
@@ -97,7 +118,9 @@ func (sr *statusRecorder) Write(b []byte) (int, error) {
 	if !sr.wroteHeader {
 		sr.WriteHeader(http.StatusOK)
 	}
-	return sr.ResponseWriter.Write(b)
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += int64(n)
+	return n, err
 }
 
 func (sr *statusRecorder) Flush() {
@@ -118,7 +141,7 @@ func (app *application) deployWebhook(next http.Handler) http.Handler {
 		}
 
 		if recorder.status >= 200 && recorder.status < 300 {
-			app.triggerDeployWebhook()
+			app.triggerDeployWebhook(r.Context())
 		}
 	})
 }
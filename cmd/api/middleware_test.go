@@ -2,11 +2,15 @@ package main
 
 import (
 	"bytes"
-	"log"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
 )
 
 func TestGetAllowedOrigin_NormalizedMatch(t *testing.T) {
@@ -31,12 +35,15 @@ func TestGetAllowedOrigin_NormalizedMatch(t *testing.T) {
 
 func TestLogRequest(t *testing.T) {
 	var buf bytes.Buffer
-	app := &application{
-		logger: log.New(&buf, "", 0),
+	logger, err := newLogger(&buf, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("unexpected error creating logger: %s", err)
 	}
+	app := &application{logger: logger}
 
 	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
 	})
 
 	// Wrap with requestID first, as logRequest expects the ID in context
@@ -48,17 +55,49 @@ func TestLogRequest(t *testing.T) {
 
 	handler.ServeHTTP(w, req)
 
-	logOutput := buf.String()
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %s", buf.String(), err)
+	}
 
-	// Check for the request ID format in the log
-	// We don't know the UUID, but we know it should start with "[" and contain "]"
-	if !strings.Contains(logOutput, "[") || !strings.Contains(logOutput, "]") {
-		t.Errorf("expected log output to contain request ID brackets, got %q", logOutput)
+	if record["request_id"] != w.Header().Get("X-Request-ID") {
+		t.Errorf("expected request_id %q, got %v", w.Header().Get("X-Request-ID"), record["request_id"])
+	}
+	for key, expected := range map[string]any{
+		"msg":         "request",
+		"method":      "GET",
+		"path":        "/test/url",
+		"remote_addr": "1.2.3.4:1234",
+		"status":      float64(200),
+		"bytes":       float64(5),
+	} {
+		if record[key] != expected {
+			t.Errorf("expected %s to be %v, got %v", key, expected, record[key])
+		}
 	}
+}
 
-	expectedLogPart := "1.2.3.4:1234 - HTTP/1.1 GET /test/url"
-	if !strings.Contains(logOutput, expectedLogPart) {
-		t.Errorf("expected log output to contain %q, got %q", expectedLogPart, logOutput)
+func TestLogRequest_ReportsQueries(t *testing.T) {
+	var buf bytes.Buffer
+	app := &application{
+		logger: slog.New(slog.NewTextHandler(&buf, nil)),
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			querybuilder.CountQueries.AfterQuery(r.Context(), querybuilder.QueryEvent{Duration: time.Millisecond})
+		}
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := app.requestID(app.logRequest(next))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/public/notes", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	logOutput := buf.String()
+	if !strings.Contains(logOutput, "status=418") || !strings.Contains(logOutput, "queries=3 query_duration_ms=3") {
+		t.Errorf("expected log output to report the status and queries, got %q", logOutput)
 	}
 }
 
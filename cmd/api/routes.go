@@ -17,10 +17,34 @@ func (app *application) routes() http.Handler {
 	mux.HandleFunc("GET /public/v1/roles/{idOrSlug}", app.getPublicRoleHandler)
 	mux.HandleFunc("GET /public/v1/notes/{idOrSlug}", app.getPublicNoteHandler)
 	mux.HandleFunc("GET /v1/healthcheck", app.healthcheck)
+	mux.HandleFunc("GET /v1/health/live", app.liveHandler)
+	mux.HandleFunc("GET /v1/health/ready", app.readyHandler)
+	// /metrics is only served on the public port behind the scrape token; otherwise it is
+	// served on its own address, if one is set.
+	if app.config.metrics.addr == "" && app.config.metrics.token != "" {
+		mux.HandleFunc("GET /metrics", app.metricsHandler)
+	}
 	mux.HandleFunc("POST /v1/admin/login", app.adminLoginHandler)
 	mux.HandleFunc("POST /v1/admin/logout", app.adminLogoutHandler)
 
 	mux.HandleFunc("POST /v1/assets", app.getCreateAssetsHandler)
+	mux.HandleFunc("GET /v1/assets/duplicates", app.getAssetDuplicatesHandler)
+	mux.HandleFunc("POST /v1/assets/presign", app.presignAssetHandler)
+	mux.HandleFunc("POST /v1/assets/confirm", app.confirmAssetHandler)
+	mux.HandleFunc("POST /v1/uploads", app.createUploadHandler)
+	mux.HandleFunc("GET /v1/uploads/{id}", app.getUploadHandler)
+	mux.HandleFunc("PATCH /v1/uploads/{id}", app.appendUploadHandler)
+	mux.HandleFunc("POST /v1/uploads/{id}/complete", app.completeUploadHandler)
+	mux.HandleFunc("DELETE /v1/uploads/{id}", app.deleteUploadHandler)
+	mux.HandleFunc("GET /v1/audit", app.getAuditEventsHandler)
+	mux.HandleFunc("GET /v1/webhooks", app.getWebhooksHandler)
+	mux.HandleFunc("POST /v1/webhooks", app.createWebhookHandler)
+	mux.HandleFunc("GET /v1/webhooks/{id}", app.getWebhookHandler)
+	mux.HandleFunc("PUT /v1/webhooks/{id}", app.updateWebhookHandler)
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", app.deleteWebhookHandler)
+	mux.HandleFunc("GET /v1/webhook-deliveries", app.getWebhookDeliveriesHandler)
+	mux.HandleFunc("GET /v1/webhook-deliveries/{id}", app.getWebhookDeliveryHandler)
+	mux.HandleFunc("POST /v1/webhook-deliveries/{id}/replay", app.replayWebhookDeliveryHandler)
 
 	mux.Handle("GET /v1/roles", app.deployWebhook(http.HandlerFunc(app.getRolesHandler)))
 	mux.Handle("POST /v1/roles", app.deployWebhook(http.HandlerFunc(app.createRoleHandler)))
@@ -47,9 +71,14 @@ func (app *application) routes() http.Handler {
 	mux.Handle("DELETE /v1/item-notes/{id}", app.deployWebhook(http.HandlerFunc(app.deleteItemNoteHandler)))
 	mux.HandleFunc("GET /v1/item-notes/items/{itemType}/{itemId}", app.getNotesForItemHandler)
 
-	mux.HandleFunc("POST /v1/{contentType}/{id}/notes", app.getCreateContentNoteHandler)
+	mux.Handle("POST /v1/{contentType}/{id}/notes", app.deployWebhook(http.HandlerFunc(app.getCreateContentNoteHandler)))
 	mux.HandleFunc("GET /v1/{contentType}/{id}/notes", app.getContentNotesHandler)
 	// mux.HandleFunc("GET /v1/{contentType}/notes", app.getAllContentNotesHandler) -- Conflicts with GET /v1/roles/{id}
+	mux.HandleFunc("GET /v1/{contentType}/{id}/assets", app.getAssetLinksHandler)
+	mux.Handle("POST /v1/{contentType}/{id}/assets", app.deployWebhook(http.HandlerFunc(app.createAssetLinkHandler)))
+	mux.Handle("PUT /v1/{contentType}/{id}/assets/order", app.deployWebhook(http.HandlerFunc(app.reorderAssetLinksHandler)))
+	mux.Handle("PUT /v1/{contentType}/{id}/assets/{assetId}", app.deployWebhook(http.HandlerFunc(app.updateAssetLinkHandler)))
+	mux.Handle("DELETE /v1/{contentType}/{id}/assets/{assetId}", app.deployWebhook(http.HandlerFunc(app.deleteAssetLinkHandler)))
 	mux.HandleFunc("GET /v1/roles/notes", app.getAllContentNotesHandler)
 	mux.HandleFunc("GET /v1/projects/notes", app.getAllContentNotesHandler)
 
@@ -72,5 +101,5 @@ func (app *application) routes() http.Handler {
 	mux.Handle("PUT /v1/tags/{id}", app.deployWebhook(http.HandlerFunc(app.updateTagHandler)))
 	mux.Handle("DELETE /v1/tags/{id}", app.deployWebhook(http.HandlerFunc(app.deleteTagHandler)))
 
-	return app.requestID(app.logRequest(app.enableCORS(mux)))
+	return app.requestID(app.resolveRoute(mux, app.traceRequest(app.identifyActor(app.logRequest(app.recordMetrics(app.enableCORS(mux)))))))
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultShutdownTimeout is how long in-flight requests and background tasks get to finish
+// once the server is asked to stop.
+const defaultShutdownTimeout = 20 * time.Second
+
+// backgroundStopGrace is the least time background tasks get to stop once requests have used
+// up the shutdown timeout, so that none is left running while the database pools close.
+const backgroundStopGrace = 2 * time.Second
+
+// serve runs srv on ln until ctx is done. It then stops accepting connections, waits for the
+// requests in flight, and only then calls every function in background, such as the stop
+// functions of the webhook dispatcher and the upload janitor, so that work queued by the last
+// requests is not lost. All of this must happen within timeout. Background tasks are stopped
+// even when requests outlast it.
+func (app *application) serve(ctx context.Context, srv *http.Server, ln net.Listener, timeout time.Duration, background ...func()) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	app.logger.Info("shutting down server, waiting for requests and background tasks", "timeout", timeout)
+
+	deadline := time.Now().Add(timeout)
+	shutdownCtx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("shut down server: %w", err))
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), max(time.Until(deadline), backgroundStopGrace))
+	defer cancelStop()
+	if err := stopBackground(stopCtx, background); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	app.logger.Info("stopped server")
+	return nil
+}
+
+// stopBackground calls every stop function at once and waits for them until ctx is done.
+func stopBackground(ctx context.Context, background []func()) error {
+	var wg sync.WaitGroup
+	for _, stop := range background {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop()
+		}()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.New("shut down server: background tasks did not finish in time")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startTestServer serves handler on a local port with app.serve, returning the server's
+// address and a channel that receives serve's result.
+func startTestServer(t *testing.T, ctx context.Context, handler http.Handler, timeout time.Duration, background ...func()) (string, <-chan error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %s", err)
+	}
+
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	done := make(chan error, 1)
+	go func() {
+		done <- app.serve(ctx, &http.Server{Handler: handler}, ln, timeout, background...)
+	}()
+
+	return "http://" + ln.Addr().String(), done
+}
+
+func TestServe_DrainsRequestsBeforeStoppingBackgroundTasks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+		record("request handled")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, done := startTestServer(t, ctx, handler, 5*time.Second, func() { record("webhooks stopped") }, func() { record("janitor stopped") })
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(addr)
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected serve to wait for the request in flight, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := http.Get(addr); err == nil {
+		t.Fatalf("expected new connections to be refused while shutting down")
+	}
+
+	close(release)
+
+	if body := <-response; body != "done" {
+		t.Fatalf("expected the request in flight to complete, got %q", body)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error shutting down: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 3 || events[0] != "request handled" {
+		t.Fatalf("expected background tasks to stop after the request finished, got %v", events)
+	}
+}
+
+func TestServe_GivesUpOnRequestsPastTheTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startTestServer(t, ctx, handler, 50*time.Millisecond)
+
+	go http.Get(addr)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected an error when requests outlast the timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected serve to give up after the timeout")
+	}
+}
+
+func TestServe_StopsBackgroundTasksWhenRequestsOutlastTheTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	var stopped atomic.Int32
+	stop := func() {
+		time.Sleep(100 * time.Millisecond)
+		stopped.Add(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startTestServer(t, ctx, handler, 50*time.Millisecond, stop, stop)
+
+	go http.Get(addr)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected an error when requests outlast the timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected serve to give up after the timeout")
+	}
+
+	if n := stopped.Load(); n != 2 {
+		t.Fatalf("expected both background tasks to have stopped before serve returned, %d did", n)
+	}
+}
+
+func TestServe_GivesUpOnBackgroundTasksPastTheTimeout(t *testing.T) {
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, done := startTestServer(t, ctx, http.NotFoundHandler(), 50*time.Millisecond, func() { <-stuck })
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected an error when a background task outlasts the timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected serve to give up after the timeout")
+	}
+}
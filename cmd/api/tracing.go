@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"api.etin.dev/internal/assets"
+	"api.etin.dev/pkg/querybuilder"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracingServiceName is the service.name spans are reported under, and the name of the tracer
+// that records them.
+const tracingServiceName = "api.etin.dev"
+
+// tracePropagator reads and writes W3C traceparent headers.
+var tracePropagator = propagation.TraceContext{}
+
+// startSpan starts a span with tracer, or a span that records nothing when tracer is nil, as it
+// is when tracing is off.
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if tracer == nil {
+		tracer = noop.Tracer{}
+	}
+	return tracer.Start(ctx, name, options...)
+}
+
+// recordSpanError marks span as failed with err. A nil err is ignored.
+func recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// traceRequest starts a server span for every request, named after the route pattern stored by
+// resolveRoute and continuing the trace in the request's traceparent header, if any. The span
+// carries the request ID so that a trace can be found from a log line and the other way round.
+func (app *application) traceRequest(next http.Handler) http.Handler {
+	if app.tracer == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := requestRoute(r)
+		id, _ := ctx.Value(requestIdKey).(string)
+		ctx, span := app.tracer.Start(ctx, route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("request.id", id),
+			),
+		)
+		defer span.End()
+
+		recorder := newStatusRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// queryTracer returns a hook that records a span for each statement run while serving a traced
+// request. Statements run outside a trace, such as the webhook dispatcher polling its outbox,
+// are not recorded. Rows affected are only known for statements that do not return rows.
+func queryTracer(tracer trace.Tracer) querybuilder.Hook {
+	return querybuilder.HookFunc(func(ctx context.Context, event querybuilder.QueryEvent) {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		end := time.Now()
+		kind := statementKind(event.SQL)
+		_, span := tracer.Start(ctx, "db "+kind,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(end.Add(-event.Duration)),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.operation", kind),
+				attribute.String("db.statement", event.SQL),
+			),
+		)
+		if event.RowsAffected >= 0 {
+			span.SetAttributes(attribute.Int64("db.rows_affected", event.RowsAffected))
+		}
+		if !errors.Is(event.Err, sql.ErrNoRows) {
+			recordSpanError(span, event.Err)
+		}
+		span.End(trace.WithTimestamp(end))
+	})
+}
+
+// traceUpload records a span around storing content in the asset backend.
+func (app *application) traceUpload(ctx context.Context, upload func(ctx context.Context) (*assets.UploadResult, error)) (*assets.UploadResult, error) {
+	ctx, span := startSpan(ctx, app.tracer, "assets.upload",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("asset.backend", app.config.assetBackend)),
+	)
+	defer span.End()
+
+	result, err := upload(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("asset.public_id", result.PublicID),
+		attribute.Int64("asset.bytes", result.Bytes),
+		attribute.Int("asset.variants", len(result.Variants)),
+	)
+	return result, nil
+}
+
+// newTracerProvider returns a provider exporting spans in batches to the configured OTLP
+// endpoint, or nil when there is none, which turns tracing off. Export errors are logged.
+func newTracerProvider(ctx context.Context, cfg config, logger *slog.Logger) (*sdktrace.TracerProvider, error) {
+	if cfg.tracing.endpoint == "" {
+		return nil, nil
+	}
+
+	headers, err := parseOTLPHeaders(cfg.tracing.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(cfg.tracing.endpoint),
+		otlptracehttp.WithHeaders(headers),
+		otlptracehttp.WithTimeout(10*time.Second),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Could not export spans", "error", err)
+	}))
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", tracingServiceName))),
+	), nil
+}
+
+// parseOTLPHeaders reads comma separated key=value pairs, such as
+// "Authorization=Bearer abc,X-Tenant=web", the format of OTEL_EXPORTER_OTLP_HEADERS.
+func parseOTLPHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q, expected key=value", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
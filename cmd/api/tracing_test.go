@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api.etin.dev/internal/assets"
+	"api.etin.dev/internal/data"
+	"api.etin.dev/pkg/querybuilder"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer(t *testing.T) (trace.Tracer, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return provider.Tracer(tracingServiceName), exporter
+}
+
+// exportedSpans returns the spans ended so far by name.
+func exportedSpans(exporter *tracetest.InMemoryExporter) map[string]tracetest.SpanStub {
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+// spanAttr returns the value of the attribute named key, or an empty value.
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTraceRequest_SpansHandlerAndQueries(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+
+	var logs bytes.Buffer
+	logger, err := newLogger(&logs, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("unexpected error creating logger: %s", err)
+	}
+	app := &application{logger: logger, tracer: tracer}
+
+	hook := queryTracer(tracer)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /public/v1/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		hook.AfterQuery(r.Context(), querybuilder.QueryEvent{SQL: "SELECT * FROM tags", Duration: time.Millisecond, RowsAffected: -1})
+		hook.AfterQuery(r.Context(), querybuilder.QueryEvent{SQL: "UPDATE notes SET title = $1", Duration: time.Millisecond, RowsAffected: 2, Err: errors.New("deadlock")})
+		app.logger.ErrorContext(r.Context(), "Could not retrieve project")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := app.requestID(app.resolveRoute(mux, app.traceRequest(mux)))
+
+	req := httptest.NewRequest(http.MethodGet, "/public/v1/projects/7", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Queries run outside a traced request are not recorded.
+	hook.AfterQuery(context.Background(), querybuilder.QueryEvent{SQL: "DELETE FROM sessions", RowsAffected: 1})
+
+	spans := exportedSpans(exporter)
+	if len(spans) != 3 {
+		t.Fatalf("expected a request span and two query spans, got %v", spans)
+	}
+
+	request := spans["GET /public/v1/projects/{id}"]
+	if request.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || request.Parent.SpanID().String() != "00f067aa0ba902b7" || !request.Parent.IsRemote() {
+		t.Errorf("expected the request span to continue the incoming trace, got %+v", request)
+	}
+	if request.SpanKind != trace.SpanKindServer || spanAttr(request, "request.id").AsString() != "req-42" || spanAttr(request, "http.response.status_code").AsInt64() != 500 || request.Status.Code != codes.Error {
+		t.Errorf("unexpected request span %+v", request)
+	}
+
+	selectSpan, updateSpan := spans["db select"], spans["db update"]
+	if selectSpan.Parent.SpanID() != request.SpanContext.SpanID() || spanAttr(selectSpan, "db.statement").AsString() != "SELECT * FROM tags" || spanAttr(selectSpan, "db.rows_affected").Type() != attribute.INVALID {
+		t.Errorf("unexpected select span %+v", selectSpan)
+	}
+	if selectSpan.EndTime.Sub(selectSpan.StartTime) != time.Millisecond {
+		t.Errorf("expected the select span to last as long as the statement, got %s", selectSpan.EndTime.Sub(selectSpan.StartTime))
+	}
+	if spanAttr(updateSpan, "db.rows_affected").AsInt64() != 2 || updateSpan.Status.Code != codes.Error || updateSpan.Status.Description != "deadlock" {
+		t.Errorf("unexpected update span %+v", updateSpan)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON log record, got %q: %s", logs.String(), err)
+	}
+	if record["trace_id"] != request.SpanContext.TraceID().String() || record["span_id"] != request.SpanContext.SpanID().String() || record["request_id"] != "req-42" {
+		t.Errorf("expected the log record to carry the trace and request IDs, got %v", record)
+	}
+}
+
+func TestTraceUpload(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	app := &application{tracer: tracer}
+	app.config.assetBackend = "cloudinary"
+
+	app.traceUpload(context.Background(), func(ctx context.Context) (*assets.UploadResult, error) {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("expected the upload to run inside its span")
+		}
+		return &assets.UploadResult{PublicID: "portfolio/cat", Bytes: 2048}, nil
+	})
+	app.traceUpload(context.Background(), func(ctx context.Context) (*assets.UploadResult, error) {
+		return nil, errors.New("cloudinary unavailable")
+	})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected two upload spans, got %d", len(spans))
+	}
+	if spanAttr(spans[0], "asset.backend").AsString() != "cloudinary" || spanAttr(spans[0], "asset.public_id").AsString() != "portfolio/cat" || spanAttr(spans[0], "asset.bytes").AsInt64() != 2048 {
+		t.Errorf("unexpected upload span %+v", spans[0])
+	}
+	if spans[1].Status.Code != codes.Error || spans[1].Status.Description != "cloudinary unavailable" || len(spans[1].Events) != 1 {
+		t.Errorf("expected the failed upload to be marked as an error, got %+v", spans[1])
+	}
+}
+
+func TestWebhookDispatcher_TracesDeliveries(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	outbox := &stubOutbox{}
+	dispatcher := newTestDispatcher(outbox, server.URL, time.Now())
+	dispatcher.tracer = tracer
+
+	if _, err := dispatcher.notify(context.Background()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	dispatcher.deliverDue(context.Background())
+
+	span, ok := exportedSpans(exporter)["webhook.deliver"]
+	if !ok {
+		t.Fatal("expected a webhook delivery span")
+	}
+	if expected := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"; traceparent != expected {
+		t.Errorf("expected the delivery to carry its span in traceparent %q, got %q", expected, traceparent)
+	}
+	if spanAttr(span, "webhook.delivery_id").AsInt64() != 1 || spanAttr(span, "webhook.kind").AsString() != "deploy" || spanAttr(span, "http.response.status_code").AsInt64() != http.StatusNoContent {
+		t.Errorf("unexpected delivery span %+v", span)
+	}
+	if outbox.statuses[0] != data.DeliveryDelivered {
+		t.Errorf("expected the delivery to succeed, got %v", outbox.statuses)
+	}
+}
+
+func TestNewTracerProvider_ExportsToTheOTLPEndpoint(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer server.Close()
+
+	var cfg config
+	if provider, err := newTracerProvider(context.Background(), cfg, slog.Default()); provider != nil || err != nil {
+		t.Fatalf("expected tracing to be off without an endpoint, got %v, %v", provider, err)
+	}
+
+	cfg.tracing.endpoint = server.URL + "/v1/traces"
+	cfg.tracing.headers = "X-Tenant=web"
+	provider, err := newTracerProvider(context.Background(), cfg, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	_, span := provider.Tracer(tracingServiceName).Start(context.Background(), "test")
+	span.End()
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("unexpected error flushing spans: %s", err)
+	}
+
+	select {
+	case r := <-requests:
+		if r.URL.Path != "/v1/traces" || r.Header.Get("X-Tenant") != "web" {
+			t.Errorf("unexpected export request to %s with headers %v", r.URL.Path, r.Header)
+		}
+	default:
+		t.Fatal("expected the span to be exported")
+	}
+}
+
+func TestParseOTLPHeaders(t *testing.T) {
+	headers, err := parseOTLPHeaders("Authorization=Bearer abc, X-Tenant=web,")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(headers) != 2 || headers["Authorization"] != "Bearer abc" || headers["X-Tenant"] != "web" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+
+	if _, err := parseOTLPHeaders("Authorization"); err == nil {
+		t.Error("expected a header without a value to be rejected")
+	}
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"api.etin.dev/internal/assets"
+)
+
+const (
+	defaultUploadMaxBytes   = 2 << 30 // 2 GiB
+	defaultUploadSessionTTL = 24 * time.Hour
+	uploadJanitorInterval   = time.Minute
+	uploadPartSuffix        = ".part"
+	uploadMetaSuffix        = ".json"
+	// directUploadGrace is how long after its signed parameters expire a direct upload may still
+	// be confirmed, so that one started just before the deadline has time to finish.
+	directUploadGrace = 15 * time.Minute
+)
+
+var (
+	errUploadNotFound   = errors.New("upload not found")
+	errUploadBusy       = errors.New("upload is already receiving a chunk")
+	errUploadOffset     = errors.New("upload offset does not match")
+	errUploadTooLarge   = errors.New("upload is too large")
+	errUploadChecksum   = errors.New("chunk checksum does not match")
+	errUploadIncomplete = errors.New("upload is incomplete")
+)
+
+// uploadSession tracks a chunked upload. Chunks are appended to a temporary file until the
+// declared length has been received, at which point the file is ingested like any other asset.
+// The exported fields are also saved next to the file so that sessions survive a restart.
+type uploadSession struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	path string
+	busy bool
+}
+
+// pendingDirectUpload is a signed direct-to-storage upload awaiting confirmation. Only keys the
+// API signed can be confirmed, and the file name supplies the extension validation checks.
+type pendingDirectUpload struct {
+	upload    assets.DirectUpload
+	filename  string
+	expiresAt time.Time
+}
+
+// chunkChecksum is the digest a client declared for a single chunk.
+type chunkChecksum struct {
+	hash     hash.Hash
+	expected []byte
+}
+
+// uploadManager keeps chunked upload sessions in memory and their data and metadata in a private
+// directory. Sessions that stop receiving chunks expire after the TTL and are removed by the
+// janitor, as are direct uploads that were signed but never confirmed.
+type uploadManager struct {
+	mu        sync.Mutex
+	sessions  map[string]*uploadSession
+	direct    map[string]pendingDirectUpload
+	dir       string
+	ttl       time.Duration
+	maxLength int64
+	now       func() time.Time
+}
+
+// newUploadManager prepares dir for upload data and reloads the sessions a previous process left
+// behind. Files that do not belong to a live session, such as spooled request bodies, are removed.
+func newUploadManager(dir string, ttl time.Duration, maxLength int64) (*uploadManager, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create upload directory: %w", err)
+	}
+
+	m := &uploadManager{
+		sessions:  make(map[string]*uploadSession),
+		direct:    make(map[string]pendingDirectUpload),
+		dir:       dir,
+		ttl:       ttl,
+		maxLength: maxLength,
+		now:       time.Now,
+	}
+
+	if err := m.restore(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// restore loads the sessions saved in the upload directory. Expired or unreadable sessions are
+// dropped. A chunk that was being written when the process stopped may not have been recorded,
+// so each file is cut back to the offset in its metadata.
+func (m *uploadManager) restore() error {
+	metas, err := filepath.Glob(filepath.Join(m.dir, "*"+uploadMetaSuffix))
+	if err != nil {
+		return fmt.Errorf("list upload directory: %w", err)
+	}
+
+	now := m.now()
+	for _, metaPath := range metas {
+		id := strings.TrimSuffix(filepath.Base(metaPath), uploadMetaSuffix)
+		session, err := m.loadSession(id)
+		if err != nil || now.After(session.ExpiresAt) {
+			os.Remove(metaPath)
+			continue
+		}
+		m.sessions[id] = session
+	}
+
+	for _, pattern := range []string{"*" + uploadPartSuffix, "*" + uploadMetaSuffix + ".tmp"} {
+		leftovers, err := filepath.Glob(filepath.Join(m.dir, pattern))
+		if err != nil {
+			return fmt.Errorf("list upload directory: %w", err)
+		}
+		for _, leftover := range leftovers {
+			id := strings.TrimSuffix(filepath.Base(leftover), uploadPartSuffix)
+			if _, ok := m.sessions[id]; !ok || !strings.HasSuffix(leftover, uploadPartSuffix) {
+				os.Remove(leftover)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *uploadManager) loadSession(id string) (*uploadSession, error) {
+	contents, err := os.ReadFile(m.metaPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var session uploadSession
+	if err := json.Unmarshal(contents, &session); err != nil {
+		return nil, err
+	}
+	if session.ID != id {
+		return nil, fmt.Errorf("upload metadata is for %q", session.ID)
+	}
+	session.path = filepath.Join(m.dir, id+uploadPartSuffix)
+
+	info, err := os.Stat(session.path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < session.Offset {
+		session.Offset = info.Size()
+	}
+	if info.Size() > session.Offset {
+		if err := os.Truncate(session.path, session.Offset); err != nil {
+			return nil, err
+		}
+	}
+
+	return &session, nil
+}
+
+func (m *uploadManager) metaPath(id string) string {
+	return filepath.Join(m.dir, id+uploadMetaSuffix)
+}
+
+// saveSession writes a session's metadata to a temporary file and renames it into place, so a
+// crash never leaves a half written record behind.
+func (m *uploadManager) saveSession(session uploadSession) error {
+	contents, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	path := m.metaPath(session.ID)
+	if err := os.WriteFile(path+".tmp", contents, 0o600); err != nil {
+		return fmt.Errorf("save upload metadata: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("save upload metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (m *uploadManager) create(filename string, length int64) (uploadSession, error) {
+	if length > m.maxLength {
+		return uploadSession{}, fmt.Errorf("%w: uploads are limited to %d bytes", errUploadTooLarge, m.maxLength)
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return uploadSession{}, err
+	}
+	id := hex.EncodeToString(idBytes)
+
+	path := filepath.Join(m.dir, id+uploadPartSuffix)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return uploadSession{}, fmt.Errorf("create upload file: %w", err)
+	}
+	file.Close()
+
+	now := m.now()
+	session := &uploadSession{
+		ID:        id,
+		Filename:  filename,
+		Length:    length,
+		CreatedAt: now.UTC(),
+		ExpiresAt: now.Add(m.ttl).UTC(),
+		path:      path,
+	}
+
+	if err := m.saveSession(*session); err != nil {
+		os.Remove(path)
+		return uploadSession{}, err
+	}
+
+	m.mu.Lock()
+	m.sessions[id] = session
+	m.mu.Unlock()
+
+	return *session, nil
+}
+
+func (m *uploadManager) get(id string) (uploadSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || m.now().After(session.ExpiresAt) {
+		return uploadSession{}, errUploadNotFound
+	}
+
+	return *session, nil
+}
+
+// acquire reserves a session for a single writer. Concurrent chunks for the same upload are
+// refused rather than queued, since only one of them can start at the current offset.
+func (m *uploadManager) acquire(id string) (*uploadSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || m.now().After(session.ExpiresAt) {
+		return nil, errUploadNotFound
+	}
+	if session.busy {
+		return nil, errUploadBusy
+	}
+
+	session.busy = true
+	return session, nil
+}
+
+// release hands a session back and extends its lifetime, since the client is still active. The
+// session is saved before it becomes available to other writers again.
+func (m *uploadManager) release(session *uploadSession) error {
+	m.mu.Lock()
+	session.ExpiresAt = m.now().Add(m.ttl).UTC()
+	snapshot := *session
+	m.mu.Unlock()
+
+	err := m.saveSession(snapshot)
+
+	m.mu.Lock()
+	session.busy = false
+	m.mu.Unlock()
+
+	return err
+}
+
+// discard forgets an acquired session and deletes its data.
+func (m *uploadManager) discard(session *uploadSession) {
+	m.mu.Lock()
+	delete(m.sessions, session.ID)
+	m.mu.Unlock()
+
+	os.Remove(session.path)
+	os.Remove(m.metaPath(session.ID))
+}
+
+// appendChunk writes body at offset, which must be the number of bytes received so far. Without
+// a checksum whatever arrived before a failure is kept so the client can resume from there; with
+// one, the chunk is all or nothing.
+func (m *uploadManager) appendChunk(id string, offset int64, body io.Reader, checksum *chunkChecksum) (_ uploadSession, err error) {
+	session, err := m.acquire(id)
+	if err != nil {
+		return uploadSession{}, err
+	}
+	defer func() {
+		if releaseErr := m.release(session); err == nil {
+			err = releaseErr
+		}
+	}()
+
+	if offset != session.Offset {
+		return *session, fmt.Errorf("%w: expected %d, got %d", errUploadOffset, session.Offset, offset)
+	}
+
+	file, err := os.OpenFile(session.path, os.O_WRONLY, 0o600)
+	if err != nil {
+		return *session, fmt.Errorf("open upload file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return *session, fmt.Errorf("seek upload file: %w", err)
+	}
+
+	remaining := session.Length - offset
+	var dst io.Writer = file
+	if checksum != nil {
+		dst = io.MultiWriter(file, checksum.hash)
+	}
+
+	// Reading one byte past the remaining length detects clients that send too much.
+	written, copyErr := io.Copy(dst, io.LimitReader(body, remaining+1))
+
+	switch {
+	case written > remaining:
+		copyErr = fmt.Errorf("%w: the chunk runs past the declared length, %d bytes remain", errUploadTooLarge, remaining)
+	case copyErr == nil && checksum != nil && !bytes.Equal(checksum.hash.Sum(nil), checksum.expected):
+		copyErr = errUploadChecksum
+	}
+
+	if copyErr != nil && (checksum != nil || written > remaining) {
+		if err := file.Truncate(offset); err != nil {
+			return *session, fmt.Errorf("roll back upload chunk: %w", err)
+		}
+		return *session, copyErr
+	}
+
+	m.mu.Lock()
+	session.Offset += written
+	m.mu.Unlock()
+
+	return *session, copyErr
+}
+
+// open acquires a fully received session and opens its data for ingestion. The caller must
+// either release or discard the session once it is done with the file.
+func (m *uploadManager) open(id string) (*uploadSession, *os.File, error) {
+	session, err := m.acquire(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if session.Offset != session.Length {
+		m.release(session)
+		return nil, nil, fmt.Errorf("%w: received %d of %d bytes", errUploadIncomplete, session.Offset, session.Length)
+	}
+
+	file, err := os.Open(session.path)
+	if err != nil {
+		m.release(session)
+		return nil, nil, fmt.Errorf("open upload file: %w", err)
+	}
+
+	return session, file, nil
+}
+
+func (m *uploadManager) remove(id string) error {
+	session, err := m.acquire(id)
+	if err != nil {
+		return err
+	}
+
+	m.discard(session)
+	return nil
+}
+
+// expire removes sessions and pending direct uploads that have outlived their TTL and reports
+// how many were dropped, along with the direct uploads whose objects should now be deleted from
+// storage. Sessions busy receiving a chunk are left for the next pass.
+func (m *uploadManager) expire() (int, []assets.DirectUpload) {
+	now := m.now()
+
+	m.mu.Lock()
+	expired := make([]*uploadSession, 0)
+	for id, session := range m.sessions {
+		if !session.busy && now.After(session.ExpiresAt) {
+			expired = append(expired, session)
+			delete(m.sessions, id)
+		}
+	}
+	var abandoned []assets.DirectUpload
+	for key, pending := range m.direct {
+		if now.After(pending.expiresAt) {
+			delete(m.direct, key)
+			abandoned = append(abandoned, pending.upload)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, session := range expired {
+		os.Remove(session.path)
+		os.Remove(m.metaPath(session.ID))
+	}
+
+	return len(expired) + len(abandoned), abandoned
+}
+
+// expectDirect records a signed direct upload so that it can be confirmed until a grace period
+// after its signed parameters expire at expiresAt.
+func (m *uploadManager) expectDirect(upload assets.DirectUpload, filename string, expiresAt time.Time) {
+	m.mu.Lock()
+	m.direct[upload.Key] = pendingDirectUpload{
+		upload:    upload,
+		filename:  filename,
+		expiresAt: expiresAt.Add(directUploadGrace),
+	}
+	m.mu.Unlock()
+}
+
+// claimDirect removes a pending direct upload so that only one confirmation can process it.
+// Callers hand it back with restoreDirect when the confirmation can be retried.
+func (m *uploadManager) claimDirect(key string) (pendingDirectUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, ok := m.direct[key]
+	if !ok || m.now().After(pending.expiresAt) {
+		return pendingDirectUpload{}, errUploadNotFound
+	}
+
+	delete(m.direct, key)
+	return pending, nil
+}
+
+func (m *uploadManager) restoreDirect(pending pendingDirectUpload) {
+	m.mu.Lock()
+	m.direct[pending.upload.Key] = pending
+	m.mu.Unlock()
+}
+
+// spool copies r into a temporary file in the upload directory. Having no metadata, the file is
+// removed on startup should the process die before the caller deletes it.
+func (m *uploadManager) spool(r io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp(m.dir, "spool-*"+uploadPartSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("create spool file: %w", err)
+	}
+
+	written, err := io.Copy(file, io.LimitReader(r, m.maxLength+1))
+	if err == nil && written > m.maxLength {
+		err = fmt.Errorf("%w: uploads are limited to %d bytes", errUploadTooLarge, m.maxLength)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// startJanitor expires abandoned sessions every interval until the returned function is called,
+// passing direct uploads that were never confirmed to discard so their objects do not linger in
+// storage. discard may be nil when the backend does not take direct uploads. The function waits
+// for an expiry pass that is under way to finish.
+func (m *uploadManager) startJanitor(interval time.Duration, discard func(context.Context, assets.DirectUpload) error, logf func(string, ...any)) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				removed, abandoned := m.expire()
+				if removed > 0 && logf != nil {
+					logf("expired %d abandoned uploads", removed)
+				}
+				if discard != nil {
+					m.discardAbandoned(abandoned, discard, logf)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// discardAbandoned deletes the objects of direct uploads that were never confirmed. A failure
+// only leaves an orphaned object behind, so it is logged and not retried.
+func (m *uploadManager) discardAbandoned(abandoned []assets.DirectUpload, discard func(context.Context, assets.DirectUpload) error, logf func(string, ...any)) {
+	ctx, cancel := context.WithTimeout(context.Background(), uploadJanitorInterval)
+	defer cancel()
+
+	for _, upload := range abandoned {
+		if err := discard(ctx, upload); err != nil && logf != nil {
+			logf("could not delete abandoned direct upload %s: %s", upload.Key, err)
+		}
+	}
+}
+
+// parseUploadFilename strips any directory components a client included in the file name.
+func parseUploadFilename(name string) string {
+	name = strings.TrimSpace(name)
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	return name
+}
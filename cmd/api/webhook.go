@@ -1,42 +1,280 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
+
+	"api.etin.dev/internal/data"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultWebhookDebounce    = 10 * time.Second
+	defaultWebhookMaxWait     = 2 * time.Minute
+	defaultWebhookBackoff     = 15 * time.Second
+	defaultWebhookMaxAttempts = 8
+	webhookMaxBackoff         = time.Hour
+	webhookPollInterval       = time.Second
 )
 
-func (app *application) triggerDeployWebhook() {
-	url := app.config.deployWebhook
-	if url == "" {
+// webhookOutbox is the durable queue webhook deliveries pass through.
+type webhookOutbox interface {
+	Enqueue(url string, debounce, maxWait time.Duration) (*data.WebhookDelivery, error)
+	Publish(event data.WebhookEvent) (int64, error)
+	Claim() (*data.WebhookDelivery, error)
+	RecordAttempt(delivery *data.WebhookDelivery, attempt data.WebhookAttempt, status string, retryAt time.Time) error
+	Recover() (int64, error)
+}
+
+// webhookDispatcher queues webhook deliveries and sends them from a background worker. Every
+// content change queues a call to the deploy webhook at url, if one is configured, and changes
+// made in quick succession share a delivery. Events are also queued for each subscription
+// listening for them. Failed deliveries are retried with exponential backoff until maxAttempts
+// is reached. outbox binds the queue to a context, so that changes queued while serving a
+// request run with it.
+type webhookDispatcher struct {
+	outbox      func(ctx context.Context) webhookOutbox
+	url         string
+	client      *http.Client
+	logger      *slog.Logger
+	debounce    time.Duration
+	maxWait     time.Duration
+	backoff     time.Duration
+	maxAttempts int
+	now         func() time.Time
+	metrics     *appMetrics
+	tracer      trace.Tracer
+}
+
+// triggerDeployWebhook queues a call to the deploy webhook. The change has already been made by
+// the time it runs, so a client that disconnects must not stop the delivery being queued.
+func (app *application) triggerDeployWebhook(ctx context.Context) {
+	if app.webhooks == nil {
 		return
 	}
 
-	go func(target string) {
-		req, err := http.NewRequest(http.MethodPost, target, strings.NewReader("{}"))
+	ctx = context.WithoutCancel(ctx)
+	if _, err := app.webhooks.notify(ctx); err != nil {
+		app.logger.ErrorContext(ctx, "Could not queue deploy webhook", "error", err)
+	}
+}
+
+// publishEvent queues a delivery of a change to every subscription listening for it. snapshot
+// is the resource as it now stands, or as it stood before a delete.
+func (app *application) publishEvent(ctx context.Context, resource, action string, id int64, snapshot any) {
+	if app.webhooks == nil {
+		return
+	}
+
+	event := data.WebhookEvent{
+		ID:         uuid.New().String(),
+		Type:       resource + "." + action,
+		Resource:   resource,
+		ResourceID: id,
+		Action:     action,
+		OccurredAt: time.Now().UTC(),
+		Data:       snapshot,
+	}
+
+	if _, err := app.webhooks.outbox(ctx).Publish(event); err != nil {
+		app.logger.ErrorContext(ctx, "Could not queue webhook event", "type", event.Type, "error", err)
+	}
+}
+
+// notify records that content changed, folding the change into a pending delivery if there is
+// one. It does nothing when no deploy webhook is configured.
+func (d *webhookDispatcher) notify(ctx context.Context) (*data.WebhookDelivery, error) {
+	if d.url == "" {
+		return nil, nil
+	}
+	return d.outbox(ctx).Enqueue(d.url, d.debounce, d.maxWait)
+}
+
+// deliverDue sends every delivery that is due and reports how many were attempted. It stops
+// early once ctx is cancelled, leaving the remaining deliveries for the next run.
+func (d *webhookDispatcher) deliverDue(ctx context.Context) int {
+	attempted := 0
+
+	for ctx.Err() == nil {
+		delivery, err := d.outbox(ctx).Claim()
 		if err != nil {
-			app.logger.Printf("Error creating deploy webhook request: %s", err)
-			return
+			if err.Error() != "record not found" && ctx.Err() == nil {
+				d.logger.Error("Could not claim deploy webhook delivery", "error", err)
+			}
+			return attempted
 		}
 
-		req.Header.Set("Content-Type", "application/json")
+		d.deliver(ctx, delivery)
+		attempted++
+	}
 
-		client := app.httpClient
-		if client == nil {
-			client = http.DefaultClient
-		}
+	return attempted
+}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			app.logger.Printf("Error invoking deploy webhook: %s", err)
-			return
+// deliver sends a claimed delivery. Cancelling ctx aborts the request, but the attempt is still
+// recorded so that the delivery is retried rather than left claimed.
+func (d *webhookDispatcher) deliver(ctx context.Context, delivery *data.WebhookDelivery) {
+	ctx, span := startSpan(ctx, d.tracer, "webhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int64("webhook.delivery_id", delivery.ID),
+			attribute.String("webhook.kind", webhookKind(delivery)),
+			attribute.String("webhook.event", delivery.EventType),
+			attribute.Int("webhook.attempt", delivery.Attempts),
+		),
+	)
+	defer span.End()
+
+	started := d.now()
+	attempt := data.WebhookAttempt{Attempt: delivery.Attempts, AttemptedAt: started.UTC()}
+
+	statusCode, err := d.send(ctx, delivery)
+	recordSpanError(span, err)
+	if statusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	attempt.DurationMs = d.now().Sub(started).Milliseconds()
+	if statusCode > 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	status := data.DeliveryDelivered
+	retryAt := delivery.NextAttemptAt
+	if err != nil {
+		attempt.Error = err.Error()
+		status = data.DeliveryFailed
+		if delivery.Attempts < d.maxAttempts {
+			status = data.DeliveryPending
+			retryAt = started.Add(d.retryDelay(delivery.Attempts))
 		}
-		defer resp.Body.Close()
+	}
+
+	// A delivery that cannot be recorded stays delivering until the next restart recovers it.
+	if err := d.outbox(context.WithoutCancel(ctx)).RecordAttempt(delivery, attempt, status, retryAt); err != nil {
+		d.logger.Error("Could not record deploy webhook delivery", "deliveryId", delivery.ID, "error", err)
+		return
+	}
+	d.metrics.observeWebhookAttempt(delivery, status)
+
+	if delivery.Status == data.DeliveryFailed {
+		d.logger.Warn("Deploy webhook delivery failed", "deliveryId", delivery.ID, "attempts", delivery.Attempts, "error", attempt.Error)
+	}
+}
 
-		io.Copy(io.Discard, resp.Body)
+// webhookKind is "subscription" for deliveries to a subscription and "deploy" for calls to the
+// deploy webhook.
+func webhookKind(delivery *data.WebhookDelivery) string {
+	if delivery.SubscriptionID != nil {
+		return "subscription"
+	}
+	return "deploy"
+}
+
+// send posts the delivery's payload and returns the response status, if one was received.
+// Subscription deliveries are signed on every attempt so that the timestamp stays current.
+func (d *webhookDispatcher) send(ctx context.Context, delivery *data.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
 
-		if resp.StatusCode >= 300 {
-			app.logger.Printf("Deploy webhook returned status %d", resp.StatusCode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	tracePropagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(delivery.Attempts))
+	if delivery.EventType != "" {
+		req.Header.Set("X-Webhook-Event", delivery.EventType)
+	}
+	if delivery.Secret != "" {
+		timestamp := strconv.FormatInt(d.now().Unix(), 10)
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Webhook-Signature", "sha256="+signWebhookPayload(delivery.Secret, timestamp, payload))
+	}
+
+	client := d.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of "<timestamp>.<payload>". Receivers
+// recompute it with their secret and reject stale timestamps to stop replayed requests.
+func signWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay doubles the backoff after every failed attempt, up to webhookMaxBackoff.
+func (d *webhookDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxBackoff)
+}
+
+// start recovers deliveries interrupted by a previous process and then sends due deliveries
+// every interval until the returned function is called, which cancels a delivery in flight.
+func (d *webhookDispatcher) start(interval time.Duration) func() {
+	if recovered, err := d.outbox(context.Background()).Recover(); err != nil {
+		d.logger.Error("Could not recover deploy webhook deliveries", "error", err)
+	} else if recovered > 0 {
+		d.logger.Info("requeued interrupted deploy webhook deliveries", "count", recovered)
+	}
+
+	ticker := time.NewTicker(interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				d.deliverDue(ctx)
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
 		}
-	}(url)
+	}()
+
+	return func() {
+		cancel()
+		<-stopped
+	}
 }
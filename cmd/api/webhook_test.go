@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api.etin.dev/internal/data"
+)
+
+// stubOutbox hands out queued deliveries and keeps what the dispatcher records about them.
+type stubOutbox struct {
+	queue    []*data.WebhookDelivery
+	events   []data.WebhookEvent
+	attempts []data.WebhookAttempt
+	statuses []string
+	retries  []time.Time
+}
+
+func (o *stubOutbox) Enqueue(url string, debounce, maxWait time.Duration) (*data.WebhookDelivery, error) {
+	delivery := &data.WebhookDelivery{ID: int64(len(o.queue) + 1), URL: url, Status: data.DeliveryPending}
+	o.queue = append(o.queue, delivery)
+	return delivery, nil
+}
+
+func (o *stubOutbox) Publish(event data.WebhookEvent) (int64, error) {
+	o.events = append(o.events, event)
+	return 1, nil
+}
+
+func (o *stubOutbox) Claim() (*data.WebhookDelivery, error) {
+	for _, delivery := range o.queue {
+		if delivery.Status == data.DeliveryPending {
+			delivery.Status = data.DeliveryDelivering
+			delivery.Attempts++
+			return delivery, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (o *stubOutbox) RecordAttempt(delivery *data.WebhookDelivery, attempt data.WebhookAttempt, status string, retryAt time.Time) error {
+	o.attempts = append(o.attempts, attempt)
+	o.statuses = append(o.statuses, status)
+	o.retries = append(o.retries, retryAt)
+	delivery.Status = status
+	return nil
+}
+
+func (o *stubOutbox) Recover() (int64, error) {
+	return 0, nil
+}
+
+func newTestDispatcher(outbox webhookOutbox, url string, now time.Time) *webhookDispatcher {
+	return &webhookDispatcher{
+		outbox:      func(context.Context) webhookOutbox { return outbox },
+		url:         url,
+		client:      &http.Client{Timeout: time.Second},
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		backoff:     10 * time.Second,
+		maxAttempts: 3,
+		now:         func() time.Time { return now },
+	}
+}
+
+func TestWebhookDispatcher_RetriesUntilDelivered(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Webhook-Delivery") != "1" {
+			t.Errorf("expected the delivery ID header, got %q", r.Header.Get("X-Webhook-Delivery"))
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	outbox := &stubOutbox{}
+	dispatcher := newTestDispatcher(outbox, server.URL, now)
+
+	if _, err := dispatcher.notify(context.Background()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	// The failed attempt goes back to pending, so the stub hands it out again straight away.
+	if attempted := dispatcher.deliverDue(context.Background()); attempted != 2 {
+		t.Fatalf("expected two attempts, got %d", attempted)
+	}
+
+	if outbox.statuses[0] != data.DeliveryPending || outbox.statuses[1] != data.DeliveryDelivered {
+		t.Fatalf("unexpected statuses %v", outbox.statuses)
+	}
+	if !outbox.retries[0].Equal(now.Add(10 * time.Second)) {
+		t.Fatalf("expected the first retry after the base backoff, got %s", outbox.retries[0])
+	}
+	if code := outbox.attempts[0].StatusCode; code == nil || *code != http.StatusBadGateway || outbox.attempts[0].Error == "" {
+		t.Fatalf("expected the failed attempt to be recorded, got %+v", outbox.attempts[0])
+	}
+	if outbox.attempts[1].Attempt != 2 || outbox.attempts[1].Error != "" {
+		t.Fatalf("unexpected successful attempt %+v", outbox.attempts[1])
+	}
+}
+
+func TestWebhookDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	outbox := &stubOutbox{}
+	dispatcher := newTestDispatcher(outbox, server.URL, time.Now())
+	dispatcher.notify(context.Background())
+
+	if attempted := dispatcher.deliverDue(context.Background()); attempted != 3 {
+		t.Fatalf("expected three attempts, got %d", attempted)
+	}
+
+	if last := outbox.statuses[len(outbox.statuses)-1]; last != data.DeliveryFailed {
+		t.Fatalf("expected the delivery to fail, got %q", last)
+	}
+}
+
+func TestWebhookDispatcher_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	outbox := &stubOutbox{}
+	dispatcher := newTestDispatcher(outbox, server.URL, time.Now())
+	outbox.Enqueue(server.URL, 0, 0)
+	outbox.Enqueue(server.URL, 0, 0)
+
+	if attempted := dispatcher.deliverDue(ctx); attempted != 1 {
+		t.Fatalf("expected one attempt before stopping, got %d", attempted)
+	}
+
+	if len(outbox.statuses) != 1 || outbox.statuses[0] != data.DeliveryPending {
+		t.Fatalf("expected the interrupted attempt to be recorded for a retry, got %v", outbox.statuses)
+	}
+	if outbox.queue[1].Attempts != 0 {
+		t.Fatalf("expected the second delivery to be left for the next run")
+	}
+}
+
+func TestWebhookDispatcher_SignsSubscriptionDeliveries(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	payload := `{"type":"note.published","resourceId":12}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != payload {
+			t.Errorf("expected the stored payload, got %s", body)
+		}
+		if r.Header.Get("X-Webhook-Event") != "note.published" || r.Header.Get("X-Webhook-Timestamp") != "1709294400" {
+			t.Errorf("unexpected event headers %v", r.Header)
+		}
+
+		mac := hmac.New(sha256.New, []byte("shh"))
+		mac.Write([]byte("1709294400." + payload))
+		if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Webhook-Signature") != expected {
+			t.Errorf("expected signature %s, got %s", expected, r.Header.Get("X-Webhook-Signature"))
+		}
+	}))
+	defer server.Close()
+
+	outbox := &stubOutbox{queue: []*data.WebhookDelivery{{
+		ID:        1,
+		URL:       server.URL,
+		EventType: "note.published",
+		Payload:   []byte(payload),
+		Status:    data.DeliveryPending,
+		Secret:    "shh",
+	}}}
+	dispatcher := newTestDispatcher(outbox, "", now)
+
+	if attempted := dispatcher.deliverDue(context.Background()); attempted != 1 || outbox.statuses[0] != data.DeliveryDelivered {
+		t.Fatalf("expected one successful delivery, got %d attempts with statuses %v", attempted, outbox.statuses)
+	}
+}
+
+func TestWebhookDispatcher_SkipsDeployDeliveriesWithoutURL(t *testing.T) {
+	outbox := &stubOutbox{}
+	dispatcher := newTestDispatcher(outbox, "", time.Now())
+
+	if delivery, err := dispatcher.notify(context.Background()); delivery != nil || err != nil || len(outbox.queue) != 0 {
+		t.Fatalf("expected nothing to be queued, got %+v, %v", delivery, err)
+	}
+}
+
+func TestWebhookDispatcher_RetryDelayDoublesUpToCap(t *testing.T) {
+	dispatcher := &webhookDispatcher{backoff: 15 * time.Second}
+
+	tests := map[int]time.Duration{
+		1:  15 * time.Second,
+		2:  30 * time.Second,
+		3:  time.Minute,
+		9:  webhookMaxBackoff,
+		40: webhookMaxBackoff,
+	}
+
+	for attempts, expected := range tests {
+		if got := dispatcher.retryDelay(attempts); got != expected {
+			t.Fatalf("retryDelay(%d) = %s, expected %s", attempts, got, expected)
+		}
+	}
+}
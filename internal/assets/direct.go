@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultPresignExpiry is how long signed upload parameters remain usable when none is given.
+const DefaultPresignExpiry = 15 * time.Minute
+
+// ErrObjectNotFound is returned when a directly uploaded object cannot be found in storage,
+// usually because the client never completed the upload.
+var ErrObjectNotFound = errors.New("object not found")
+
+// DirectUploader is implemented by backends that let clients send files straight to storage
+// rather than streaming them through the API. The API signs the upload, then reads the object
+// back to validate it before recording it.
+type DirectUploader interface {
+	// PresignUpload returns the request a client must make to store a single file.
+	PresignUpload(ctx context.Context, options PresignOptions) (*PresignedUpload, error)
+	// FetchUpload reads back a directly uploaded object.
+	FetchUpload(ctx context.Context, upload DirectUpload) (io.ReadCloser, error)
+	// DescribeUpload returns the metadata of a directly uploaded object that passed validation.
+	// file holds the object's content, from which backends that cannot render variants on
+	// demand produce them.
+	DescribeUpload(ctx context.Context, upload DirectUpload, file io.ReadSeeker, options UploadOptions) (*UploadResult, error)
+	// DeleteUpload removes a stored object that was rejected or turned out to be a duplicate.
+	// Deleting an object that does not exist is not an error.
+	DeleteUpload(ctx context.Context, upload DirectUpload) error
+}
+
+// PresignOptions describes the file a client intends to upload directly.
+type PresignOptions struct {
+	// Filename supplies the extension of the object key.
+	Filename string
+	// ContentType is the media type the client declared. Backends that sign headers require the
+	// upload to be sent with exactly this type.
+	ContentType string
+	Folder      string
+	Expires     time.Duration
+}
+
+// DirectUpload identifies an object a client was allowed to upload directly.
+type DirectUpload struct {
+	Key          string
+	ResourceType string
+	// Variants lists renditions stored alongside the object, which DeleteUpload removes with it.
+	// Backends that render variants on demand ignore it.
+	Variants []VariantResult
+}
+
+// PresignedUpload is the request a client makes to upload a file directly. S3 expects a PUT of
+// the raw file with Headers set; Cloudinary expects a multipart POST of Fields plus a file part.
+type PresignedUpload struct {
+	DirectUpload
+	Method    string
+	URL       string
+	Headers   map[string]string
+	Fields    map[string]string
+	ExpiresAt time.Time
+}
+
+// directResourceType maps a declared media type onto Cloudinary's resource types, which decide
+// the upload endpoint. PDFs are images to Cloudinary so that pages can be rendered.
+func directResourceType(contentType string) string {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	switch {
+	case strings.HasPrefix(mediaType, "image/"), mediaType == "application/pdf":
+		return "image"
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return "video"
+	default:
+		return "raw"
+	}
+}
+
+var (
+	_ DirectUploader = (*s3Uploader)(nil)
+	_ DirectUploader = (*cloudinaryUploader)(nil)
+)
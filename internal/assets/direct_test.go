@@ -0,0 +1,63 @@
+package assets
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2/api"
+)
+
+func TestCloudinaryUploader_PresignUpload(t *testing.T) {
+	uploader, err := NewCloudinaryUploader("demo", "key", "secret", "website")
+	if err != nil {
+		t.Fatalf("create uploader: %v", err)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cloudinary := uploader.(*cloudinaryUploader)
+	cloudinary.now = func() time.Time { return now }
+
+	presigned, err := cloudinary.PresignUpload(context.Background(), PresignOptions{ContentType: "video/mp4", Expires: 10 * time.Minute})
+	if err != nil {
+		t.Fatalf("presign: %v", err)
+	}
+
+	if presigned.Method != http.MethodPost || presigned.URL != "https://api.cloudinary.com/v1_1/demo/video/upload" {
+		t.Fatalf("unexpected request %s %s", presigned.Method, presigned.URL)
+	}
+	if presigned.ResourceType != "video" || presigned.Key != "website/"+presigned.Fields["public_id"] {
+		t.Fatalf("unexpected upload reference %+v", presigned.DirectUpload)
+	}
+	if !presigned.ExpiresAt.Equal(now.Add(10 * time.Minute)) {
+		t.Fatalf("expected parameters to expire after ten minutes, got %s", presigned.ExpiresAt)
+	}
+
+	params := url.Values{}
+	for _, name := range []string{"public_id", "timestamp", "folder"} {
+		params.Set(name, presigned.Fields[name])
+	}
+	expected, _ := api.SignParameters(params, "secret")
+	if presigned.Fields["signature"] != expected || presigned.Fields["api_key"] != "key" {
+		t.Fatalf("unexpected signed fields %v", presigned.Fields)
+	}
+}
+
+func TestDirectResourceType(t *testing.T) {
+	tests := map[string]string{
+		"image/png":              "image",
+		"application/pdf":        "image",
+		"video/mp4":              "video",
+		"audio/mpeg; codecs=mp3": "video",
+		"application/zip":        "raw",
+		"":                       "raw",
+	}
+
+	for contentType, expected := range tests {
+		if got := directResourceType(contentType); got != expected {
+			t.Fatalf("directResourceType(%q) = %q, expected %q", contentType, got, expected)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// sniffLength mirrors the number of bytes http.DetectContentType inspects.
+const sniffLength = 512
+
+// Probe describes what could be learnt about a file by inspecting its contents locally.
+type Probe struct {
+	ContentType  string
+	Format       string
+	ResourceType string
+	Width        int
+	Height       int
+}
+
+// ProbeFile inspects the supplied bytes to determine the content type, a file format
+// suitable for use as an extension and, for raster images, the pixel dimensions.
+func ProbeFile(data []byte) Probe {
+	head := data
+	if len(head) > sniffLength {
+		head = head[:sniffLength]
+	}
+
+	probe := Probe{ContentType: http.DetectContentType(head)}
+
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		probe.Format = format
+		probe.Width = config.Width
+		probe.Height = config.Height
+		probe.ContentType = "image/" + format
+		probe.ResourceType = "image"
+		return probe
+	}
+
+	mediaType := strings.TrimSpace(strings.SplitN(probe.ContentType, ";", 2)[0])
+	if (mediaType == "text/xml" || mediaType == "text/plain") && looksLikeSVG(data) {
+		mediaType = "image/svg+xml"
+		probe.ContentType = mediaType
+	}
+
+	probe.Format = formatForMediaType(mediaType)
+	probe.ResourceType = resourceTypeForMediaType(mediaType)
+
+	return probe
+}
+
+// ProbeReader reads the remainder of r into memory before probing it.
+func ProbeReader(r io.Reader) ([]byte, Probe, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, Probe{}, err
+	}
+
+	return data, ProbeFile(data), nil
+}
+
+// looksLikeSVG reports whether the first element of an XML document is an svg root. The
+// standard sniffer reports SVG documents as plain text or XML.
+func looksLikeSVG(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return false
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			return strings.EqualFold(t.Name.Local, "svg")
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+func formatForMediaType(mediaType string) string {
+	switch mediaType {
+	case "application/pdf":
+		return "pdf"
+	case "image/svg+xml":
+		return "svg"
+	case "image/bmp":
+		return "bmp"
+	case "image/x-icon":
+		return "ico"
+	case "video/mp4":
+		return "mp4"
+	case "video/webm":
+		return "webm"
+	case "audio/mpeg":
+		return "mp3"
+	case "audio/wave":
+		return "wav"
+	case "text/plain":
+		return "txt"
+	case "application/zip":
+		return "zip"
+	}
+
+	if _, subtype, ok := strings.Cut(mediaType, "/"); ok && !strings.ContainsAny(subtype, "+.-") {
+		return subtype
+	}
+
+	return "bin"
+}
+
+// resourceTypeForMediaType follows Cloudinary's vocabulary so that rows created by any
+// backend can be read the same way.
+func resourceTypeForMediaType(mediaType string) string {
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return "image"
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return "video"
+	default:
+		return "raw"
+	}
+}
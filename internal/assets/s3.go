@@ -0,0 +1,442 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// S3Config describes how to reach an S3-compatible bucket.
+type S3Config struct {
+	Bucket          string
+	Prefix          string
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// UsePathStyle addresses objects as endpoint/bucket/key rather than bucket.endpoint/key,
+	// which most self-hosted S3 implementations require.
+	UsePathStyle bool
+	// PublicBaseURL overrides the URL stored against uploaded assets, e.g. when the bucket is
+	// fronted by a CDN. Object keys are appended to it.
+	PublicBaseURL string
+	HTTPClient    *http.Client
+}
+
+type s3Uploader struct {
+	config   S3Config
+	endpoint *url.URL
+	signer   sigV4Signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Uploader initialises an uploader backed by an S3-compatible object store.
+func NewS3Uploader(config S3Config) (Uploader, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("create s3 uploader: bucket must be provided")
+	}
+
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("create s3 uploader: access key and secret must be provided")
+	}
+
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("create s3 uploader: invalid endpoint %q", config.Endpoint)
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	return &s3Uploader{
+		config:   config,
+		endpoint: endpoint,
+		signer: sigV4Signer{
+			accessKeyID:     config.AccessKeyID,
+			secretAccessKey: config.SecretAccessKey,
+			region:          config.Region,
+			service:         "s3",
+		},
+		client: client,
+		now:    time.Now,
+	}, nil
+}
+
+// Upload stores the file in the configured bucket. Dimensions and format are probed locally
+// because, unlike Cloudinary, S3 does not inspect the objects it stores.
+func (u *s3Uploader) Upload(ctx context.Context, file io.Reader, options UploadOptions) (*UploadResult, error) {
+	body, probe, err := openS3Body(file)
+	if err != nil {
+		return nil, fmt.Errorf("read asset for s3 upload: %w", err)
+	}
+
+	publicID, err := u.publicID(options)
+	if err != nil {
+		return nil, err
+	}
+
+	key := publicID
+	if probe.Format != "" {
+		key += "." + probe.Format
+	}
+
+	now := u.now()
+
+	etag, err := u.putObject(ctx, key, probe.ContentType, body, options.Overwrite, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.result(ctx, key, etag, probe, body.content, body.size, now, options)
+}
+
+// result describes a stored object. Variants are rendered from content, which must hold the
+// object's bytes.
+func (u *s3Uploader) result(ctx context.Context, key, etag string, probe Probe, content io.ReadSeeker, size int64, now time.Time, options UploadOptions) (*UploadResult, error) {
+	resourceType := probe.ResourceType
+	if options.ResourceType != "" {
+		resourceType = options.ResourceType
+	}
+
+	deliveryURL := u.deliveryURL(key)
+
+	result := &UploadResult{
+		AssetID:      etag,
+		PublicID:     key,
+		Format:       probe.Format,
+		ResourceType: resourceType,
+		URL:          deliveryURL,
+		SecureURL:    deliveryURL,
+		Bytes:        size,
+		Width:        probe.Width,
+		Height:       probe.Height,
+		CreatedAt:    now.UTC(),
+	}
+
+	if variantsApply(result) && len(options.Variants) > 0 {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("rewind asset for variants: %w", err)
+		}
+		variants, err := u.renderVariants(ctx, content, key, result, now, options.Variants)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = variants
+	}
+
+	return result, nil
+}
+
+// PresignUpload signs a PUT of a single object. The content type is part of the signature, so
+// the client must send the type it declared.
+func (u *s3Uploader) PresignUpload(_ context.Context, options PresignOptions) (*PresignedUpload, error) {
+	publicID, err := u.publicID(UploadOptions{Folder: options.Folder})
+	if err != nil {
+		return nil, err
+	}
+
+	key := publicID
+	if ext := extensionFormat(options.Filename); ext != "" {
+		key += "." + ext
+	}
+
+	expires := options.Expires
+	if expires <= 0 {
+		expires = DefaultPresignExpiry
+	}
+
+	header := http.Header{}
+	headers := map[string]string{}
+	if options.ContentType != "" {
+		header.Set("Content-Type", options.ContentType)
+		headers["Content-Type"] = options.ContentType
+	}
+
+	now := u.now()
+	signed := u.signer.presign(http.MethodPut, u.objectURL(key), header, expires, now)
+
+	return &PresignedUpload{
+		DirectUpload: DirectUpload{Key: key},
+		Method:       http.MethodPut,
+		URL:          signed.String(),
+		Headers:      headers,
+		ExpiresAt:    now.Add(expires).UTC(),
+	}, nil
+}
+
+// FetchUpload downloads a directly uploaded object.
+func (u *s3Uploader) FetchUpload(ctx context.Context, upload DirectUpload) (io.ReadCloser, error) {
+	resp, err := u.send(ctx, http.MethodGet, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// DescribeUpload probes a directly uploaded object locally, as Upload does, and renders its
+// variants.
+func (u *s3Uploader) DescribeUpload(ctx context.Context, upload DirectUpload, file io.ReadSeeker, options UploadOptions) (*UploadResult, error) {
+	resp, err := u.send(ctx, http.MethodHead, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	probe, size, err := probeSeeker(file)
+	if err != nil {
+		return nil, fmt.Errorf("probe direct upload: %w", err)
+	}
+
+	etag := strings.Trim(resp.Header.Get("ETag"), `"`)
+	return u.result(ctx, upload.Key, etag, probe, file, size, u.now(), options)
+}
+
+// DeleteUpload removes an uploaded object and the variants rendered next to it.
+func (u *s3Uploader) DeleteUpload(ctx context.Context, upload DirectUpload) error {
+	keys := []string{upload.Key}
+	for _, variant := range upload.Variants {
+		keys = append(keys, s3VariantKey(upload.Key, variant.Name, variant.Format))
+	}
+
+	for _, key := range keys {
+		resp, err := u.send(ctx, http.MethodDelete, key)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+// s3VariantKey is where a variant of the object at key is stored, such as banner_thumb.webp
+// for banner.png.
+func s3VariantKey(key, name, format string) string {
+	return fmt.Sprintf("%s_%s.%s", strings.TrimSuffix(key, path.Ext(key)), name, format)
+}
+
+// send issues a signed request without a body. The caller must close the response body.
+func (u *s3Uploader) send(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 request: %w", err)
+	}
+
+	emptyHash := sha256.Sum256(nil)
+	u.signer.sign(req, hex.EncodeToString(emptyHash[:]), u.now())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s s3 object: %w", strings.ToLower(method), err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s s3 object: unexpected status %d: %s", strings.ToLower(method), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+// s3Body is an object's content along with the size and payload hash S3 requires up front.
+type s3Body struct {
+	content     io.ReadSeeker
+	size        int64
+	payloadHash string
+}
+
+// openS3Body prepares content for a PUT. Seekable files, such as completed chunked uploads, are
+// hashed in a streaming pass and rewound so that large files never have to fit in memory.
+func openS3Body(file io.Reader) (s3Body, Probe, error) {
+	seeker, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, probe, err := ProbeReader(file)
+		if err != nil {
+			return s3Body{}, Probe{}, err
+		}
+		return newS3Body(data), probe, nil
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, seeker); err != nil {
+		return s3Body{}, Probe{}, err
+	}
+
+	probe, size, err := probeSeeker(seeker)
+	if err != nil {
+		return s3Body{}, Probe{}, err
+	}
+
+	return s3Body{
+		content:     seeker,
+		size:        size,
+		payloadHash: hex.EncodeToString(hasher.Sum(nil)),
+	}, probe, nil
+}
+
+// probeSeeker probes the start of a seekable file and reports its size, leaving it rewound.
+func probeSeeker(file io.ReadSeeker) (Probe, int64, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Probe{}, 0, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return Probe{}, 0, err
+	}
+	head := make([]byte, min(size, probeHeadBytes))
+	if _, err := io.ReadFull(file, head); err != nil {
+		return Probe{}, 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return Probe{}, 0, err
+	}
+
+	return ProbeFile(head), size, nil
+}
+
+func newS3Body(data []byte) s3Body {
+	payloadHash := sha256.Sum256(data)
+	return s3Body{
+		content:     bytes.NewReader(data),
+		size:        int64(len(data)),
+		payloadHash: hex.EncodeToString(payloadHash[:]),
+	}
+}
+
+// renderVariants produces every variant the uploader can encode locally and stores them next to
+// the original. Formats that need an external encoder, such as webp and avif, are skipped.
+func (u *s3Uploader) renderVariants(ctx context.Context, original io.Reader, key string, result *UploadResult, now time.Time, variants []Variant) ([]VariantResult, error) {
+	source, _, err := image.Decode(original)
+	if err != nil {
+		return nil, fmt.Errorf("decode image for variants: %w", err)
+	}
+
+	rendered := make([]VariantResult, 0, len(variants))
+	for _, variant := range variants {
+		format := variant.outputFormat(result.Format)
+		if !canRender(format) {
+			continue
+		}
+
+		encoded, width, height, err := renderVariant(source, variant, format)
+		if err != nil {
+			return nil, err
+		}
+
+		variantKey := s3VariantKey(key, variant.Name, format)
+		if _, err := u.putObject(ctx, variantKey, "image/"+format, newS3Body(encoded), nil, now); err != nil {
+			return nil, fmt.Errorf("store variant %q: %w", variant.Name, err)
+		}
+
+		rendered = append(rendered, VariantResult{
+			Name:   variant.Name,
+			Format: format,
+			URL:    u.deliveryURL(variantKey),
+			Width:  width,
+			Height: height,
+			Bytes:  int64(len(encoded)),
+		})
+	}
+
+	return rendered, nil
+}
+
+func (u *s3Uploader) putObject(ctx context.Context, key, contentType string, body s3Body, overwrite *bool, now time.Time) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.objectURL(key).String(), io.NopCloser(body.content))
+	if err != nil {
+		return "", fmt.Errorf("create s3 upload request: %w", err)
+	}
+
+	req.ContentLength = body.size
+	req.Header.Set("Content-Type", contentType)
+	if overwrite != nil && !*overwrite {
+		req.Header.Set("If-None-Match", "*")
+	}
+
+	u.signer.sign(req, body.payloadHash, now)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("upload asset to s3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("upload asset to s3: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	return strings.Trim(resp.Header.Get("ETag"), `"`), nil
+}
+
+func (u *s3Uploader) publicID(options UploadOptions) (string, error) {
+	name := options.PublicID
+	if name == "" {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return "", fmt.Errorf("generate s3 object name: %w", err)
+		}
+		name = hex.EncodeToString(random)
+	}
+
+	// Folders nest under the configured prefix, so every object stays within it.
+	return strings.TrimLeft(path.Join(u.config.Prefix, options.Folder, name), "/"), nil
+}
+
+// objectURL addresses key in the bucket. Endpoints served under a base path, such as a store
+// behind a proxy, keep it ahead of the bucket and key.
+func (u *s3Uploader) objectURL(key string) *url.URL {
+	target := *u.endpoint
+	base := strings.TrimRight(u.endpoint.Path, "/")
+	if u.config.UsePathStyle {
+		target.Path = base + "/" + u.config.Bucket + "/" + key
+	} else {
+		target.Host = u.config.Bucket + "." + u.endpoint.Host
+		target.Path = base + "/" + key
+	}
+	target.RawPath = ""
+
+	return &target
+}
+
+func (u *s3Uploader) deliveryURL(key string) string {
+	if u.config.PublicBaseURL != "" {
+		return strings.TrimRight(u.config.PublicBaseURL, "/") + "/" + key
+	}
+
+	return u.objectURL(key).String()
+}
@@ -2,11 +2,18 @@ package assets
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
@@ -21,9 +28,12 @@ type UploadOptions struct {
 	PublicID     string
 	ResourceType string
 	Overwrite    *bool
+	// Variants lists the renditions to produce for image uploads. Backends either render
+	// them at upload time or describe URLs that render them on demand.
+	Variants []Variant
 }
 
-// UploadResult captures the relevant metadata returned by Cloudinary after an upload.
+// UploadResult captures the relevant metadata returned by the asset backend after an upload.
 type UploadResult struct {
 	AssetID      string
 	PublicID     string
@@ -36,11 +46,14 @@ type UploadResult struct {
 	Width        int
 	Height       int
 	CreatedAt    time.Time
+	Variants     []VariantResult
 }
 
 type cloudinaryUploader struct {
 	client        *cloudinary.Cloudinary
 	defaultFolder string
+	httpClient    *http.Client
+	now           func() time.Time
 }
 
 // NewCloudinaryUploader initialises an uploader backed by Cloudinary.
@@ -50,7 +63,12 @@ func NewCloudinaryUploader(cloudName, apiKey, apiSecret, defaultFolder string) (
 		return nil, fmt.Errorf("create cloudinary client: %w", err)
 	}
 
-	return &cloudinaryUploader{client: client, defaultFolder: defaultFolder}, nil
+	return &cloudinaryUploader{
+		client:        client,
+		defaultFolder: defaultFolder,
+		httpClient:    &http.Client{Timeout: 5 * time.Minute},
+		now:           time.Now,
+	}, nil
 }
 
 // Upload pushes a file to Cloudinary and returns the metadata describing the stored asset.
@@ -78,7 +96,11 @@ func (u *cloudinaryUploader) Upload(ctx context.Context, file io.Reader, options
 		return nil, fmt.Errorf("upload asset to cloudinary: %w", err)
 	}
 
-	return &UploadResult{
+	return cloudinaryResult(result, options), nil
+}
+
+func cloudinaryResult(result *uploader.UploadResult, options UploadOptions) *UploadResult {
+	uploaded := &UploadResult{
 		AssetID:      result.AssetID,
 		PublicID:     result.PublicID,
 		Format:       result.Format,
@@ -90,5 +112,189 @@ func (u *cloudinaryUploader) Upload(ctx context.Context, file io.Reader, options
 		Width:        result.Width,
 		Height:       result.Height,
 		CreatedAt:    result.CreatedAt,
+	}
+
+	if variantsApply(uploaded) {
+		for _, variant := range options.Variants {
+			uploaded.Variants = append(uploaded.Variants, cloudinaryVariant(uploaded, variant))
+		}
+	}
+
+	return uploaded
+}
+
+// PresignUpload signs the parameters of a multipart POST to Cloudinary's upload API. The
+// signature covers the public ID, so the client cannot choose where the file is stored.
+func (u *cloudinaryUploader) PresignUpload(_ context.Context, options PresignOptions) (*PresignedUpload, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("generate cloudinary public id: %w", err)
+	}
+	publicID := hex.EncodeToString(random)
+
+	folder := u.defaultFolder
+	if options.Folder != "" {
+		folder = options.Folder
+	}
+
+	expires := options.Expires
+	if expires <= 0 {
+		expires = DefaultPresignExpiry
+	}
+
+	// Cloudinary rejects signatures whose timestamp is more than an hour old, so the timestamp
+	// is backdated to make the parameters expire after the requested duration instead.
+	now := u.now()
+	timestamp := now.Add(expires - time.Hour)
+	if expires > time.Hour {
+		timestamp = now
+	}
+
+	params := url.Values{}
+	params.Set("public_id", publicID)
+	params.Set("timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+	if folder != "" {
+		params.Set("folder", folder)
+	}
+
+	signature, err := api.SignParameters(params, u.client.Config.Cloud.APISecret)
+	if err != nil {
+		return nil, fmt.Errorf("sign cloudinary upload: %w", err)
+	}
+
+	fields := map[string]string{
+		"api_key":   u.client.Config.Cloud.APIKey,
+		"signature": signature,
+	}
+	for name := range params {
+		fields[name] = params.Get(name)
+	}
+
+	key := publicID
+	if folder != "" {
+		key = strings.Trim(folder, "/") + "/" + publicID
+	}
+
+	resourceType := directResourceType(options.ContentType)
+
+	return &PresignedUpload{
+		DirectUpload: DirectUpload{Key: key, ResourceType: resourceType},
+		Method:       http.MethodPost,
+		URL:          fmt.Sprintf("%s/v1_1/%s/%s/upload", strings.TrimRight(u.client.Config.API.UploadPrefix, "/"), u.client.Config.Cloud.CloudName, resourceType),
+		Fields:       fields,
+		ExpiresAt:    timestamp.Add(time.Hour).UTC(),
 	}, nil
 }
+
+// FetchUpload downloads the original of a directly uploaded asset from its delivery URL.
+func (u *cloudinaryUploader) FetchUpload(ctx context.Context, upload DirectUpload) (io.ReadCloser, error) {
+	result, err := u.explicit(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.SecureURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create cloudinary download request: %w", err)
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download cloudinary asset: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download cloudinary asset: unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// DescribeUpload reads the metadata Cloudinary recorded for a directly uploaded asset. Variants
+// are transformation URLs, so the file itself is not needed.
+func (u *cloudinaryUploader) DescribeUpload(ctx context.Context, upload DirectUpload, _ io.ReadSeeker, options UploadOptions) (*UploadResult, error) {
+	result, err := u.explicit(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudinaryResult(result, options), nil
+}
+
+// DeleteUpload destroys a directly uploaded asset.
+func (u *cloudinaryUploader) DeleteUpload(ctx context.Context, upload DirectUpload) error {
+	result, err := u.client.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     upload.Key,
+		ResourceType: upload.ResourceType,
+		Invalidate:   api.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("delete cloudinary asset: %w", err)
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("delete cloudinary asset: %s", result.Error.Message)
+	}
+
+	return nil
+}
+
+// explicit looks up an uploaded asset through the explicit method, which returns the same
+// metadata as an upload.
+func (u *cloudinaryUploader) explicit(ctx context.Context, upload DirectUpload) (*uploader.UploadResult, error) {
+	result, err := u.client.Upload.Explicit(ctx, uploader.ExplicitParams{
+		PublicID:     upload.Key,
+		Type:         "upload",
+		ResourceType: upload.ResourceType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("look up cloudinary asset: %w", err)
+	}
+	if result.Error.Message != "" {
+		if strings.Contains(strings.ToLower(result.Error.Message), "not found") {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, upload.Key)
+		}
+		return nil, fmt.Errorf("look up cloudinary asset: %s", result.Error.Message)
+	}
+
+	return &result.UploadResult, nil
+}
+
+// cloudinaryVariant describes a variant as a delivery URL with an inline transformation.
+// Cloudinary renders and caches the rendition the first time the URL is requested.
+func cloudinaryVariant(result *UploadResult, variant Variant) VariantResult {
+	width, height := variant.Dimensions(result.Width, result.Height)
+	format := variant.outputFormat(result.Format)
+
+	transformation := "c_limit"
+	if variant.Crop {
+		transformation = "c_fill"
+	}
+	if variant.Width > 0 {
+		transformation += fmt.Sprintf(",w_%d", variant.Width)
+	}
+	if variant.Height > 0 {
+		transformation += fmt.Sprintf(",h_%d", variant.Height)
+	}
+
+	url := result.SecureURL
+	if index := strings.Index(url, "/upload/"); index >= 0 {
+		split := index + len("/upload/")
+		url = url[:split] + transformation + "/" + url[split:]
+	}
+
+	if format != result.Format {
+		if dot := strings.LastIndex(url, "."); dot > strings.LastIndex(url, "/") {
+			url = url[:dot]
+		}
+		url += "." + format
+	}
+
+	return VariantResult{
+		Name:   variant.Name,
+		Format: format,
+		URL:    url,
+		Width:  width,
+		Height: height,
+	}
+}
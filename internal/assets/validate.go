@@ -0,0 +1,347 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultAllowedTypes lists the media types accepted for upload when none are configured.
+const DefaultAllowedTypes = "image/png,image/jpeg,image/gif,image/webp,image/svg+xml,application/pdf"
+
+// DefaultMaxDimension is the default limit on the width and height of uploaded images.
+const DefaultMaxDimension = 10000
+
+const (
+	// probeHeadBytes is how much of a file ValidateFile reads to detect its type and dimensions.
+	probeHeadBytes = 1 << 20
+	// maxSVGBytes bounds the documents SanitizeSVG holds in memory.
+	maxSVGBytes = 10 << 20
+	// polyglotBlockBytes is the read size used when scanning files for embedded markup.
+	polyglotBlockBytes = 64 << 10
+)
+
+var (
+	// ErrUnsupportedType is returned when a file's sniffed media type is not allowed.
+	ErrUnsupportedType = errors.New("unsupported file type")
+	// ErrInvalidFile is returned when a file of an allowed type fails a content check.
+	ErrInvalidFile = errors.New("invalid file")
+)
+
+// ValidationPolicy describes which uploads are accepted. The file contents, rather than the
+// client supplied name or content type, decide what a file is.
+type ValidationPolicy struct {
+	// AllowedTypes holds media types such as image/png. A trailing /* allows a whole family.
+	AllowedTypes []string
+	// MaxWidth and MaxHeight limit raster image dimensions. Zero disables the limit. Raster
+	// images whose dimensions cannot be read are rejected either way.
+	MaxWidth  int
+	MaxHeight int
+}
+
+// ParseAllowedTypes splits a comma separated list of media types.
+func ParseAllowedTypes(list string) []string {
+	types := make([]string, 0)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			types = append(types, entry)
+		}
+	}
+	return types
+}
+
+// Validate checks an upload against the policy. It returns the bytes that should be stored,
+// which differ from the input when an SVG had to be sanitised, along with the probe result.
+func (p ValidationPolicy) Validate(filename string, data []byte) ([]byte, Probe, error) {
+	stored, probe, err := p.ValidateFile(filename, bytes.NewReader(data))
+	if err != nil {
+		return nil, probe, err
+	}
+
+	if probe.Format != "svg" {
+		return data, probe, nil
+	}
+
+	sanitised, err := io.ReadAll(stored)
+	if err != nil {
+		return nil, probe, err
+	}
+	return sanitised, probe, nil
+}
+
+// ValidateFile applies the same checks as Validate to a file on disk, such as a completed
+// chunked upload, without reading the whole file into memory. The returned reader yields the
+// bytes that should be stored and is positioned at the start of the content.
+func (p ValidationPolicy) ValidateFile(filename string, file io.ReadSeeker) (io.ReadSeeker, Probe, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, Probe{}, fmt.Errorf("measure upload: %w", err)
+	}
+	if size == 0 {
+		return nil, Probe{}, fmt.Errorf("%w: file is empty", ErrInvalidFile)
+	}
+
+	head := make([]byte, min(size, probeHeadBytes))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, Probe{}, fmt.Errorf("rewind upload: %w", err)
+	}
+	if _, err := io.ReadFull(file, head); err != nil {
+		return nil, Probe{}, fmt.Errorf("read upload: %w", err)
+	}
+
+	probe := ProbeFile(head)
+	mediaType := strings.TrimSpace(strings.SplitN(probe.ContentType, ";", 2)[0])
+
+	if !p.Allows(mediaType) {
+		return nil, probe, fmt.Errorf("%w: %s is not allowed", ErrUnsupportedType, mediaType)
+	}
+
+	if ext := extensionFormat(filename); ext != "" && ext != probe.Format {
+		return nil, probe, fmt.Errorf("%w: file extension .%s does not match detected type %s", ErrInvalidFile, ext, mediaType)
+	}
+
+	if probe.Format == "svg" {
+		if size > maxSVGBytes {
+			return nil, probe, fmt.Errorf("%w: svg documents are limited to %d bytes", ErrInvalidFile, maxSVGBytes)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, probe, fmt.Errorf("rewind upload: %w", err)
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, probe, fmt.Errorf("read upload: %w", err)
+		}
+		sanitised, err := SanitizeSVG(data)
+		if err != nil {
+			return nil, probe, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		return bytes.NewReader(sanitised), probe, nil
+	}
+
+	if probe.ResourceType == "image" && probe.Width == 0 {
+		// The probe only sees the head of the file, which can end inside large metadata
+		// segments, so read the header from the whole file before giving up on it.
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, probe, fmt.Errorf("rewind upload: %w", err)
+		}
+		config, _, err := image.DecodeConfig(file)
+		if err != nil {
+			return nil, probe, fmt.Errorf("%w: could not read %s image dimensions", ErrInvalidFile, probe.Format)
+		}
+		probe.Width = config.Width
+		probe.Height = config.Height
+	}
+
+	// Documents such as PDFs legitimately contain markup and scripts, so only images, which
+	// have no reason to, are scanned.
+	if probe.ResourceType == "image" {
+		marker, err := scanPolyglot(file, size)
+		if err != nil {
+			return nil, probe, fmt.Errorf("scan upload: %w", err)
+		}
+		if marker != "" {
+			return nil, probe, fmt.Errorf("%w: %s content contains embedded %s", ErrInvalidFile, probe.Format, marker)
+		}
+	}
+
+	if p.MaxWidth > 0 && probe.Width > p.MaxWidth || p.MaxHeight > 0 && probe.Height > p.MaxHeight {
+		return nil, probe, fmt.Errorf("%w: image is %dx%d, the limit is %dx%d", ErrInvalidFile, probe.Width, probe.Height, p.MaxWidth, p.MaxHeight)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, probe, fmt.Errorf("rewind upload: %w", err)
+	}
+
+	return file, probe, nil
+}
+
+// Allows reports whether a media type, without parameters, is on the allow-list.
+func (p ValidationPolicy) Allows(mediaType string) bool {
+	for _, allowed := range p.AllowedTypes {
+		if allowed == mediaType {
+			return true
+		}
+		if family, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, family+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionFormat normalises a file name's extension to the format names reported by ProbeFile.
+func extensionFormat(filename string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	switch ext {
+	case "jpg", "jpe":
+		return "jpeg"
+	case "htm":
+		return "html"
+	case "tif":
+		return "tiff"
+	default:
+		return ext
+	}
+}
+
+var polyglotMarkers = []string{"<script", "<html", "<!doctype html", "<?php", "<%@"}
+
+// polyglotMarker looks for content that browsers or servers could interpret as something other
+// than the detected type, such as markup appended to an image or a trailing zip archive.
+func polyglotMarker(data []byte) string {
+	marker, _ := scanPolyglot(bytes.NewReader(data), int64(len(data)))
+	return marker
+}
+
+// scanPolyglot is polyglotMarker for content that is read in blocks. Each block keeps the end
+// of the previous one so that markers spanning a block boundary are still found.
+func scanPolyglot(file io.ReadSeeker, size int64) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	overlap := 0
+	for _, marker := range polyglotMarkers {
+		overlap = max(overlap, len(marker)-1)
+	}
+
+	block := make([]byte, overlap+polyglotBlockBytes)
+	carried := 0
+	for {
+		n, err := io.ReadFull(file, block[carried:])
+		if n > 0 {
+			lowered := bytes.ToLower(block[:carried+n])
+			for _, marker := range polyglotMarkers {
+				if bytes.Contains(lowered, []byte(marker)) {
+					return marker + " markup", nil
+				}
+			}
+			carried = min(overlap, len(lowered))
+			copy(block, lowered[len(lowered)-carried:])
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// A zip archive is located through the end of central directory record near the end of
+	// the file, so an archive appended to an image remains readable.
+	tailStart := max(0, size-(1<<16+22))
+	if _, err := file.Seek(tailStart, io.SeekStart); err != nil {
+		return "", err
+	}
+	tail, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Contains(tail, []byte("PK\x05\x06")) {
+		return "zip archive", nil
+	}
+
+	return "", nil
+}
+
+var svgBlockedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+}
+
+// SanitizeSVG re-serialises an SVG document without scripts, event handler attributes,
+// external entity declarations or links to script and non-image data URLs.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var out bytes.Buffer
+	skipDepth := 0
+
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("parse svg: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || svgBlockedElements[strings.ToLower(t.Name.Local)] {
+				skipDepth++
+				continue
+			}
+
+			out.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range t.Attr {
+				if !safeSVGAttribute(attr) {
+					continue
+				}
+				out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+				xml.EscapeText(&out, []byte(attr.Value))
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth == 0 {
+				xml.EscapeText(&out, t)
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" && out.Len() == 0 {
+				out.WriteString("<?xml " + string(t.Inst) + "?>")
+			}
+		}
+	}
+
+	if out.Len() == 0 {
+		return nil, errors.New("parse svg: document is empty")
+	}
+
+	return out.Bytes(), nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func safeSVGAttribute(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	value := strings.ToLower(strings.Join(strings.Fields(attr.Value), ""))
+
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+
+	if strings.Contains(value, "javascript:") || strings.Contains(value, "vbscript:") {
+		return false
+	}
+
+	if local == "href" && strings.HasPrefix(value, "data:") {
+		for _, allowed := range []string{"data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp"} {
+			if strings.HasPrefix(value, allowed) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
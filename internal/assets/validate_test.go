@@ -0,0 +1,182 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testPolicy() ValidationPolicy {
+	return ValidationPolicy{
+		AllowedTypes: ParseAllowedTypes(DefaultAllowedTypes),
+		MaxWidth:     100,
+		MaxHeight:    100,
+	}
+}
+
+func TestValidationPolicy_AcceptsAllowedImage(t *testing.T) {
+	payload := testPNG(t, 20, 10)
+
+	stored, probe, err := testPolicy().Validate("banner.PNG", payload)
+	if err != nil {
+		t.Fatalf("expected png to be accepted: %v", err)
+	}
+
+	if probe.Format != "png" || probe.Width != 20 {
+		t.Fatalf("unexpected probe %+v", probe)
+	}
+
+	if len(stored) != len(payload) {
+		t.Fatalf("expected raster images to be stored unchanged")
+	}
+}
+
+func TestValidationPolicy_Rejections(t *testing.T) {
+	png := testPNG(t, 20, 10)
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		policy   ValidationPolicy
+		expected error
+	}{
+		{"empty file", "empty.png", nil, testPolicy(), ErrInvalidFile},
+		{"html is not allowed", "page.html", []byte("<!DOCTYPE html><html><body>hi</body></html>"), testPolicy(), ErrUnsupportedType},
+		{"executable is not allowed", "tool", []byte("MZ\x90\x00\x03\x00\x00\x00"), testPolicy(), ErrUnsupportedType},
+		{"type outside configured list", "banner.png", png, ValidationPolicy{AllowedTypes: []string{"application/pdf"}}, ErrUnsupportedType},
+		{"mismatched extension", "banner.jpg", png, testPolicy(), ErrInvalidFile},
+		{"unknown extension", "banner.bin", png, testPolicy(), ErrInvalidFile},
+		{"image with appended markup", "banner.png", append(append([]byte{}, png...), []byte("<SCRIPT>alert(1)</script>")...), testPolicy(), ErrInvalidFile},
+		{"image with appended zip", "banner.png", append(append([]byte{}, png...), []byte("PK\x05\x06\x00\x00\x00\x00")...), testPolicy(), ErrInvalidFile},
+		{"dimensions too large", "banner.png", testPNG(t, 101, 10), testPolicy(), ErrInvalidFile},
+		{"unreadable dimensions", "banner.png", png[:20], testPolicy(), ErrInvalidFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.policy.Validate(tt.filename, tt.data)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidationPolicy_FamilyWildcard(t *testing.T) {
+	policy := ValidationPolicy{AllowedTypes: []string{"image/*"}}
+
+	if _, _, err := policy.Validate("banner.png", testPNG(t, 2, 2)); err != nil {
+		t.Fatalf("expected image/* to allow png: %v", err)
+	}
+
+	if _, _, err := policy.Validate("talk.pdf", []byte("%PDF-1.7\n...")); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected pdf to be rejected, got %v", err)
+	}
+}
+
+func TestValidationPolicy_AcceptsDocumentsWithScripts(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n1 0 obj << /S /JavaScript /JS (app.alert(1)) >> endobj\n<script>\n%%EOF\n")
+
+	if _, _, err := testPolicy().Validate("talk.pdf", pdf); err != nil {
+		t.Fatalf("expected pdf to be accepted: %v", err)
+	}
+}
+
+func TestValidationPolicy_ReadsDimensionsPastProbeHead(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 101, 10)), nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+
+	// Insert application segments after the start of image marker so that the frame header
+	// with the dimensions lies beyond the bytes ProbeFile sees.
+	payload := append([]byte{}, encoded.Bytes()[:2]...)
+	segment := append([]byte{0xFF, 0xEF, 0xFF, 0xFF}, make([]byte, 0xFFFD)...)
+	for len(payload) <= probeHeadBytes {
+		payload = append(payload, segment...)
+	}
+	payload = append(payload, encoded.Bytes()[2:]...)
+
+	_, _, err := testPolicy().ValidateFile("photo.jpg", bytes.NewReader(payload))
+	if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), "101x10") {
+		t.Fatalf("expected the dimension limit to apply, got %v", err)
+	}
+}
+
+func TestValidationPolicy_SanitisesSVG(t *testing.T) {
+	svg := `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10" onload="alert(1)">
+  <script>alert(2)</script>
+  <foreignObject><iframe src="https://example.com"></iframe></foreignObject>
+  <a xlink:href="javascript:alert(3)"><rect width="10" height="10" fill="red"/></a>
+  <image href="data:text/html;base64,PHNjcmlwdD4="/>
+  <image href="data:image/png;base64,iVBORw0KGgo="/>
+</svg>`
+
+	stored, probe, err := testPolicy().Validate("logo.svg", []byte(svg))
+	if err != nil {
+		t.Fatalf("expected svg to be accepted: %v", err)
+	}
+
+	if probe.ContentType != "image/svg+xml" || probe.Format != "svg" {
+		t.Fatalf("expected svg to be detected, got %+v", probe)
+	}
+
+	output := string(stored)
+	for _, unwanted := range []string{"onload", "<script", "alert(2)", "foreignObject", "iframe", "javascript:", "data:text/html"} {
+		if strings.Contains(output, unwanted) {
+			t.Fatalf("expected %q to be removed, got %s", unwanted, output)
+		}
+	}
+
+	for _, wanted := range []string{`xmlns:xlink="http://www.w3.org/1999/xlink"`, `<rect width="10" height="10" fill="red"></rect>`, "data:image/png"} {
+		if !strings.Contains(output, wanted) {
+			t.Fatalf("expected %q to be kept, got %s", wanted, output)
+		}
+	}
+}
+
+func TestValidationPolicy_RejectsSVGEntities(t *testing.T) {
+	svg := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>
+<svg xmlns="http://www.w3.org/2000/svg"><text>&xxe;</text></svg>`
+
+	if _, _, err := testPolicy().Validate("logo.svg", []byte(svg)); !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("expected undefined entity to be rejected, got %v", err)
+	}
+}
+
+func TestValidationPolicy_ValidateFileScansAcrossBlocks(t *testing.T) {
+	payload := testPNG(t, 20, 10)
+
+	// Slide the marker across the boundary between the first two scan blocks.
+	firstBlock := polyglotBlockBytes + len("<!doctype html") - 1
+	for start := polyglotBlockBytes - 5; start < firstBlock+1; start++ {
+		polyglot := append(append([]byte{}, payload...), make([]byte, start-len(payload))...)
+		polyglot = append(polyglot, []byte("<?php echo 1; ?>")...)
+
+		_, _, err := testPolicy().ValidateFile("banner.png", bytes.NewReader(polyglot))
+		if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), "<?php") {
+			t.Fatalf("expected embedded markup at offset %d to be rejected, got %v", start, err)
+		}
+	}
+
+	clean := append(append([]byte{}, payload...), make([]byte, 3*polyglotBlockBytes)...)
+	stored, probe, err := testPolicy().ValidateFile("banner.png", bytes.NewReader(clean))
+	if err != nil {
+		t.Fatalf("expected padded png to be accepted: %v", err)
+	}
+	if probe.Width != 20 {
+		t.Fatalf("unexpected probe %+v", probe)
+	}
+
+	read, err := io.ReadAll(stored)
+	if err != nil || len(read) != len(clean) {
+		t.Fatalf("expected the whole file to be readable from the start, read %d bytes: %v", len(read), err)
+	}
+}
@@ -0,0 +1,220 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Variant describes a named rendition of an uploaded image, such as a thumbnail.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	// Crop fills the exact Width x Height box, cropping the overflow. Otherwise the image is
+	// scaled to fit inside the box while keeping its aspect ratio and is never enlarged.
+	Crop bool
+	// Format requests an alternative encoding such as webp or avif. Empty keeps the original.
+	Format string
+}
+
+// VariantResult describes a rendition produced by a backend.
+type VariantResult struct {
+	Name   string
+	Format string
+	URL    string
+	Width  int
+	Height int
+	// Bytes is zero when the backend renders the variant on demand.
+	Bytes int64
+}
+
+// DefaultVariantSpec is the variant configuration used when none is supplied.
+const DefaultVariantSpec = "thumb=320x320:fill,card=800x450:fill,hero=1920x1080:fit"
+
+// DefaultVariantFormats lists the alternative encodings produced for each variant by default.
+const DefaultVariantFormats = "webp,avif"
+
+// ParseVariants reads a comma separated list of name=WIDTHxHEIGHT[:fill|:fit] entries. A zero
+// width or height leaves that side unconstrained. Each variant is produced in the original
+// format plus every format listed in formats.
+func ParseVariants(spec string, formats string) ([]Variant, error) {
+	var base []Variant
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, size, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("parse variant %q: expected name=WIDTHxHEIGHT", entry)
+		}
+
+		mode := "fit"
+		if dims, m, found := strings.Cut(size, ":"); found {
+			size, mode = dims, strings.ToLower(strings.TrimSpace(m))
+		}
+
+		widthText, heightText, ok := strings.Cut(strings.ToLower(size), "x")
+		if !ok {
+			return nil, fmt.Errorf("parse variant %q: expected WIDTHxHEIGHT", entry)
+		}
+
+		width, err := strconv.Atoi(strings.TrimSpace(widthText))
+		if err != nil || width < 0 {
+			return nil, fmt.Errorf("parse variant %q: invalid width", entry)
+		}
+
+		height, err := strconv.Atoi(strings.TrimSpace(heightText))
+		if err != nil || height < 0 {
+			return nil, fmt.Errorf("parse variant %q: invalid height", entry)
+		}
+
+		if width == 0 && height == 0 {
+			return nil, fmt.Errorf("parse variant %q: width or height must be set", entry)
+		}
+
+		variant := Variant{Name: strings.TrimSpace(name), Width: width, Height: height}
+		switch mode {
+		case "fill":
+			if width == 0 || height == 0 {
+				return nil, fmt.Errorf("parse variant %q: fill requires both width and height", entry)
+			}
+			variant.Crop = true
+		case "fit":
+		default:
+			return nil, fmt.Errorf("parse variant %q: unknown mode %q", entry, mode)
+		}
+
+		base = append(base, variant)
+	}
+
+	variants := append([]Variant{}, base...)
+	for _, format := range strings.Split(formats, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+
+		for _, variant := range base {
+			variant.Format = format
+			variants = append(variants, variant)
+		}
+	}
+
+	return variants, nil
+}
+
+// Dimensions calculates the size of the variant when rendered from a source image of the given size.
+func (v Variant) Dimensions(sourceWidth, sourceHeight int) (int, int) {
+	if sourceWidth <= 0 || sourceHeight <= 0 {
+		return 0, 0
+	}
+
+	if v.Crop {
+		return v.Width, v.Height
+	}
+
+	scale := 1.0
+	if v.Width > 0 {
+		scale = min(scale, float64(v.Width)/float64(sourceWidth))
+	}
+	if v.Height > 0 {
+		scale = min(scale, float64(v.Height)/float64(sourceHeight))
+	}
+
+	width := max(1, int(float64(sourceWidth)*scale+0.5))
+	height := max(1, int(float64(sourceHeight)*scale+0.5))
+
+	return width, height
+}
+
+func (v Variant) outputFormat(sourceFormat string) string {
+	if v.Format != "" {
+		return v.Format
+	}
+	return sourceFormat
+}
+
+// variantsApply reports whether variants can be rendered for the uploaded file.
+func variantsApply(result *UploadResult) bool {
+	return result.ResourceType == "image" && result.Width > 0 && result.Height > 0
+}
+
+// CheckLocalVariants fails when a variant is in a format that cannot be encoded locally, as the
+// S3 backend must, naming every such format so the configuration can be fixed.
+func CheckLocalVariants(variants []Variant) error {
+	var unsupported []string
+	for _, variant := range variants {
+		if variant.Format == "" || canRender(variant.Format) || slices.Contains(unsupported, variant.Format) {
+			continue
+		}
+		unsupported = append(unsupported, variant.Format)
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("variant formats %s cannot be encoded locally; only jpeg, png and gif can", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// canRender reports whether variants in the given format can be encoded locally.
+func canRender(format string) bool {
+	switch format {
+	case "jpeg", "jpg", "png", "gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// renderVariant scales (and for cropped variants, centre-crops) the source image and encodes it
+// in the requested format.
+func renderVariant(source image.Image, variant Variant, format string) ([]byte, int, int, error) {
+	bounds := source.Bounds()
+	width, height := variant.Dimensions(bounds.Dx(), bounds.Dy())
+	if width == 0 || height == 0 {
+		return nil, 0, 0, fmt.Errorf("render variant %q: source image has no dimensions", variant.Name)
+	}
+
+	sourceRect := bounds
+	if variant.Crop {
+		scale := max(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+		cropWidth := min(bounds.Dx(), int(float64(width)/scale+0.5))
+		cropHeight := min(bounds.Dy(), int(float64(height)/scale+0.5))
+		x0 := bounds.Min.X + (bounds.Dx()-cropWidth)/2
+		y0 := bounds.Min.Y + (bounds.Dy()-cropHeight)/2
+		sourceRect = image.Rect(x0, y0, x0+cropWidth, y0+cropHeight)
+	}
+
+	target := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(target, target.Bounds(), source, sourceRect, draw.Src, nil)
+
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "jpeg", "jpg":
+		err = jpeg.Encode(&buf, target, &jpeg.Options{Quality: 85})
+	case "png":
+		err = png.Encode(&buf, target)
+	case "gif":
+		err = gif.Encode(&buf, target, nil)
+	default:
+		err = fmt.Errorf("render variant %q: cannot encode %s", variant.Name, format)
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return buf.Bytes(), width, height, nil
+}
@@ -0,0 +1,169 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseVariants(t *testing.T) {
+	variants, err := ParseVariants("thumb=320x320:fill, hero=1920x0", "webp")
+	if err != nil {
+		t.Fatalf("parse variants: %v", err)
+	}
+
+	expected := []Variant{
+		{Name: "thumb", Width: 320, Height: 320, Crop: true},
+		{Name: "hero", Width: 1920},
+		{Name: "thumb", Width: 320, Height: 320, Crop: true, Format: "webp"},
+		{Name: "hero", Width: 1920, Format: "webp"},
+	}
+
+	if len(variants) != len(expected) {
+		t.Fatalf("expected %d variants, got %+v", len(expected), variants)
+	}
+
+	for i := range expected {
+		if variants[i] != expected[i] {
+			t.Fatalf("variant %d: expected %+v, got %+v", i, expected[i], variants[i])
+		}
+	}
+}
+
+func TestParseVariants_Invalid(t *testing.T) {
+	specs := []string{"thumb", "thumb=axb", "thumb=0x0", "thumb=320x0:fill", "thumb=10x10:stretch"}
+
+	for _, spec := range specs {
+		if _, err := ParseVariants(spec, ""); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestCheckLocalVariants(t *testing.T) {
+	variants, err := ParseVariants("thumb=320x320:fill,hero=1920x0", "png,gif")
+	if err != nil {
+		t.Fatalf("parse variants: %v", err)
+	}
+	if err := CheckLocalVariants(variants); err != nil {
+		t.Fatalf("expected png and gif variants to be accepted, got %v", err)
+	}
+
+	variants, err = ParseVariants("thumb=320x320:fill,hero=1920x0", "webp,png,avif")
+	if err != nil {
+		t.Fatalf("parse variants: %v", err)
+	}
+	err = CheckLocalVariants(variants)
+	if err == nil || !strings.Contains(err.Error(), "webp, avif") {
+		t.Fatalf("expected webp and avif to be named once each, got %v", err)
+	}
+}
+
+func TestVariantDimensions(t *testing.T) {
+	tests := []struct {
+		name           string
+		variant        Variant
+		width, height  int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{"fill uses the exact box", Variant{Width: 320, Height: 320, Crop: true}, 1200, 800, 320, 320},
+		{"fit keeps aspect ratio", Variant{Width: 800, Height: 450}, 1200, 800, 675, 450},
+		{"fit never enlarges", Variant{Width: 1920, Height: 1080}, 640, 480, 640, 480},
+		{"unconstrained height", Variant{Width: 600}, 1200, 800, 600, 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := tt.variant.Dimensions(tt.width, tt.height)
+			if width != tt.expectedWidth || height != tt.expectedHeight {
+				t.Fatalf("expected %dx%d, got %dx%d", tt.expectedWidth, tt.expectedHeight, width, height)
+			}
+		})
+	}
+}
+
+func TestCloudinaryVariant(t *testing.T) {
+	result := &UploadResult{
+		Format:    "jpg",
+		SecureURL: "https://res.cloudinary.com/demo/image/upload/v1/website/banner.jpg",
+		Width:     1200,
+		Height:    800,
+	}
+
+	variant := cloudinaryVariant(result, Variant{Name: "card", Width: 800, Height: 450, Crop: true, Format: "webp"})
+
+	expectedURL := "https://res.cloudinary.com/demo/image/upload/c_fill,w_800,h_450/v1/website/banner.webp"
+	if variant.URL != expectedURL {
+		t.Fatalf("expected %s, got %s", expectedURL, variant.URL)
+	}
+
+	if variant.Width != 800 || variant.Height != 450 || variant.Format != "webp" {
+		t.Fatalf("unexpected variant metadata %+v", variant)
+	}
+}
+
+func TestS3Uploader_RendersVariants(t *testing.T) {
+	store := newFakeS3()
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	uploader, err := NewS3Uploader(S3Config{
+		Bucket:          "website",
+		Endpoint:        server.URL,
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+	})
+	if err != nil {
+		t.Fatalf("create uploader: %v", err)
+	}
+
+	variants, err := ParseVariants("thumb=10x10:fill,card=40x0", "webp")
+	if err != nil {
+		t.Fatalf("parse variants: %v", err)
+	}
+
+	result, err := uploader.Upload(context.Background(), bytes.NewReader(testPNG(t, 120, 60)), UploadOptions{PublicID: "banner", Variants: variants})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	if len(result.Variants) != 2 {
+		t.Fatalf("expected webp variants to be skipped, got %+v", result.Variants)
+	}
+
+	thumb := result.Variants[0]
+	if thumb.Name != "thumb" || thumb.Width != 10 || thumb.Height != 10 || thumb.URL != server.URL+"/website/banner_thumb.png" {
+		t.Fatalf("unexpected thumb variant %+v", thumb)
+	}
+
+	stored, ok := store.objects["/website/banner_card.png"]
+	if !ok {
+		t.Fatalf("expected card variant to be stored, have %v", store.objects)
+	}
+
+	decoded, err := png.DecodeConfig(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatalf("decode stored variant: %v", err)
+	}
+
+	if decoded.Width != 40 || decoded.Height != 20 {
+		t.Fatalf("expected stored card variant to be 40x20, got %dx%d", decoded.Width, decoded.Height)
+	}
+
+	if result.Variants[1].Bytes != int64(len(stored)) {
+		t.Fatalf("expected variant bytes to match stored object")
+	}
+
+	upload := DirectUpload{Key: result.PublicID, Variants: result.Variants}
+	if err := uploader.(DirectUploader).DeleteUpload(context.Background(), upload); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(store.objects) != 0 {
+		t.Fatalf("expected the object and its variants to be deleted, have %v", store.objects)
+	}
+}
@@ -0,0 +1,274 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"api.etin.dev/pkg/querybuilder"
+)
+
+// ErrAssetOrderMismatch is returned when a reorder request does not list exactly the assets
+// currently linked to the item.
+var ErrAssetOrderMismatch = errors.New("asset order must list every linked asset exactly once")
+
+// AssetLink attaches an asset to a note, project or role as part of an ordered gallery.
+type AssetLink struct {
+	ItemType ItemType `json:"itemType"`
+	ItemID   int64    `json:"itemId" db:"itemId"`
+	AssetID  int64    `json:"assetId" db:"assetId"`
+	Position int      `json:"position" db:"position"`
+	Caption  string   `json:"caption" db:"caption"`
+	AltText  string   `json:"altText" db:"altText"`
+	Asset    *Asset   `json:"asset,omitempty"`
+}
+
+type AssetLinkModel struct {
+	DB     querybuilder.Executor
+	Query  *querybuilder.QueryBuilder
+	Logger *log.Logger
+}
+
+type assetLinkTable struct {
+	table  string
+	column string
+}
+
+var assetLinkTables = map[ItemType]assetLinkTable{
+	ItemTypeNotes:    {table: "note_assets", column: "noteId"},
+	ItemTypeProjects: {table: "project_assets", column: "projectId"},
+	ItemTypeRoles:    {table: "role_assets", column: "roleId"},
+}
+
+func linkTableFor(itemType ItemType) (assetLinkTable, error) {
+	table, ok := assetLinkTables[itemType]
+	if !ok {
+		return assetLinkTable{}, ErrInvalidItemType
+	}
+	return table, nil
+}
+
+// Attach links an asset to the end of an item's gallery.
+func (m AssetLinkModel) Attach(link *AssetLink) error {
+	table, err := linkTableFor(link.ItemType)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+        INSERT INTO %[1]s (%[2]s, assetId, position, caption, altText)
+        VALUES ($1, $2, (SELECT COALESCE(MAX(position) + 1, 0) FROM %[1]s WHERE %[2]s = $1), $3, $4)
+        RETURNING position
+    `, table.table, table.column)
+
+	return m.Query.Executor().QueryRowContext(m.Query.Context(), query, link.ItemID, link.AssetID, link.Caption, link.AltText).Scan(&link.Position)
+}
+
+// Update changes the caption and alt text of an existing link.
+func (m AssetLinkModel) Update(link *AssetLink) error {
+	table, err := linkTableFor(link.ItemType)
+	if err != nil {
+		return err
+	}
+
+	values := querybuilder.Clauses{
+		querybuilder.Clause{ColumnName: "caption", Value: link.Caption},
+		querybuilder.Clause{ColumnName: "altText", Value: link.AltText},
+	}
+
+	row, err := m.Query.SetBaseTable(table.table).Update(values).
+		WhereEqual(table.column, link.ItemID).
+		WhereEqual("assetId", link.AssetID).
+		Returning("position").
+		QueryRow()
+	if err != nil {
+		return err
+	}
+
+	if err := querybuilder.ScanStruct(row, link, "position"); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("record not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Detach removes an asset from an item's gallery.
+func (m AssetLinkModel) Detach(itemType ItemType, itemID, assetID int64) error {
+	table, err := linkTableFor(itemType)
+	if err != nil {
+		return err
+	}
+
+	results, err := m.Query.SetBaseTable(table.table).Delete().
+		WhereEqual(table.column, itemID).
+		WhereEqual("assetId", assetID).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("record not found")
+	}
+
+	return nil
+}
+
+// Reorder sets gallery positions to match the order of assetIDs, which must contain every
+// asset currently linked to the item.
+func (m AssetLinkModel) Reorder(itemType ItemType, itemID int64, assetIDs []int64) error {
+	table, err := linkTableFor(itemType)
+	if err != nil {
+		return err
+	}
+
+	ctx := m.Query.Context()
+	return runInTx(ctx, m.DB, func(tx querybuilder.Executor) error {
+		tx = querybuilder.Instrument(tx, m.Query.Hooks...)
+
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT assetId FROM %s WHERE %s = $1 FOR UPDATE", table.table, table.column), itemID)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[int64]bool)
+		for rows.Next() {
+			var assetID int64
+			if err := rows.Scan(&assetID); err != nil {
+				rows.Close()
+				return err
+			}
+			current[assetID] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(current) != len(assetIDs) {
+			return ErrAssetOrderMismatch
+		}
+
+		seen := make(map[int64]bool, len(assetIDs))
+		for _, assetID := range assetIDs {
+			if !current[assetID] || seen[assetID] {
+				return ErrAssetOrderMismatch
+			}
+			seen[assetID] = true
+		}
+
+		update := fmt.Sprintf("UPDATE %s SET position = $1 WHERE %s = $2 AND assetId = $3", table.table, table.column)
+		for position, assetID := range assetIDs {
+			if _, err := tx.ExecContext(ctx, update, position, itemID, assetID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetForItem returns an item's gallery in display order.
+func (m AssetLinkModel) GetForItem(itemType ItemType, itemID int64) ([]*AssetLink, error) {
+	links, err := m.GetForItems(itemType, []int64{itemID})
+	if err != nil {
+		return nil, err
+	}
+
+	if links[itemID] == nil {
+		return []*AssetLink{}, nil
+	}
+
+	return links[itemID], nil
+}
+
+// GetForItems returns the galleries of several items of the same type, keyed by item ID.
+func (m AssetLinkModel) GetForItems(itemType ItemType, itemIDs []int64) (map[int64][]*AssetLink, error) {
+	table, err := linkTableFor(itemType)
+	if err != nil {
+		return nil, err
+	}
+
+	galleries := make(map[int64][]*AssetLink)
+	if len(itemIDs) == 0 {
+		return galleries, nil
+	}
+
+	linkColumn := func(column string) string {
+		return "links." + column
+	}
+
+	columns := []string{linkColumn(table.column) + " AS itemId", linkColumn("position"), linkColumn("caption"), linkColumn("altText")}
+	for _, column := range assetColumns {
+		columns = append(columns, "assets."+column)
+	}
+
+	rows, err := m.Query.Select(columns...).From(table.table+" AS links").
+		InnerJoin("assets", "assetId", "id").
+		WhereIn(linkColumn(table.column), itemIDs).
+		WhereEqual("assets.deletedAt", nil).
+		OrderBy(linkColumn(table.column), "asc").
+		OrderBy(linkColumn("position"), "asc").
+		OrderBy(linkColumn("assetId"), "asc").
+		Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assetIDs := []int64{}
+	seen := map[int64]bool{}
+	links := []*AssetLink{}
+
+	for rows.Next() {
+		link := AssetLink{ItemType: itemType, Asset: &Asset{}}
+
+		linkTargets, finishLink, err := querybuilder.Targets(&link, "itemId", "position", "caption", "altText")
+		if err != nil {
+			return nil, err
+		}
+		assetTargets, finishAsset, err := querybuilder.Targets(link.Asset)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rows.Scan(append(linkTargets, assetTargets...)...); err != nil {
+			return nil, err
+		}
+
+		finishLink()
+		finishAsset()
+		link.AssetID = link.Asset.ID
+
+		links = append(links, &link)
+		if !seen[link.AssetID] {
+			seen[link.AssetID] = true
+			assetIDs = append(assetIDs, link.AssetID)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	variants, err := AssetModel{DB: m.DB, Query: m.Query, Logger: m.Logger}.getVariants(assetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		link.Asset.Variants = variants[link.AssetID]
+		galleries[link.ItemID] = append(galleries[link.ItemID], link)
+	}
+
+	return galleries, nil
+}
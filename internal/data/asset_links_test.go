@@ -0,0 +1,148 @@
+package data
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newAssetLinkModel(t *testing.T) (AssetLinkModel, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return AssetLinkModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}, mock
+}
+
+func TestAssetLinkModel_AttachAppendsToGallery(t *testing.T) {
+	m, mock := newAssetLinkModel(t)
+
+	mock.ExpectQuery(`INSERT INTO project_assets \(projectId, assetId, position, caption, altText\) VALUES \(\$1, \$2, \(SELECT COALESCE\(MAX\(position\) \+ 1, 0\) FROM project_assets WHERE projectId = \$1\), \$3, \$4\) RETURNING position`).
+		WithArgs(7, 3, "Launch day", "The team on stage").
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(2))
+
+	link := &AssetLink{ItemType: ItemTypeProjects, ItemID: 7, AssetID: 3, Caption: "Launch day", AltText: "The team on stage"}
+	if err := m.Attach(link); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if link.Position != 2 {
+		t.Fatalf("expected position 2, got %d", link.Position)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestAssetLinkModel_RejectsUnknownItemType(t *testing.T) {
+	m, _ := newAssetLinkModel(t)
+
+	if err := m.Attach(&AssetLink{ItemType: "companies", ItemID: 1, AssetID: 1}); !errors.Is(err, ErrInvalidItemType) {
+		t.Fatalf("expected ErrInvalidItemType, got %v", err)
+	}
+
+	if _, err := m.GetForItems("companies", []int64{1}); !errors.Is(err, ErrInvalidItemType) {
+		t.Fatalf("expected ErrInvalidItemType, got %v", err)
+	}
+}
+
+func TestAssetLinkModel_Reorder(t *testing.T) {
+	m, mock := newAssetLinkModel(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT assetId FROM note_assets WHERE noteId = \$1 FOR UPDATE`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"assetId"}).AddRow(1).AddRow(2))
+	mock.ExpectExec(`UPDATE note_assets SET position = \$1 WHERE noteId = \$2 AND assetId = \$3`).
+		WithArgs(0, 4, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE note_assets SET position = \$1 WHERE noteId = \$2 AND assetId = \$3`).
+		WithArgs(1, 4, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := m.Reorder(ItemTypeNotes, 4, []int64{2, 1}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestAssetLinkModel_ReorderMismatch(t *testing.T) {
+	for name, order := range map[string][]int64{
+		"missing asset":   {2},
+		"unknown asset":   {1, 3},
+		"duplicate asset": {1, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m, mock := newAssetLinkModel(t)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT assetId FROM role_assets WHERE roleId = \$1 FOR UPDATE`).
+				WithArgs(9).
+				WillReturnRows(sqlmock.NewRows([]string{"assetId"}).AddRow(1).AddRow(2))
+			mock.ExpectRollback()
+
+			if err := m.Reorder(ItemTypeRoles, 9, order); !errors.Is(err, ErrAssetOrderMismatch) {
+				t.Fatalf("expected ErrAssetOrderMismatch, got %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("there were unmet expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestAssetLinkModel_GetForItemsGroupsByItem(t *testing.T) {
+	m, mock := newAssetLinkModel(t)
+	now := time.Now()
+
+	columns := []string{"projectId", "position", "caption", "altText", "id", "createdAt", "updatedAt", "deletedAt", "url", "secureUrl", "publicId", "format", "resourceType", "bytes", "width", "height", "contentHash"}
+
+	mock.ExpectQuery(`SELECT links.projectId AS itemId, links.position, links.caption, links.altText, assets.id, .* FROM project_assets AS links INNER JOIN assets ON links.assetId = assets.id WHERE links.projectId IN \(\$1, \$2\) AND assets.deletedAt IS NULL ORDER BY links.projectId asc, links.position asc, links.assetId asc`).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 0, "First", "", 10, now, now, nil, "http://a", "https://a", "a", "png", "image", 100, 10, 10, nil).
+			AddRow(1, 1, nil, nil, 11, now, now, nil, "http://b", "https://b", "b", "png", "image", 100, 10, 10, nil).
+			AddRow(2, 0, "Other", "Alt", 10, now, now, nil, "http://a", "https://a", "a", "png", "image", 100, 10, 10, nil))
+	mock.ExpectQuery(`SELECT id, assetId, name, format, url, width, height, bytes FROM asset_variants WHERE assetId IN \(\$1, \$2\) ORDER BY assetId asc, width asc, id asc`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assetId", "name", "format", "url", "width", "height", "bytes"}).
+			AddRow(1, 10, "thumb", "png", "https://a_thumb", 5, 5, 20))
+
+	galleries, err := m.GetForItems(ItemTypeProjects, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(galleries[1]) != 2 || len(galleries[2]) != 1 {
+		t.Fatalf("unexpected galleries %+v", galleries)
+	}
+
+	if galleries[1][0].Caption != "First" || galleries[1][1].AssetID != 11 || galleries[2][0].AltText != "Alt" {
+		t.Fatalf("unexpected gallery contents %+v %+v", galleries[1], galleries[2])
+	}
+
+	if len(galleries[2][0].Asset.Variants) != 1 {
+		t.Fatalf("expected variants to be loaded for linked assets")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
@@ -4,33 +4,67 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"api.etin.dev/pkg/querybuilder"
 )
 
 type Asset struct {
-	ID           int64      `json:"id"`
-	CreatedAt    time.Time  `json:"-"`
-	UpdatedAt    time.Time  `json:"-"`
-	DeletedAt    *time.Time `json:"-"`
-	URL          string     `json:"url"`
-	SecureURL    string     `json:"secureUrl"`
-	PublicID     string     `json:"publicId"`
-	Format       string     `json:"format"`
-	ResourceType string     `json:"resourceType"`
-	Bytes        int        `json:"bytes"`
-	Width        int        `json:"width"`
-	Height       int        `json:"height"`
+	ID           int64      `json:"id" db:"id"`
+	CreatedAt    time.Time  `json:"-" db:"createdAt"`
+	UpdatedAt    time.Time  `json:"-" db:"updatedAt"`
+	DeletedAt    *time.Time `json:"-" db:"deletedAt"`
+	URL          string     `json:"url" db:"url"`
+	SecureURL    string     `json:"secureUrl" db:"secureUrl"`
+	PublicID     string     `json:"publicId" db:"publicId"`
+	Format       string     `json:"format" db:"format"`
+	ResourceType string     `json:"resourceType" db:"resourceType"`
+	Bytes        int        `json:"bytes" db:"bytes"`
+	Width        int        `json:"width" db:"width"`
+	Height       int        `json:"height" db:"height"`
+	// ContentHash is the hex encoded SHA-256 of the uploaded bytes. It is empty for assets
+	// uploaded before hashes were recorded.
+	ContentHash string         `json:"contentHash,omitempty" db:"contentHash"`
+	Variants    []AssetVariant `json:"variants,omitempty"`
 }
 
+// AssetVariant is a named rendition of an image asset, such as a thumbnail or a webp copy.
+type AssetVariant struct {
+	ID      int64  `json:"-" db:"id"`
+	AssetID int64  `json:"-" db:"assetId"`
+	Name    string `json:"name" db:"name"`
+	Format  string `json:"format" db:"format"`
+	URL     string `json:"url" db:"url"`
+	Width   int    `json:"width" db:"width"`
+	Height  int    `json:"height" db:"height"`
+	Bytes   int    `json:"bytes" db:"bytes"`
+}
+
+var (
+	assetColumns        = querybuilder.Columns(Asset{})
+	assetVariantColumns = querybuilder.Columns(AssetVariant{})
+)
+
 type AssetModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
 
+// Insert records asset and its variants in one transaction, so an asset is never left without
+// the variants rendered for it.
 func (m AssetModel) Insert(asset *Asset) error {
+	ctx := m.Query.Context()
+	return runInTx(ctx, m.DB, func(tx querybuilder.Executor) error {
+		query := func() *querybuilder.QueryBuilder {
+			return (&querybuilder.QueryBuilder{DB: tx, Hooks: m.Query.Hooks}).WithContext(ctx)
+		}
+		return insertAsset(query, asset)
+	})
+}
+
+func insertAsset(query func() *querybuilder.QueryBuilder, asset *Asset) error {
 	values := querybuilder.Clauses{
 		querybuilder.Clause{ColumnName: "url", Value: asset.URL},
 		querybuilder.Clause{ColumnName: "secureUrl", Value: asset.SecureURL},
@@ -40,30 +74,54 @@ func (m AssetModel) Insert(asset *Asset) error {
 		querybuilder.Clause{ColumnName: "bytes", Value: asset.Bytes},
 		querybuilder.Clause{ColumnName: "width", Value: asset.Width},
 		querybuilder.Clause{ColumnName: "height", Value: asset.Height},
+		querybuilder.Clause{ColumnName: "contentHash", Value: nullableString(asset.ContentHash)},
 	}
 
-	row, err := m.Query.SetBaseTable("assets").Insert(values).Returning(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-	).QueryRow()
+	returned := []string{"id", "createdAt", "updatedAt", "deletedAt"}
+
+	row, err := query().SetBaseTable("assets").Insert(values).Returning(returned...).QueryRow()
 	if err != nil {
 		return err
 	}
 
-	var deletedAt sql.NullTime
+	if err := querybuilder.ScanStruct(row, asset, returned...); err != nil {
+		return err
+	}
+
+	if len(asset.Variants) == 0 {
+		return nil
+	}
+
+	rows := make([]querybuilder.Clauses, len(asset.Variants))
+	for i := range asset.Variants {
+		variant := &asset.Variants[i]
+		variant.AssetID = asset.ID
+
+		rows[i] = querybuilder.Clauses{
+			querybuilder.Clause{ColumnName: "assetId", Value: variant.AssetID},
+			querybuilder.Clause{ColumnName: "name", Value: variant.Name},
+			querybuilder.Clause{ColumnName: "format", Value: variant.Format},
+			querybuilder.Clause{ColumnName: "url", Value: variant.URL},
+			querybuilder.Clause{ColumnName: "width", Value: variant.Width},
+			querybuilder.Clause{ColumnName: "height", Value: variant.Height},
+			querybuilder.Clause{ColumnName: "bytes", Value: variant.Bytes},
+		}
+	}
 
-	err = row.Scan(&asset.ID, &asset.CreatedAt, &asset.UpdatedAt, &deletedAt)
+	// Postgres returns the ids in the order the rows were given.
+	variantRows, err := query().SetBaseTable("asset_variants").Insert(rows...).Returning("id").Query()
 	if err != nil {
 		return err
 	}
+	defer variantRows.Close()
 
-	if deletedAt.Valid {
-		asset.DeletedAt = &deletedAt.Time
+	for i := 0; i < len(asset.Variants) && variantRows.Next(); i++ {
+		if err := querybuilder.ScanStruct(variantRows, &asset.Variants[i], "id"); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return variantRows.Err()
 }
 
 func (m AssetModel) Get(id int64) (*Asset, error) {
@@ -71,41 +129,31 @@ func (m AssetModel) Get(id int64) (*Asset, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := m.Query.SetBaseTable("assets").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"url",
-		"secureUrl",
-		"publicId",
-		"format",
-		"resourceType",
-		"bytes",
-		"width",
-		"height",
-	).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
+	row, err := m.Query.SetBaseTable("assets").Select(assetColumns...).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var asset Asset
-	var deletedAt sql.NullTime
-
-	err = row.Scan(
-		&asset.ID,
-		&asset.CreatedAt,
-		&asset.UpdatedAt,
-		&deletedAt,
-		&asset.URL,
-		&asset.SecureURL,
-		&asset.PublicID,
-		&asset.Format,
-		&asset.ResourceType,
-		&asset.Bytes,
-		&asset.Width,
-		&asset.Height,
-	)
+	return m.scanWithVariants(row)
+}
+
+// GetByContentHash returns the live asset whose uploaded bytes hash to the supplied value. The
+// unique index on contentHash only covers live assets, so deleted ones can be uploaded again.
+func (m AssetModel) GetByContentHash(hash string) (*Asset, error) {
+	if hash == "" {
+		return nil, errors.New("record not found")
+	}
+
+	row, err := m.Query.SetBaseTable("assets").Select(assetColumns...).WhereEqual("deletedAt", nil).WhereEqual("contentHash", hash).QueryRow()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.scanWithVariants(row)
+}
+
+func (m AssetModel) scanWithVariants(row querybuilder.Scanner) (*Asset, error) {
+	asset, err := scanAsset(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("record not found")
@@ -113,9 +161,178 @@ func (m AssetModel) Get(id int64) (*Asset, error) {
 		return nil, err
 	}
 
-	if deletedAt.Valid {
-		asset.DeletedAt = &deletedAt.Time
+	variants, err := m.getVariants([]int64{asset.ID})
+	if err != nil {
+		return nil, err
+	}
+	asset.Variants = variants[asset.ID]
+
+	return asset, nil
+}
+
+// GetByURLs returns the assets whose url or secureUrl matches one of the supplied URLs, along
+// with their variants. It lets content that only stores a URL, such as project images and
+// images embedded in note bodies, be enriched with responsive image metadata.
+func (m AssetModel) GetByURLs(urls []string) ([]*Asset, error) {
+	if len(urls) == 0 {
+		return []*Asset{}, nil
+	}
+
+	rows, err := m.Query.SetBaseTable("assets").Select(assetColumns...).
+		WhereEqual("deletedAt", nil).
+		Where(querybuilder.Or(querybuilder.In("secureUrl", urls), querybuilder.In("url", urls))).
+		Query()
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := querybuilder.ScanAll[Asset](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(assets))
+	for i, asset := range assets {
+		ids[i] = asset.ID
+	}
+
+	variants, err := m.getVariants(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, asset := range assets {
+		asset.Variants = variants[asset.ID]
+	}
+
+	return assets, nil
+}
+
+func (m AssetModel) getVariants(assetIDs []int64) (map[int64][]AssetVariant, error) {
+	variants := make(map[int64][]AssetVariant)
+	if len(assetIDs) == 0 {
+		return variants, nil
+	}
+
+	rows, err := m.Query.SetBaseTable("asset_variants").Select(assetVariantColumns...).
+		WhereIn("assetId", assetIDs).
+		OrderBy("assetId", "asc").
+		OrderBy("width", "asc").
+		OrderBy("id", "asc").
+		Query()
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := querybuilder.ScanAll[AssetVariant](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range all {
+		variants[variant.AssetID] = append(variants[variant.AssetID], *variant)
+	}
+
+	return variants, nil
+}
+
+// Ways in which the assets of an AssetDuplicateGroup were matched.
+const (
+	DuplicateMatchContentHash = "contentHash"
+	DuplicateMatchMetadata    = "metadata"
+)
+
+// AssetDuplicateGroup collects assets that are likely to be copies of the same file. Match says
+// whether the assets share a content hash or, lacking one, only their size, format and dimensions.
+type AssetDuplicateGroup struct {
+	Match       string   `json:"match"`
+	ContentHash string   `json:"contentHash,omitempty"`
+	Bytes       int      `json:"bytes"`
+	Format      string   `json:"format"`
+	Width       int      `json:"width"`
+	Height      int      `json:"height"`
+	Assets      []*Asset `json:"assets"`
+}
+
+// GetDuplicateGroups reports assets that share a content hash. Assets created before content
+// hashes were recorded cannot be compared byte for byte, so those are grouped by matching size,
+// format and dimensions instead, which is the best available signal. The oldest asset in each
+// group is listed first.
+func (m AssetModel) GetDuplicateGroups() ([]*AssetDuplicateGroup, error) {
+	query := `
+        SELECT ` + strings.Join(assetColumns, ", ") + `
+        FROM assets
+        WHERE deletedAt IS NULL AND (
+            contentHash IN (
+                SELECT contentHash
+                FROM assets
+                WHERE deletedAt IS NULL AND contentHash IS NOT NULL
+                GROUP BY contentHash
+                HAVING COUNT(*) > 1
+            )
+            OR contentHash IS NULL AND (bytes, format, width, height) IN (
+                SELECT bytes, format, width, height
+                FROM assets
+                WHERE deletedAt IS NULL AND contentHash IS NULL
+                GROUP BY bytes, format, width, height
+                HAVING COUNT(*) > 1
+            )
+        )
+        ORDER BY bytes DESC, format, width, height, contentHash NULLS LAST, id
+    `
+
+	rows, err := m.Query.Executor().QueryContext(m.Query.Context(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*AssetDuplicateGroup{}
+	var current *AssetDuplicateGroup
+
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.ContentHash != asset.ContentHash || current.Bytes != asset.Bytes || current.Format != asset.Format || current.Width != asset.Width || current.Height != asset.Height {
+			current = &AssetDuplicateGroup{
+				Match:       DuplicateMatchMetadata,
+				ContentHash: asset.ContentHash,
+				Bytes:       asset.Bytes,
+				Format:      asset.Format,
+				Width:       asset.Width,
+				Height:      asset.Height,
+			}
+			if asset.ContentHash != "" {
+				current.Match = DuplicateMatchContentHash
+			}
+			groups = append(groups, current)
+		}
+
+		current.Assets = append(current.Assets, asset)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func scanAsset(row querybuilder.Scanner) (*Asset, error) {
+	var asset Asset
+	if err := querybuilder.ScanStruct(row, &asset); err != nil {
+		return nil, err
 	}
 
 	return &asset, nil
 }
+
+func nullableString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAssetModel_GetDuplicateGroups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	m := AssetModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}
+
+	columns := []string{"id", "createdAt", "updatedAt", "deletedAt", "url", "secureUrl", "publicId", "format", "resourceType", "bytes", "width", "height", "contentHash"}
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT id, createdAt, updatedAt, deletedAt, url, secureUrl, publicId, format, resourceType, bytes, width, height, contentHash FROM assets WHERE deletedAt IS NULL AND \( contentHash IN .+ OR contentHash IS NULL AND \(bytes, format, width, height\) IN`).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(4, now, now, nil, "http://b", "https://b", "b", "png", "image", 2048, 10, 10, "abc").
+			AddRow(6, now, now, nil, "http://e", "https://e", "e", "png", "image", 2048, 10, 10, "abc").
+			AddRow(1, now, now, nil, "http://a", "https://a", "a", "png", "image", 2048, 10, 10, nil).
+			AddRow(5, now, now, nil, "http://f", "https://f", "f", "png", "image", 2048, 10, 10, nil).
+			AddRow(2, now, now, nil, "http://c", "https://c", "c", "pdf", "raw", 512, 0, 0, nil).
+			AddRow(3, now, now, nil, "http://d", "https://d", "d", "pdf", "raw", 512, 0, 0, nil))
+
+	groups, err := m.GetDuplicateGroups()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+
+	if groups[0].Match != DuplicateMatchContentHash || groups[0].ContentHash != "abc" || len(groups[0].Assets) != 2 || groups[0].Assets[1].ID != 6 {
+		t.Fatalf("unexpected first group %+v", groups[0])
+	}
+
+	if groups[1].Match != DuplicateMatchMetadata || groups[1].Format != "png" || groups[1].Assets[0].ID != 1 || groups[1].Assets[1].ID != 5 {
+		t.Fatalf("unexpected second group %+v", groups[1])
+	}
+
+	if groups[2].Match != DuplicateMatchMetadata || groups[2].Format != "pdf" || groups[2].Assets[0].ID != 2 || groups[2].Assets[1].ID != 3 {
+		t.Fatalf("unexpected third group %+v", groups[2])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestAssetModel_InsertWritesVariantsInOneStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	m := AssetModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}
+
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO assets \(.*\) VALUES \(.*\) RETURNING id, createdAt, updatedAt, deletedAt`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt"}).AddRow(9, now, now, nil))
+	mock.ExpectQuery(`INSERT INTO asset_variants \(assetId, name, format, url, width, height, bytes\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\), \(\$8, \$9, \$10, \$11, \$12, \$13, \$14\) RETURNING id`).
+		WithArgs(int64(9), "thumb", "webp", "https://a_thumb", 100, 100, 10, int64(9), "large", "webp", "https://a_large", 1200, 800, 90).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21).AddRow(22))
+	mock.ExpectCommit()
+
+	asset := &Asset{
+		URL:    "http://a",
+		Format: "png",
+		Variants: []AssetVariant{
+			{Name: "thumb", Format: "webp", URL: "https://a_thumb", Width: 100, Height: 100, Bytes: 10},
+			{Name: "large", Format: "webp", URL: "https://a_large", Width: 1200, Height: 800, Bytes: 90},
+		},
+	}
+
+	if err := m.Insert(asset); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if asset.ID != 9 || asset.Variants[0].ID != 21 || asset.Variants[1].ID != 22 || asset.Variants[1].AssetID != 9 {
+		t.Fatalf("unexpected asset %+v", asset)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestAssetModel_InsertRollsBackWhenVariantsFail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	m := AssetModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}
+
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO assets`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt"}).AddRow(9, now, now, nil))
+	mock.ExpectQuery(`INSERT INTO asset_variants`).WillReturnError(errors.New("variant insert failed"))
+	mock.ExpectRollback()
+
+	asset := &Asset{
+		URL:      "http://a",
+		Format:   "png",
+		Variants: []AssetVariant{{Name: "thumb", Format: "webp", URL: "https://a_thumb"}},
+	}
+
+	if err := m.Insert(asset); err == nil {
+		t.Fatal("expected the variant failure to be returned")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+)
+
+// AuditEvent records one admin mutation. Before and After hold the resource as it stood either
+// side of the change; Before is empty for creations and After for deletions.
+type AuditEvent struct {
+	ID         int64           `json:"id" db:"id"`
+	OccurredAt time.Time       `json:"occurredAt" db:"occurredAt"`
+	ActorType  string          `json:"actorType" db:"actorType"`
+	Actor      string          `json:"actor" db:"actor"`
+	RequestID  string          `json:"requestId" db:"requestId"`
+	Resource   string          `json:"resource" db:"resource"`
+	ResourceID int64           `json:"resourceId" db:"resourceId"`
+	Action     string          `json:"action" db:"action"`
+	Before     json.RawMessage `json:"before,omitempty" db:"before"`
+	After      json.RawMessage `json:"after,omitempty" db:"after"`
+}
+
+type AuditEventFilters struct {
+	CursorFilters
+	Resource   string
+	ResourceID int64
+	Actor      string
+	From       time.Time
+	To         time.Time
+}
+
+// AuditEventModel only appends and reads; audit events are never changed once written.
+type AuditEventModel struct {
+	DB     querybuilder.Executor
+	Query  *querybuilder.QueryBuilder
+	Logger *log.Logger
+}
+
+var auditEventColumns = querybuilder.Columns(AuditEvent{})
+
+func (m AuditEventModel) Insert(event *AuditEvent) error {
+	values := querybuilder.Clauses{
+		querybuilder.Clause{ColumnName: "actorType", Value: event.ActorType},
+		querybuilder.Clause{ColumnName: "actor", Value: event.Actor},
+		querybuilder.Clause{ColumnName: "requestId", Value: event.RequestID},
+		querybuilder.Clause{ColumnName: "resource", Value: event.Resource},
+		querybuilder.Clause{ColumnName: "resourceId", Value: event.ResourceID},
+		querybuilder.Clause{ColumnName: "action", Value: event.Action},
+		querybuilder.Clause{ColumnName: "before", Value: nullableJSON(event.Before)},
+		querybuilder.Clause{ColumnName: "after", Value: nullableJSON(event.After)},
+	}
+
+	row, err := m.Query.SetBaseTable("audit_events").Insert(values).Returning("id", "occurredAt").QueryRow()
+	if err != nil {
+		return err
+	}
+
+	return querybuilder.ScanStruct(row, event, "id", "occurredAt")
+}
+
+// GetAll returns matching events newest first. From is inclusive and To exclusive.
+func (m AuditEventModel) GetAll(filters AuditEventFilters) ([]*AuditEvent, Metadata, error) {
+	query := m.Query.SetBaseTable("audit_events").Select(auditEventColumns...)
+
+	if filters.Resource != "" {
+		query.WhereEqual("resource", filters.Resource)
+	}
+
+	if filters.ResourceID > 0 {
+		query.WhereEqual("resourceId", filters.ResourceID)
+	}
+
+	if filters.Actor != "" {
+		query.WhereEqual("actor", filters.Actor)
+	}
+
+	if !filters.From.IsZero() {
+		query.WhereGreaterThanEqual("occurredAt", filters.From)
+	}
+
+	if !filters.To.IsZero() {
+		query.WhereLessThan("occurredAt", filters.To)
+	}
+
+	if filters.Cursor != "" {
+		query.WhereLessThan("id", filters.Cursor)
+	}
+
+	rows, err := query.Limit(filters.Limit).OrderBy("id", "desc").Query()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	events, err := querybuilder.ScanAll[AuditEvent](rows)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var metadata Metadata
+	if len(events) > 0 && len(events) >= filters.Limit {
+		metadata.NextCursor = strconv.FormatInt(events[len(events)-1].ID, 10)
+	}
+
+	return events, metadata, nil
+}
+
+func nullableJSON(value json.RawMessage) any {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
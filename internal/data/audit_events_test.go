@@ -0,0 +1,93 @@
+package data
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newAuditEventModel(t *testing.T) (AuditEventModel, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return AuditEventModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}, mock
+}
+
+func TestAuditEventModel_InsertStoresMissingSnapshotsAsNull(t *testing.T) {
+	m, mock := newAuditEventModel(t)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO audit_events \(actorType, actor, requestId, resource, resourceId, action, before, after\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\) RETURNING id, occurredAt`).
+		WithArgs("admin", "admin@example.com", "req-1", "role", int64(7), "created", nil, `{"title":"Engineer"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "occurredAt"}).AddRow(11, now))
+
+	event := &AuditEvent{
+		ActorType:  "admin",
+		Actor:      "admin@example.com",
+		RequestID:  "req-1",
+		Resource:   "role",
+		ResourceID: 7,
+		Action:     "created",
+		After:      json.RawMessage(`{"title":"Engineer"}`),
+	}
+	if err := m.Insert(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if event.ID != 11 || !event.OccurredAt.Equal(now) {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestAuditEventModel_GetAllAppliesFilters(t *testing.T) {
+	m, mock := newAuditEventModel(t)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	mock.ExpectQuery(`SELECT id, occurredAt, actorType, actor, requestId, resource, resourceId, action, before, after FROM audit_events WHERE resource = \$1 AND actor = \$2 AND occurredAt >= \$3 AND occurredAt < \$4 ORDER BY id desc LIMIT 2`).
+		WithArgs("role", "admin@example.com", from, to).
+		WillReturnRows(sqlmock.NewRows(auditEventColumns).
+			AddRow(12, from, "admin", "admin@example.com", "req-2", "role", 7, "deleted", []byte(`{"title":"Engineer"}`), nil).
+			AddRow(11, from, "admin", "admin@example.com", "req-1", "role", 7, "created", nil, []byte(`{"title":"Engineer"}`)))
+
+	events, metadata, err := m.GetAll(AuditEventFilters{
+		CursorFilters: CursorFilters{Limit: 2},
+		Resource:      "role",
+		Actor:         "admin@example.com",
+		From:          from,
+		To:            to,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(events) != 2 || events[0].After != nil || string(events[1].After) != `{"title":"Engineer"}` {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if metadata.NextCursor != "11" {
+		t.Fatalf("expected the next cursor to be 11, got %q", metadata.NextCursor)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
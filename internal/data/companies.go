@@ -10,17 +10,19 @@ import (
 )
 
 type Company struct {
-	ID          int64      `json:"id"`
-	CreatedAt   time.Time  `json:"-"`
-	UpdatedAt   time.Time  `json:"-"`
-	DeletedAt   *time.Time `json:"-"`
-	Name        string     `json:"name"`
-	Icon        *string    `json:"icon,omitempty"`
-	Description *string    `json:"description,omitempty"`
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"-" db:"createdAt"`
+	UpdatedAt   time.Time  `json:"-" db:"updatedAt"`
+	DeletedAt   *time.Time `json:"-" db:"deletedAt"`
+	Name        string     `json:"name" db:"name"`
+	Icon        *string    `json:"icon,omitempty" db:"icon"`
+	Description *string    `json:"description,omitempty" db:"description"`
 }
 
+var companyColumns = querybuilder.Columns(Company{})
+
 type CompanyModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -41,28 +43,14 @@ func (c CompanyModel) Insert(company *Company) error {
 		querybuilder.Clause{ColumnName: "description", Value: description},
 	}
 
-	row, err := c.Query.SetBaseTable("companies").Insert(values).Returning(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-	).QueryRow()
-	if err != nil {
-		return err
-	}
-
-	var deletedAt sql.NullTime
+	returned := []string{"id", "createdAt", "updatedAt", "deletedAt"}
 
-	err = row.Scan(&company.ID, &company.CreatedAt, &company.UpdatedAt, &deletedAt)
+	row, err := c.Query.SetBaseTable("companies").Insert(values).Returning(returned...).QueryRow()
 	if err != nil {
 		return err
 	}
 
-	if deletedAt.Valid {
-		company.DeletedAt = &deletedAt.Time
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, company, returned...)
 }
 
 func (c CompanyModel) Get(id int64) (*Company, error) {
@@ -70,52 +58,19 @@ func (c CompanyModel) Get(id int64) (*Company, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := c.Query.SetBaseTable("companies").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"name",
-		"icon",
-		"description",
-	).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
+	row, err := c.Query.SetBaseTable("companies").Select(companyColumns...).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
 	var company Company
-	var deletedAt sql.NullTime
-	var icon sql.NullString
-	var description sql.NullString
-
-	err = row.Scan(
-		&company.ID,
-		&company.CreatedAt,
-		&company.UpdatedAt,
-		&deletedAt,
-		&company.Name,
-		&icon,
-		&description,
-	)
-	if err != nil {
+	if err := querybuilder.ScanStruct(row, &company); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("record not found")
 		}
 		return nil, err
 	}
 
-	if deletedAt.Valid {
-		company.DeletedAt = &deletedAt.Time
-	}
-
-	if icon.Valid {
-		company.Icon = &icon.String
-	}
-
-	if description.Valid {
-		company.Description = &description.String
-	}
-
 	return &company, nil
 }
 
@@ -137,65 +92,14 @@ func (c CompanyModel) Update(company *Company) error {
 	}
 
 	row, err := c.Query.With(
-		c.Query.SetBaseTable("companies").Update(values).WhereEqual("id", company.ID).WhereEqual("deletedAt", nil).Returning(
-			"id",
-			"createdAt",
-			"updatedAt",
-			"deletedAt",
-			"name",
-			"icon",
-			"description",
-		),
+		c.Query.SetBaseTable("companies").Update(values).WhereEqual("id", company.ID).WhereEqual("deletedAt", nil).Returning(companyColumns...),
 		"updated_company",
-	).Select(
-		"updated_company.id",
-		"updated_company.createdAt",
-		"updated_company.updatedAt",
-		"updated_company.deletedAt",
-		"updated_company.name",
-		"updated_company.icon",
-		"updated_company.description",
-	).From("updated_company").QueryRow()
+	).Select(querybuilder.QualifiedColumns("updated_company", Company{})...).From("updated_company").QueryRow()
 	if err != nil {
 		return err
 	}
 
-	var deletedAt sql.NullTime
-	var iconVal sql.NullString
-	var descriptionVal sql.NullString
-
-	err = row.Scan(
-		&company.ID,
-		&company.CreatedAt,
-		&company.UpdatedAt,
-		&deletedAt,
-		&company.Name,
-		&iconVal,
-		&descriptionVal,
-	)
-	if err != nil {
-		return err
-	}
-
-	if deletedAt.Valid {
-		company.DeletedAt = &deletedAt.Time
-	} else {
-		company.DeletedAt = nil
-	}
-
-	if iconVal.Valid {
-		company.Icon = &iconVal.String
-	} else {
-		company.Icon = nil
-	}
-
-	if descriptionVal.Valid {
-		company.Description = &descriptionVal.String
-	} else {
-		company.Description = nil
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, company)
 }
 
 func (c CompanyModel) Delete(company *Company) error {
@@ -226,59 +130,10 @@ func (c CompanyModel) Delete(company *Company) error {
 }
 
 func (c CompanyModel) GetAll() ([]*Company, error) {
-	rows, err := c.Query.SetBaseTable("companies").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"name",
-		"icon",
-		"description",
-	).WhereEqual("deletedAt", nil).OrderBy("createdAt", "desc").Query()
+	rows, err := c.Query.SetBaseTable("companies").Select(companyColumns...).WhereEqual("deletedAt", nil).OrderBy("createdAt", "desc").Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	companies := []*Company{}
-
-	for rows.Next() {
-		var company Company
-		var deletedAt sql.NullTime
-		var icon sql.NullString
-		var description sql.NullString
-
-		err := rows.Scan(
-			&company.ID,
-			&company.CreatedAt,
-			&company.UpdatedAt,
-			&deletedAt,
-			&company.Name,
-			&icon,
-			&description,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			company.DeletedAt = &deletedAt.Time
-		}
-
-		if icon.Valid {
-			company.Icon = &icon.String
-		}
-
-		if description.Valid {
-			company.Description = &description.String
-		}
-
-		companies = append(companies, &company)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
 
-	return companies, nil
+	return querybuilder.ScanAll[Company](rows)
 }
@@ -7,18 +7,19 @@ import (
 	"time"
 
 	"api.etin.dev/pkg/querybuilder"
-	"github.com/lib/pq"
 )
 
 type ItemNote struct {
-	ID       int64  `json:"id"`
-	NoteID   int64  `json:"noteId"`
-	ItemID   int64  `json:"itemId"`
-	ItemType string `json:"itemType"`
+	ID       int64  `json:"id" db:"id"`
+	NoteID   int64  `json:"noteId" db:"noteId"`
+	ItemID   int64  `json:"itemId" db:"itemId"`
+	ItemType string `json:"itemType" db:"itemType"`
 }
 
+var itemNoteColumns = querybuilder.Columns(ItemNote{})
+
 type ItemNoteModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -35,12 +36,7 @@ func (i ItemNoteModel) Insert(itemNote *ItemNote) error {
 		return err
 	}
 
-	err = row.Scan(&itemNote.ID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, itemNote, "id")
 }
 
 func (i ItemNoteModel) Get(id int64) (*ItemNote, error) {
@@ -48,25 +44,13 @@ func (i ItemNoteModel) Get(id int64) (*ItemNote, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := i.Query.SetBaseTable("item_notes").Select(
-		"id",
-		"noteId",
-		"itemId",
-		"itemType",
-	).WhereEqual("id", id).QueryRow()
+	row, err := i.Query.SetBaseTable("item_notes").Select(itemNoteColumns...).WhereEqual("id", id).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
 	var itemNote ItemNote
-
-	err = row.Scan(
-		&itemNote.ID,
-		&itemNote.NoteID,
-		&itemNote.ItemID,
-		&itemNote.ItemType,
-	)
-	if err != nil {
+	if err := querybuilder.ScanStruct(row, &itemNote); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("record not found")
 		}
@@ -84,29 +68,14 @@ func (i ItemNoteModel) Update(itemNote *ItemNote) error {
 	}
 
 	row, err := i.Query.With(
-		i.Query.SetBaseTable("item_notes").Update(values).WhereEqual("id", itemNote.ID).Returning("id", "noteId", "itemId", "itemType"),
+		i.Query.SetBaseTable("item_notes").Update(values).WhereEqual("id", itemNote.ID).Returning(itemNoteColumns...),
 		"updated_item_note",
-	).Select(
-		"updated_item_note.id",
-		"updated_item_note.noteId",
-		"updated_item_note.itemId",
-		"updated_item_note.itemType",
-	).From("updated_item_note").QueryRow()
-	if err != nil {
-		return err
-	}
-
-	err = row.Scan(
-		&itemNote.ID,
-		&itemNote.NoteID,
-		&itemNote.ItemID,
-		&itemNote.ItemType,
-	)
+	).Select(querybuilder.QualifiedColumns("updated_item_note", ItemNote{})...).From("updated_item_note").QueryRow()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return querybuilder.ScanStruct(row, itemNote)
 }
 
 func (i ItemNoteModel) Delete(id int64) error {
@@ -132,40 +101,12 @@ func (i ItemNoteModel) Delete(id int64) error {
 }
 
 func (i ItemNoteModel) GetAll() ([]*ItemNote, error) {
-	rows, err := i.Query.SetBaseTable("item_notes").Select(
-		"id",
-		"noteId",
-		"itemId",
-		"itemType",
-	).OrderBy("id", "desc").Query()
+	rows, err := i.Query.SetBaseTable("item_notes").Select(itemNoteColumns...).OrderBy("id", "desc").Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	itemNotes := []*ItemNote{}
-
-	for rows.Next() {
-		var itemNote ItemNote
-
-		err := rows.Scan(
-			&itemNote.ID,
-			&itemNote.NoteID,
-			&itemNote.ItemID,
-			&itemNote.ItemType,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		itemNotes = append(itemNotes, &itemNote)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return itemNotes, nil
+	return querybuilder.ScanAll[ItemNote](rows)
 }
 
 func (i ItemNoteModel) GetByNoteIDs(noteIDs []int64) ([]*ItemNote, error) {
@@ -173,53 +114,18 @@ func (i ItemNoteModel) GetByNoteIDs(noteIDs []int64) ([]*ItemNote, error) {
 		return []*ItemNote{}, nil
 	}
 
-	query := `
-        SELECT id, noteId, itemId, itemType
-        FROM item_notes
-        WHERE noteId = ANY($1)
-    `
-
-	rows, err := i.DB.Query(query, pq.Array(noteIDs))
+	rows, err := i.Query.SetBaseTable("item_notes").Select(itemNoteColumns...).WhereIn("noteId", noteIDs).Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	itemNotes := []*ItemNote{}
-
-	for rows.Next() {
-		var itemNote ItemNote
-		err := rows.Scan(
-			&itemNote.ID,
-			&itemNote.NoteID,
-			&itemNote.ItemID,
-			&itemNote.ItemType,
-		)
-		if err != nil {
-			return nil, err
-		}
-		itemNotes = append(itemNotes, &itemNote)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
 
-	return itemNotes, nil
+	return querybuilder.ScanAll[ItemNote](rows)
 }
 
 func (i ItemNoteModel) GetNotesForItem(itemType string, itemID int64, filters CursorFilters) ([]*Note, Metadata, error) {
-	query := i.Query.SetBaseTable("item_notes").Select(
-		"notes.id",
-		"notes.createdAt",
-		"notes.updatedAt",
-		"notes.deletedAt",
-		"notes.publishedAt",
-		"notes.title",
-		"notes.subtitle",
-		"notes.slug",
-		"notes.body",
-	).LeftJoin("notes", "noteId", "id").WhereEqual("itemType", itemType).WhereEqual("itemId", itemID).WhereEqual("notes.deletedAt", nil)
+	query := i.Query.SetBaseTable("item_notes").Select(querybuilder.QualifiedColumns("notes", Note{})...).
+		LeftJoin("notes", "noteId", "id").
+		WhereEqual("itemType", itemType).WhereEqual("itemId", itemID).WhereEqual("notes.deletedAt", nil)
 
 	if filters.Cursor != "" {
 		query.WhereLessThan("notes.id", filters.Cursor)
@@ -230,52 +136,12 @@ func (i ItemNoteModel) GetNotesForItem(itemType string, itemID int64, filters Cu
 	}
 
 	rows, err := query.Limit(filters.Limit).OrderBy("notes.id", "desc").Query()
-
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 
-	defer rows.Close()
-
-	notes := []*Note{}
-
-	for rows.Next() {
-		var note Note
-		var deletedAt sql.NullTime
-		var publishedAt sql.NullTime
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&note.ID,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&deletedAt,
-			&publishedAt,
-			&note.Title,
-			&note.Subtitle,
-			&slug,
-			&note.Body,
-		)
-		if err != nil {
-			return nil, Metadata{}, err
-		}
-
-		if deletedAt.Valid {
-			note.DeletedAt = &deletedAt.Time
-		}
-
-		if publishedAt.Valid {
-			note.PublishedAt = &publishedAt.Time
-		}
-
-		if slug.Valid {
-			note.Slug = slug.String
-		}
-
-		notes = append(notes, &note)
-	}
-
-	if err = rows.Err(); err != nil {
+	notes, err := querybuilder.ScanAll[Note](rows)
+	if err != nil {
 		return nil, Metadata{}, err
 	}
 
@@ -285,17 +151,9 @@ func (i ItemNoteModel) GetNotesForItem(itemType string, itemID int64, filters Cu
 }
 
 func (i ItemNoteModel) GetNotesForContentType(contentType string, filters CursorFilters) ([]*Note, Metadata, error) {
-	query := i.Query.SetBaseTable("item_notes").Select(
-		"notes.id",
-		"notes.createdAt",
-		"notes.updatedAt",
-		"notes.deletedAt",
-		"notes.publishedAt",
-		"notes.title",
-		"notes.subtitle",
-		"notes.slug",
-		"notes.body",
-	).LeftJoin("notes", "noteId", "id").WhereEqual("itemType", contentType).WhereEqual("notes.deletedAt", nil)
+	query := i.Query.SetBaseTable("item_notes").Select(querybuilder.QualifiedColumns("notes", Note{})...).
+		LeftJoin("notes", "noteId", "id").
+		WhereEqual("itemType", contentType).WhereEqual("notes.deletedAt", nil)
 
 	if filters.Cursor != "" {
 		query.WhereLessThan("notes.id", filters.Cursor)
@@ -306,52 +164,12 @@ func (i ItemNoteModel) GetNotesForContentType(contentType string, filters Cursor
 	}
 
 	rows, err := query.Limit(filters.Limit).OrderBy("notes.id", "desc").Query()
-
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 
-	defer rows.Close()
-
-	notes := []*Note{}
-
-	for rows.Next() {
-		var note Note
-		var deletedAt sql.NullTime
-		var publishedAt sql.NullTime
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&note.ID,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&deletedAt,
-			&publishedAt,
-			&note.Title,
-			&note.Subtitle,
-			&slug,
-			&note.Body,
-		)
-		if err != nil {
-			return nil, Metadata{}, err
-		}
-
-		if deletedAt.Valid {
-			note.DeletedAt = &deletedAt.Time
-		}
-
-		if publishedAt.Valid {
-			note.PublishedAt = &publishedAt.Time
-		}
-
-		if slug.Valid {
-			note.Slug = slug.String
-		}
-
-		notes = append(notes, &note)
-	}
-
-	if err = rows.Err(); err != nil {
+	notes, err := querybuilder.ScanAll[Note](rows)
+	if err != nil {
 		return nil, Metadata{}, err
 	}
 
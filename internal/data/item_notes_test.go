@@ -24,7 +24,7 @@ func TestItemNoteModel_GetNotesForItem(t *testing.T) {
 	}
 
 	// Case 1: No published filtering (default)
-	mock.ExpectQuery(`SELECT notes.id, notes.createdAt, notes.updatedAt, notes.deletedAt, notes.publishedAt, notes.title, notes.subtitle, notes.slug, notes.body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND itemId = \$2 AND notes.deletedAt IS NULL ORDER BY notes.id desc LIMIT 20`).
+	mock.ExpectQuery(`SELECT notes.id AS id, notes.createdAt AS createdAt, notes.updatedAt AS updatedAt, notes.deletedAt AS deletedAt, notes.publishedAt AS publishedAt, notes.title AS title, notes.subtitle AS subtitle, notes.slug AS slug, notes.body AS body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND itemId = \$2 AND notes.deletedAt IS NULL ORDER BY notes.id desc LIMIT 20`).
 		WithArgs("projects", 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"}))
 
@@ -35,7 +35,7 @@ func TestItemNoteModel_GetNotesForItem(t *testing.T) {
 	}
 
 	// Case 2: With published filtering
-	mock.ExpectQuery(`SELECT notes.id, notes.createdAt, notes.updatedAt, notes.deletedAt, notes.publishedAt, notes.title, notes.subtitle, notes.slug, notes.body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND itemId = \$2 AND notes.deletedAt IS NULL AND notes.publishedAt <= \$3 ORDER BY notes.id desc LIMIT 20`).
+	mock.ExpectQuery(`SELECT notes.id AS id, notes.createdAt AS createdAt, notes.updatedAt AS updatedAt, notes.deletedAt AS deletedAt, notes.publishedAt AS publishedAt, notes.title AS title, notes.subtitle AS subtitle, notes.slug AS slug, notes.body AS body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND itemId = \$2 AND notes.deletedAt IS NULL AND notes.publishedAt <= \$3 ORDER BY notes.id desc LIMIT 20`).
 		WithArgs("projects", 1, sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"}))
 
@@ -65,7 +65,7 @@ func TestItemNoteModel_GetNotesForContentType(t *testing.T) {
 	}
 
 	// Case 1: No published filtering (default)
-	mock.ExpectQuery(`SELECT notes.id, notes.createdAt, notes.updatedAt, notes.deletedAt, notes.publishedAt, notes.title, notes.subtitle, notes.slug, notes.body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND notes.deletedAt IS NULL ORDER BY notes.id desc LIMIT 20`).
+	mock.ExpectQuery(`SELECT notes.id AS id, notes.createdAt AS createdAt, notes.updatedAt AS updatedAt, notes.deletedAt AS deletedAt, notes.publishedAt AS publishedAt, notes.title AS title, notes.subtitle AS subtitle, notes.slug AS slug, notes.body AS body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND notes.deletedAt IS NULL ORDER BY notes.id desc LIMIT 20`).
 		WithArgs("projects").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"}))
 
@@ -76,7 +76,7 @@ func TestItemNoteModel_GetNotesForContentType(t *testing.T) {
 	}
 
 	// Case 2: With published filtering
-	mock.ExpectQuery(`SELECT notes.id, notes.createdAt, notes.updatedAt, notes.deletedAt, notes.publishedAt, notes.title, notes.subtitle, notes.slug, notes.body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND notes.deletedAt IS NULL AND notes.publishedAt <= \$2 ORDER BY notes.id desc LIMIT 20`).
+	mock.ExpectQuery(`SELECT notes.id AS id, notes.createdAt AS createdAt, notes.updatedAt AS updatedAt, notes.deletedAt AS deletedAt, notes.publishedAt AS publishedAt, notes.title AS title, notes.subtitle AS subtitle, notes.slug AS slug, notes.body AS body FROM item_notes LEFT JOIN notes ON item_notes.noteId = notes.id WHERE itemType = \$1 AND notes.deletedAt IS NULL AND notes.publishedAt <= \$2 ORDER BY notes.id desc LIMIT 20`).
 		WithArgs("projects", sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"}))
 
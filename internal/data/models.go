@@ -1,32 +1,141 @@
 package data
 
 import (
-	"database/sql"
+	"context"
 	"log"
 
 	"api.etin.dev/pkg/querybuilder"
 )
 
 type Models struct {
-	Roles     RoleModel
-	Companies CompanyModel
-	Notes     NoteModel
-	Projects  ProjectModel
-	Tags      TagModel
-	TagItems  TagItemModel
-	ItemNotes ItemNoteModel
-	Assets    AssetModel
-}
-
-func NewModels(db *sql.DB, logger *log.Logger) Models {
-	return Models{
-		Roles:     RoleModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		Companies: CompanyModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		Notes:     NoteModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		Projects:  ProjectModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		Tags:      TagModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		TagItems:  TagItemModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		ItemNotes: ItemNoteModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
-		Assets:    AssetModel{DB: db, Query: &querybuilder.QueryBuilder{DB: db}, Logger: logger},
+	Roles                RoleModel
+	Companies            CompanyModel
+	Notes                NoteModel
+	Projects             ProjectModel
+	Tags                 TagModel
+	TagItems             TagItemModel
+	ItemNotes            ItemNoteModel
+	Assets               AssetModel
+	AssetLinks           AssetLinkModel
+	WebhookDeliveries    WebhookDeliveryModel
+	WebhookSubscriptions WebhookSubscriptionModel
+	AuditEvents          AuditEventModel
+	Schema               SchemaModel
+
+	db      querybuilder.Executor
+	ctx     context.Context
+	replica *Replica
+	hooks   []querybuilder.Hook
+}
+
+// NewModels returns models backed by db, which is a *sql.DB or a querybuilder.StatementCache over
+// one. Every statement they run is reported to hooks.
+func NewModels(db querybuilder.Executor, logger *log.Logger, hooks ...querybuilder.Hook) Models {
+	models := Models{
+		Roles:                RoleModel{Logger: logger},
+		Companies:            CompanyModel{Logger: logger},
+		Notes:                NoteModel{Logger: logger},
+		Projects:             ProjectModel{Logger: logger},
+		Tags:                 TagModel{Logger: logger},
+		TagItems:             TagItemModel{Logger: logger},
+		ItemNotes:            ItemNoteModel{Logger: logger},
+		Assets:               AssetModel{Logger: logger},
+		AssetLinks:           AssetLinkModel{Logger: logger},
+		WebhookDeliveries:    WebhookDeliveryModel{Logger: logger},
+		WebhookSubscriptions: WebhookSubscriptionModel{Logger: logger},
+		AuditEvents:          AuditEventModel{Logger: logger},
+		Schema:               SchemaModel{Logger: logger},
+		hooks:                hooks,
+	}
+
+	return models.bind(db, context.Background())
+}
+
+// WithContext returns models whose queries run with ctx, so they stop when it is cancelled. When
+// ctx came from models inside RunInTx, the returned models join that transaction.
+func (m Models) WithContext(ctx context.Context) Models {
+	if tx, ok := ctx.Value(txKey{}).(querybuilder.Executor); ok {
+		return m.bind(tx, ctx)
+	}
+	return m.bind(m.db, ctx)
+}
+
+// Context returns the context the models' queries run with.
+func (m Models) Context() context.Context {
+	return m.ctx
+}
+
+// WithReplica returns models that send the queries of ReadOnly models to replica.
+func (m Models) WithReplica(replica *Replica) Models {
+	m.replica = replica
+	return m
+}
+
+// ReadOnly returns models whose queries run with ctx against the read replica. They fall back to
+// the primary when there is no replica, when it is unhealthy, or when ctx came from
+// ReadYourWrites. They must only be used to read, and may see data a little behind the primary.
+func (m Models) ReadOnly(ctx context.Context) Models {
+	if m.replica == nil || !m.replica.Healthy() || readsFromPrimary(ctx) {
+		return m.WithContext(ctx)
+	}
+
+	return m.bind(m.replica.db, ctx)
+}
+
+type readYourWritesKey struct{}
+
+// ReadYourWrites returns a context under which ReadOnly models read from the primary, for
+// callers that must see what they have just written.
+func ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+func readsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return primary
+}
+
+type txKey struct{}
+
+// RunInTx calls fn with models that share one transaction, committing it if fn returns nil and
+// rolling it back otherwise. Calling it on models already inside a transaction joins that one.
+// The context of the models fn receives carries the transaction, so that models bound to it
+// with WithContext join it too.
+func (m Models) RunInTx(ctx context.Context, fn func(tx Models) error) error {
+	return runInTx(ctx, m.db, func(tx querybuilder.Executor) error {
+		return fn(m.bind(tx, context.WithValue(ctx, txKey{}, tx)))
+	})
+}
+
+// Transact is RunInTx for callers that bind models to a context rather than passing them
+// around: models bound with WithContext to the context fn receives share one transaction.
+func (m Models) Transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.WithContext(ctx).RunInTx(ctx, func(tx Models) error {
+		return fn(tx.Context())
+	})
+}
+
+// bind points every model at db and ctx, keeping their loggers.
+func (m Models) bind(db querybuilder.Executor, ctx context.Context) Models {
+	query := func() *querybuilder.QueryBuilder {
+		return (&querybuilder.QueryBuilder{DB: db, Hooks: m.hooks}).WithContext(ctx)
 	}
+
+	m.db = db
+	m.ctx = ctx
+	m.Roles.DB, m.Roles.Query = db, query()
+	m.Companies.DB, m.Companies.Query = db, query()
+	m.Notes.DB, m.Notes.Query = db, query()
+	m.Projects.DB, m.Projects.Query = db, query()
+	m.Tags.DB, m.Tags.Query = db, query()
+	m.TagItems.DB, m.TagItems.Query = db, query()
+	m.ItemNotes.DB, m.ItemNotes.Query = db, query()
+	m.Assets.DB, m.Assets.Query = db, query()
+	m.AssetLinks.DB, m.AssetLinks.Query = db, query()
+	m.WebhookDeliveries.DB, m.WebhookDeliveries.Query = db, query()
+	m.WebhookSubscriptions.DB, m.WebhookSubscriptions.Query = db, query()
+	m.AuditEvents.DB, m.AuditEvents.Query = db, query()
+	m.Schema.DB, m.Schema.Query = db, query()
+
+	return m
 }
@@ -13,19 +13,21 @@ import (
 )
 
 type Note struct {
-	ID          int64      `json:"id"`
-	CreatedAt   time.Time  `json:"-"`
-	UpdatedAt   time.Time  `json:"-"`
-	DeletedAt   *time.Time `json:"-"`
-	PublishedAt *time.Time `json:"publishedAt,omitempty"`
-	Title       string     `json:"title"`
-	Subtitle    string     `json:"subtitle"`
-	Slug        string     `json:"slug"`
-	Body        string     `json:"body"`
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"-" db:"createdAt"`
+	UpdatedAt   time.Time  `json:"-" db:"updatedAt"`
+	DeletedAt   *time.Time `json:"-" db:"deletedAt"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty" db:"publishedAt"`
+	Title       string     `json:"title" db:"title"`
+	Subtitle    string     `json:"subtitle" db:"subtitle"`
+	Slug        string     `json:"slug" db:"slug"`
+	Body        string     `json:"body" db:"body"`
 }
 
+var noteColumns = querybuilder.Columns(Note{})
+
 type NoteModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -52,35 +54,14 @@ func (n NoteModel) Insert(note *Note) error {
 		querybuilder.Clause{ColumnName: "body", Value: note.Body},
 	}
 
-	row, err := n.Query.SetBaseTable("notes").Insert(values).Returning("id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "slug").QueryRow()
-	if err != nil {
-		return err
-	}
-
-	var deletedAt sql.NullTime
-	var published sql.NullTime
-	var slug sql.NullString
+	returned := []string{"id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "slug"}
 
-	err = row.Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &deletedAt, &published, &slug)
+	row, err := n.Query.SetBaseTable("notes").Insert(values).Returning(returned...).QueryRow()
 	if err != nil {
 		return err
 	}
 
-	if deletedAt.Valid {
-		note.DeletedAt = &deletedAt.Time
-	}
-
-	if published.Valid {
-		note.PublishedAt = &published.Time
-	} else {
-		note.PublishedAt = nil
-	}
-
-	if slug.Valid {
-		note.Slug = slug.String
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, note, returned...)
 }
 
 func (n NoteModel) Get(id int64) (*Note, error) {
@@ -88,57 +69,12 @@ func (n NoteModel) Get(id int64) (*Note, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := n.Query.SetBaseTable("notes").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"publishedAt",
-		"title",
-		"subtitle",
-		"slug",
-		"body",
-	).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
+	row, err := n.Query.SetBaseTable("notes").Select(noteColumns...).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var note Note
-	var deletedAt sql.NullTime
-	var publishedAt sql.NullTime
-	var slug sql.NullString
-
-	err = row.Scan(
-		&note.ID,
-		&note.CreatedAt,
-		&note.UpdatedAt,
-		&deletedAt,
-		&publishedAt,
-		&note.Title,
-		&note.Subtitle,
-		&slug,
-		&note.Body,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		note.DeletedAt = &deletedAt.Time
-	}
-
-	if publishedAt.Valid {
-		note.PublishedAt = &publishedAt.Time
-	}
-
-	if slug.Valid {
-		note.Slug = slug.String
-	}
-
-	return &note, nil
+	return scanNote(row)
 }
 
 func (n NoteModel) Update(note *Note) error {
@@ -165,59 +101,14 @@ func (n NoteModel) Update(note *Note) error {
 	}
 
 	row, err := n.Query.With(
-		n.Query.SetBaseTable("notes").Update(values).WhereEqual("id", note.ID).WhereEqual("deletedAt", nil).Returning("id", "createdAt", "updatedAt", "deletedAt", "publishedAt", "title", "subtitle", "slug", "body"),
+		n.Query.SetBaseTable("notes").Update(values).WhereEqual("id", note.ID).WhereEqual("deletedAt", nil).Returning(noteColumns...),
 		"updated_note",
-	).Select(
-		"updated_note.id",
-		"updated_note.createdAt",
-		"updated_note.updatedAt",
-		"updated_note.deletedAt",
-		"updated_note.publishedAt",
-		"updated_note.title",
-		"updated_note.subtitle",
-		"updated_note.slug",
-		"updated_note.body",
-	).From("updated_note").QueryRow()
-	if err != nil {
-		return err
-	}
-
-	var deletedAt sql.NullTime
-	var published sql.NullTime
-	var slug sql.NullString
-
-	err = row.Scan(
-		&note.ID,
-		&note.CreatedAt,
-		&note.UpdatedAt,
-		&deletedAt,
-		&published,
-		&note.Title,
-		&note.Subtitle,
-		&slug,
-		&note.Body,
-	)
+	).Select(querybuilder.QualifiedColumns("updated_note", Note{})...).From("updated_note").QueryRow()
 	if err != nil {
 		return err
 	}
 
-	if deletedAt.Valid {
-		note.DeletedAt = &deletedAt.Time
-	} else {
-		note.DeletedAt = nil
-	}
-
-	if published.Valid {
-		note.PublishedAt = &published.Time
-	} else {
-		note.PublishedAt = nil
-	}
-
-	if slug.Valid {
-		note.Slug = slug.String
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, note)
 }
 
 func (n NoteModel) Delete(id int64) error {
@@ -248,143 +139,33 @@ func (n NoteModel) Delete(id int64) error {
 }
 
 func (n NoteModel) GetAll() ([]*Note, error) {
-	rows, err := n.Query.SetBaseTable("notes").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"publishedAt",
-		"title",
-		"subtitle",
-		"slug",
-		"body",
-	).WhereEqual("deletedAt", nil).OrderBy("COALESCE(publishedAt, createdAt)", "desc").Query()
+	rows, err := n.Query.SetBaseTable("notes").Select(noteColumns...).
+		WhereEqual("deletedAt", nil).
+		OrderByExpression("COALESCE(publishedAt, createdAt)", "desc").
+		Query()
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	notes := []*Note{}
-
-	for rows.Next() {
-		var note Note
-		var deletedAt sql.NullTime
-		var publishedAt sql.NullTime
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&note.ID,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&deletedAt,
-			&publishedAt,
-			&note.Title,
-			&note.Subtitle,
-			&slug,
-			&note.Body,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			note.DeletedAt = &deletedAt.Time
-		}
-
-		if publishedAt.Valid {
-			note.PublishedAt = &publishedAt.Time
-		}
-
-		if slug.Valid {
-			note.Slug = slug.String
-		}
-
-		notes = append(notes, &note)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return notes, nil
+	return querybuilder.ScanAll[Note](rows)
 }
 
 func (n NoteModel) GetAllPublished() ([]*Note, error) {
-	rows, err := n.Query.SetBaseTable("notes").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"publishedAt",
-		"title",
-		"subtitle",
-		"slug",
-		"body",
-	).WhereEqual("deletedAt", nil).WhereLessThanEqual("publishedAt", time.Now()).OrderBy("COALESCE(publishedAt, createdAt)", "desc").Query()
+	rows, err := n.Query.SetBaseTable("notes").Select(noteColumns...).
+		WhereEqual("deletedAt", nil).
+		WhereLessThanEqual("publishedAt", time.Now()).
+		OrderByExpression("COALESCE(publishedAt, createdAt)", "desc").
+		Query()
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	notes := []*Note{}
-
-	for rows.Next() {
-		var note Note
-		var deletedAt sql.NullTime
-		var publishedAt sql.NullTime
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&note.ID,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&deletedAt,
-			&publishedAt,
-			&note.Title,
-			&note.Subtitle,
-			&slug,
-			&note.Body,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			note.DeletedAt = &deletedAt.Time
-		}
-
-		if publishedAt.Valid {
-			note.PublishedAt = &publishedAt.Time
-		}
-
-		if slug.Valid {
-			note.Slug = slug.String
-		}
-
-		notes = append(notes, &note)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return notes, nil
+	return querybuilder.ScanAll[Note](rows)
 }
 
 func (n NoteModel) GetPreviousPublished(publishedAt time.Time) (*Note, error) {
-	row, err := n.Query.SetBaseTable("notes").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"publishedAt",
-		"title",
-		"subtitle",
-		"slug",
-		"body",
-	).WhereEqual("deletedAt", nil).
+	row, err := n.Query.SetBaseTable("notes").Select(noteColumns...).
+		WhereEqual("deletedAt", nil).
 		WhereLessThan("publishedAt", publishedAt).
 		OrderBy("publishedAt", "desc").
 		Limit(1).
@@ -393,56 +174,12 @@ func (n NoteModel) GetPreviousPublished(publishedAt time.Time) (*Note, error) {
 		return nil, err
 	}
 
-	var note Note
-	var deletedAt sql.NullTime
-	var publishedAtVal sql.NullTime
-	var slugVal sql.NullString
-
-	err = row.Scan(
-		&note.ID,
-		&note.CreatedAt,
-		&note.UpdatedAt,
-		&deletedAt,
-		&publishedAtVal,
-		&note.Title,
-		&note.Subtitle,
-		&slugVal,
-		&note.Body,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		note.DeletedAt = &deletedAt.Time
-	}
-
-	if publishedAtVal.Valid {
-		note.PublishedAt = &publishedAtVal.Time
-	}
-
-	if slugVal.Valid {
-		note.Slug = slugVal.String
-	}
-
-	return &note, nil
+	return scanNote(row)
 }
 
 func (n NoteModel) GetNextPublished(publishedAt time.Time) (*Note, error) {
-	row, err := n.Query.SetBaseTable("notes").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"publishedAt",
-		"title",
-		"subtitle",
-		"slug",
-		"body",
-	).WhereEqual("deletedAt", nil).
+	row, err := n.Query.SetBaseTable("notes").Select(noteColumns...).
+		WhereEqual("deletedAt", nil).
 		WhereGreaterThan("publishedAt", publishedAt).
 		OrderBy("publishedAt", "asc").
 		Limit(1).
@@ -451,96 +188,16 @@ func (n NoteModel) GetNextPublished(publishedAt time.Time) (*Note, error) {
 		return nil, err
 	}
 
-	var note Note
-	var deletedAt sql.NullTime
-	var publishedAtVal sql.NullTime
-	var slugVal sql.NullString
-
-	err = row.Scan(
-		&note.ID,
-		&note.CreatedAt,
-		&note.UpdatedAt,
-		&deletedAt,
-		&publishedAtVal,
-		&note.Title,
-		&note.Subtitle,
-		&slugVal,
-		&note.Body,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		note.DeletedAt = &deletedAt.Time
-	}
-
-	if publishedAtVal.Valid {
-		note.PublishedAt = &publishedAtVal.Time
-	}
-
-	if slugVal.Valid {
-		note.Slug = slugVal.String
-	}
-
-	return &note, nil
+	return scanNote(row)
 }
 
 func (n NoteModel) GetBySlug(slug string) (*Note, error) {
-	row, err := n.Query.SetBaseTable("notes").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"publishedAt",
-		"title",
-		"subtitle",
-		"slug",
-		"body",
-	).WhereEqual("deletedAt", nil).WhereEqual("slug", slug).QueryRow()
+	row, err := n.Query.SetBaseTable("notes").Select(noteColumns...).WhereEqual("deletedAt", nil).WhereEqual("slug", slug).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var note Note
-	var deletedAt sql.NullTime
-	var publishedAt sql.NullTime
-	var slugVal sql.NullString
-
-	err = row.Scan(
-		&note.ID,
-		&note.CreatedAt,
-		&note.UpdatedAt,
-		&deletedAt,
-		&publishedAt,
-		&note.Title,
-		&note.Subtitle,
-		&slugVal,
-		&note.Body,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		note.DeletedAt = &deletedAt.Time
-	}
-
-	if publishedAt.Valid {
-		note.PublishedAt = &publishedAt.Time
-	}
-
-	if slugVal.Valid {
-		note.Slug = slugVal.String
-	}
-
-	return &note, nil
+	return scanNote(row)
 }
 
 func (n NoteModel) ensureUniqueSlug(note *Note) error {
@@ -571,3 +228,16 @@ func (n NoteModel) ensureUniqueSlug(note *Note) error {
 	}
 	return nil
 }
+
+// scanNote reads a single note, reporting a missing row as "record not found".
+func scanNote(row querybuilder.Scanner) (*Note, error) {
+	var note Note
+	if err := querybuilder.ScanStruct(row, &note); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
+		}
+		return nil, err
+	}
+
+	return &note, nil
+}
@@ -9,24 +9,25 @@ import (
 
 	"api.etin.dev/pkg/querybuilder"
 	"github.com/gosimple/slug"
-	"github.com/lib/pq"
 )
 
 type Project struct {
-	ID          int64      `json:"id"`
-	CreatedAt   time.Time  `json:"-"`
-	UpdatedAt   time.Time  `json:"-"`
-	DeletedAt   *time.Time `json:"-"`
-	StartDate   time.Time  `json:"startDate"`
-	EndDate     *time.Time `json:"endDate,omitempty"`
-	Title       string     `json:"title"`
-	Slug        string     `json:"slug"`
-	Description string     `json:"description"`
-	ImageURL    *string    `json:"imageUrl,omitempty"`
+	ID          int64      `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"-" db:"createdAt"`
+	UpdatedAt   time.Time  `json:"-" db:"updatedAt"`
+	DeletedAt   *time.Time `json:"-" db:"deletedAt"`
+	StartDate   time.Time  `json:"startDate" db:"startDate"`
+	EndDate     *time.Time `json:"endDate,omitempty" db:"endDate"`
+	Title       string     `json:"title" db:"title"`
+	Slug        string     `json:"slug" db:"slug"`
+	Description string     `json:"description" db:"description"`
+	ImageURL    *string    `json:"imageUrl,omitempty" db:"imageUrl"`
 }
 
+var projectColumns = querybuilder.Columns(Project{})
+
 type ProjectModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -59,66 +60,12 @@ func (p ProjectModel) Insert(project *Project) error {
 		{ColumnName: "imageUrl", Value: imageURL},
 	}
 
-	row, err := p.Query.SetBaseTable("projects").Insert(values).Returning(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"startDate",
-		"endDate",
-		"title",
-		"slug",
-		"description",
-		"imageUrl",
-	).QueryRow()
+	row, err := p.Query.SetBaseTable("projects").Insert(values).Returning(projectColumns...).QueryRow()
 	if err != nil {
 		return err
 	}
 
-	var deletedAt sql.NullTime
-	var savedEndDate sql.NullTime
-	var savedImageURL sql.NullString
-	var slug sql.NullString
-
-	err = row.Scan(
-		&project.ID,
-		&project.CreatedAt,
-		&project.UpdatedAt,
-		&deletedAt,
-		&project.StartDate,
-		&savedEndDate,
-		&project.Title,
-		&slug,
-		&project.Description,
-		&savedImageURL,
-	)
-	if err != nil {
-		return err
-	}
-
-	if deletedAt.Valid {
-		project.DeletedAt = &deletedAt.Time
-	} else {
-		project.DeletedAt = nil
-	}
-
-	if savedEndDate.Valid {
-		project.EndDate = &savedEndDate.Time
-	} else {
-		project.EndDate = nil
-	}
-
-	if savedImageURL.Valid {
-		project.ImageURL = &savedImageURL.String
-	} else {
-		project.ImageURL = nil
-	}
-
-	if slug.Valid {
-		project.Slug = slug.String
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, project)
 }
 
 func (p ProjectModel) Get(projectID int64) (*Project, error) {
@@ -126,64 +73,12 @@ func (p ProjectModel) Get(projectID int64) (*Project, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := p.Query.SetBaseTable("projects").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"startDate",
-		"endDate",
-		"title",
-		"slug",
-		"description",
-		"imageUrl",
-	).WhereEqual("deletedAt", nil).WhereEqual("id", projectID).QueryRow()
+	row, err := p.Query.SetBaseTable("projects").Select(projectColumns...).WhereEqual("deletedAt", nil).WhereEqual("id", projectID).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var project Project
-	var deletedAt sql.NullTime
-	var endDate sql.NullTime
-	var imageURL sql.NullString
-	var slug sql.NullString
-
-	err = row.Scan(
-		&project.ID,
-		&project.CreatedAt,
-		&project.UpdatedAt,
-		&deletedAt,
-		&project.StartDate,
-		&endDate,
-		&project.Title,
-		&slug,
-		&project.Description,
-		&imageURL,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		project.DeletedAt = &deletedAt.Time
-	}
-
-	if endDate.Valid {
-		project.EndDate = &endDate.Time
-	}
-
-	if imageURL.Valid {
-		project.ImageURL = &imageURL.String
-	}
-
-	if slug.Valid {
-		project.Slug = slug.String
-	}
-
-	return &project, nil
+	return scanProject(row)
 }
 
 func (p ProjectModel) Update(project *Project) error {
@@ -215,66 +110,12 @@ func (p ProjectModel) Update(project *Project) error {
 		{ColumnName: "updatedAt", Value: time.Now()},
 	}
 
-	row, err := p.Query.SetBaseTable("projects").Update(values).WhereEqual("id", project.ID).WhereEqual("deletedAt", nil).Returning(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"startDate",
-		"endDate",
-		"title",
-		"slug",
-		"description",
-		"imageUrl",
-	).QueryRow()
+	row, err := p.Query.SetBaseTable("projects").Update(values).WhereEqual("id", project.ID).WhereEqual("deletedAt", nil).Returning(projectColumns...).QueryRow()
 	if err != nil {
 		return err
 	}
 
-	var deletedAt sql.NullTime
-	var savedEndDate sql.NullTime
-	var savedImageURL sql.NullString
-	var slug sql.NullString
-
-	err = row.Scan(
-		&project.ID,
-		&project.CreatedAt,
-		&project.UpdatedAt,
-		&deletedAt,
-		&project.StartDate,
-		&savedEndDate,
-		&project.Title,
-		&slug,
-		&project.Description,
-		&savedImageURL,
-	)
-	if err != nil {
-		return err
-	}
-
-	if deletedAt.Valid {
-		project.DeletedAt = &deletedAt.Time
-	} else {
-		project.DeletedAt = nil
-	}
-
-	if savedEndDate.Valid {
-		project.EndDate = &savedEndDate.Time
-	} else {
-		project.EndDate = nil
-	}
-
-	if savedImageURL.Valid {
-		project.ImageURL = &savedImageURL.String
-	} else {
-		project.ImageURL = nil
-	}
-
-	if slug.Valid {
-		project.Slug = slug.String
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, project)
 }
 
 func (p ProjectModel) Delete(projectID int64) error {
@@ -305,73 +146,12 @@ func (p ProjectModel) Delete(projectID int64) error {
 }
 
 func (p ProjectModel) GetAll() ([]*Project, error) {
-	rows, err := p.Query.SetBaseTable("projects").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"startDate",
-		"endDate",
-		"title",
-		"slug",
-		"description",
-		"imageUrl",
-	).WhereEqual("deletedAt", nil).OrderBy("startDate", "desc").Query()
+	rows, err := p.Query.SetBaseTable("projects").Select(projectColumns...).WhereEqual("deletedAt", nil).OrderBy("startDate", "desc").Query()
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	projects := []*Project{}
-
-	for rows.Next() {
-		var project Project
-		var deletedAt sql.NullTime
-		var endDate sql.NullTime
-		var imageURL sql.NullString
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&project.ID,
-			&project.CreatedAt,
-			&project.UpdatedAt,
-			&deletedAt,
-			&project.StartDate,
-			&endDate,
-			&project.Title,
-			&slug,
-			&project.Description,
-			&imageURL,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			project.DeletedAt = &deletedAt.Time
-		}
-
-		if endDate.Valid {
-			project.EndDate = &endDate.Time
-		}
-
-		if imageURL.Valid {
-			project.ImageURL = &imageURL.String
-		}
-
-		if slug.Valid {
-			project.Slug = slug.String
-		}
-
-		projects = append(projects, &project)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return projects, nil
+	return querybuilder.ScanAll[Project](rows)
 }
 
 func (p ProjectModel) GetByIDs(ids []int64) ([]*Project, error) {
@@ -379,128 +159,21 @@ func (p ProjectModel) GetByIDs(ids []int64) ([]*Project, error) {
 		return []*Project{}, nil
 	}
 
-	query := `
-        SELECT id, createdAt, updatedAt, deletedAt, startDate, endDate, title, slug, description, imageUrl
-        FROM projects
-        WHERE deletedAt IS NULL AND id = ANY($1)
-    `
-
-	rows, err := p.DB.Query(query, pq.Array(ids))
+	rows, err := p.Query.SetBaseTable("projects").Select(projectColumns...).WhereEqual("deletedAt", nil).WhereIn("id", ids).Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	projects := []*Project{}
-
-	for rows.Next() {
-		var project Project
-		var deletedAt sql.NullTime
-		var endDate sql.NullTime
-		var imageURL sql.NullString
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&project.ID,
-			&project.CreatedAt,
-			&project.UpdatedAt,
-			&deletedAt,
-			&project.StartDate,
-			&endDate,
-			&project.Title,
-			&slug,
-			&project.Description,
-			&imageURL,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			project.DeletedAt = &deletedAt.Time
-		}
-
-		if endDate.Valid {
-			project.EndDate = &endDate.Time
-		}
-
-		if imageURL.Valid {
-			project.ImageURL = &imageURL.String
-		}
-
-		if slug.Valid {
-			project.Slug = slug.String
-		}
-
-		projects = append(projects, &project)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
 
-	return projects, nil
+	return querybuilder.ScanAll[Project](rows)
 }
 
 func (p ProjectModel) GetBySlug(slug string) (*Project, error) {
-	row, err := p.Query.SetBaseTable("projects").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"startDate",
-		"endDate",
-		"title",
-		"slug",
-		"description",
-		"imageUrl",
-	).WhereEqual("deletedAt", nil).WhereEqual("slug", slug).QueryRow()
+	row, err := p.Query.SetBaseTable("projects").Select(projectColumns...).WhereEqual("deletedAt", nil).WhereEqual("slug", slug).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var project Project
-	var deletedAt sql.NullTime
-	var endDate sql.NullTime
-	var imageURL sql.NullString
-	var slugVal sql.NullString
-
-	err = row.Scan(
-		&project.ID,
-		&project.CreatedAt,
-		&project.UpdatedAt,
-		&deletedAt,
-		&project.StartDate,
-		&endDate,
-		&project.Title,
-		&slugVal,
-		&project.Description,
-		&imageURL,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		project.DeletedAt = &deletedAt.Time
-	}
-
-	if endDate.Valid {
-		project.EndDate = &endDate.Time
-	}
-
-	if imageURL.Valid {
-		project.ImageURL = &imageURL.String
-	}
-
-	if slugVal.Valid {
-		project.Slug = slugVal.String
-	}
-
-	return &project, nil
+	return scanProject(row)
 }
 
 func (p ProjectModel) ensureUniqueSlug(project *Project) error {
@@ -531,3 +204,16 @@ func (p ProjectModel) ensureUniqueSlug(project *Project) error {
 	}
 	return nil
 }
+
+// scanProject reads a single project, reporting a missing row as "record not found".
+func scanProject(row querybuilder.Scanner) (*Project, error) {
+	var project Project
+	if err := querybuilder.ScanStruct(row, &project); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
+		}
+		return nil, err
+	}
+
+	return &project, nil
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+)
+
+// Replica is a read replica of the primary database. Models.ReadOnly sends queries to it while
+// it is healthy and to the primary otherwise.
+type Replica struct {
+	db      querybuilder.Executor
+	pinger  pinger
+	healthy atomic.Bool
+}
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// NewReplica returns a replica that runs queries through db and checks its health with p,
+// which is usually the *sql.DB that db is, or wraps. It starts out healthy.
+func NewReplica(db querybuilder.Executor, p pinger) *Replica {
+	replica := &Replica{db: db, pinger: p}
+	replica.healthy.Store(true)
+	return replica
+}
+
+// Healthy reports whether the last health check succeeded.
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// Check pings the replica and records whether it answered.
+func (r *Replica) Check(ctx context.Context) error {
+	err := r.pinger.PingContext(ctx)
+	r.healthy.Store(err == nil)
+	return err
+}
+
+// Monitor checks the replica every interval until stop is called, logging whenever it becomes
+// unreachable or recovers. stop waits for a check that is under way to finish.
+func (r *Replica) Monitor(interval time.Duration, logf func(format string, v ...any)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				wasHealthy := r.Healthy()
+
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				err := r.Check(ctx)
+				cancel()
+
+				switch {
+				case err != nil && wasHealthy:
+					logf("Read replica is unavailable, reading from the primary: %s", err)
+				case err == nil && !wasHealthy:
+					logf("Read replica is available again")
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
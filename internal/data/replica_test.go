@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestModels_ReadOnlyRoutesToHealthyReplica(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer primary.Close()
+
+	replicaDB, replicaMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer replicaDB.Close()
+
+	replica := NewReplica(replicaDB, replicaDB)
+	models := NewModels(primary, log.New(os.Stdout, "", 0)).WithReplica(replica)
+	ctx := context.Background()
+
+	// Each read finds nothing; what matters is which database it was sent to.
+	tagQuery := `SELECT (.+) FROM tags WHERE deletedAt IS NULL AND id = \$1`
+	tagRows := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"id"}) }
+
+	// Healthy: public reads go to the replica, but not when reading your own writes.
+	replicaMock.ExpectQuery(tagQuery).WithArgs(1).WillReturnRows(tagRows())
+	primaryMock.ExpectQuery(tagQuery).WithArgs(1).WillReturnRows(tagRows())
+
+	if _, err := models.ReadOnly(ctx).Tags.Get(1); !isNotFound(err) {
+		t.Fatalf("unexpected error reading from the replica: %s", err)
+	}
+	if _, err := models.ReadOnly(ReadYourWrites(ctx)).Tags.Get(1); !isNotFound(err) {
+		t.Fatalf("unexpected error reading your writes: %s", err)
+	}
+
+	// Unhealthy: public reads fall back to the primary until the replica answers again.
+	replicaMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	primaryMock.ExpectQuery(tagQuery).WithArgs(1).WillReturnRows(tagRows())
+	replicaMock.ExpectPing()
+	replicaMock.ExpectQuery(tagQuery).WithArgs(1).WillReturnRows(tagRows())
+
+	if err := replica.Check(ctx); err == nil || replica.Healthy() {
+		t.Fatalf("expected the replica to be unhealthy, got %v", err)
+	}
+	if _, err := models.ReadOnly(ctx).Tags.Get(1); !isNotFound(err) {
+		t.Fatalf("unexpected error falling back to the primary: %s", err)
+	}
+
+	if err := replica.Check(ctx); err != nil || !replica.Healthy() {
+		t.Fatalf("expected the replica to recover, got %v", err)
+	}
+	if _, err := models.ReadOnly(ctx).Tags.Get(1); !isNotFound(err) {
+		t.Fatalf("unexpected error reading from the recovered replica: %s", err)
+	}
+
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet primary expectations: %s", err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet replica expectations: %s", err)
+	}
+}
+
+func TestModels_ReadOnlyWithoutReplicaUsesPrimary(t *testing.T) {
+	primary, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer primary.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM tags WHERE deletedAt IS NULL AND id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	models := NewModels(primary, log.New(os.Stdout, "", 0))
+	if _, err := models.ReadOnly(context.Background()).Tags.Get(1); !isNotFound(err) {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+// isNotFound reports whether a read reached a database that had no matching row.
+func isNotFound(err error) bool {
+	return err != nil && err.Error() == "record not found"
+}
@@ -10,28 +10,61 @@ import (
 	"api.etin.dev/pkg/querybuilder"
 	"github.com/gosimple/slug"
 	"github.com/lib/pq"
-	_ "github.com/lib/pq"
 )
 
 type Role struct {
-	ID          int64     `json:"id"`
-	CreatedAt   time.Time `json:"-"`
-	UpdatedAt   time.Time `json:"-"`
-	DeletedAt   time.Time `json:"-"`
-	StartDate   time.Time `json:"startDate"`
-	EndDate     time.Time `json:"endDate"`
-	Title       string    `json:"title"`
-	Subtitle    string    `json:"subtitle"`
-	CompanyId   int64     `json:"companyId"`
-	Company     string    `json:"company"`
-	CompanyIcon string    `json:"companyIcon"`
-	Slug        string    `json:"slug"`
-	Description string    `json:"description"`
-	Skills      []string  `json:"skills"`
+	ID          int64     `json:"id" db:"id"`
+	CreatedAt   time.Time `json:"-" db:"createdAt"`
+	UpdatedAt   time.Time `json:"-" db:"updatedAt"`
+	DeletedAt   time.Time `json:"-" db:"deletedAt"`
+	StartDate   time.Time `json:"startDate" db:"startDate"`
+	EndDate     time.Time `json:"endDate" db:"endDate"`
+	Title       string    `json:"title" db:"title"`
+	Subtitle    string    `json:"subtitle" db:"subtitle"`
+	CompanyId   int64     `json:"companyId" db:"companyId"`
+	Company     string    `json:"company" db:"company"`
+	CompanyIcon string    `json:"companyIcon" db:"companyIcon"`
+	Slug        string    `json:"slug" db:"slug"`
+	Description string    `json:"description" db:"description"`
+	Skills      []string  `json:"skills" db:"skills"`
+}
+
+// roleColumns are the fields a role is read into. company and companyIcon come from the joined
+// companies table, so roles are selected with roleSelect rather than by column name alone.
+var roleColumns = []string{
+	"id",
+	"createdAt",
+	"updatedAt",
+	"startDate",
+	"endDate",
+	"title",
+	"subtitle",
+	"slug",
+	"description",
+	"skills",
+	"companyId",
+	"company",
+	"companyIcon",
+}
+
+var roleSelect = []string{
+	"roles.id AS id",
+	"roles.createdAt AS createdAt",
+	"roles.updatedAt AS updatedAt",
+	"roles.startDate AS startDate",
+	"roles.endDate AS endDate",
+	"roles.title AS title",
+	"roles.subtitle AS subtitle",
+	"roles.slug AS slug",
+	"roles.description AS description",
+	"roles.skills AS skills",
+	"roles.companyId AS companyId",
+	"companies.name AS company",
+	"companies.icon AS companyIcon",
 }
 
 type RoleModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -69,7 +102,7 @@ func (r RoleModel) Insert(role *Role) error {
 		return err
 	}
 
-	return row.Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt, &role.Company, &role.CompanyIcon)
+	return querybuilder.ScanStruct(row, role, "id", "createdAt", "updatedAt", "company", "companyIcon")
 }
 
 func (r RoleModel) Get(roleId int64) (*Role, error) {
@@ -77,50 +110,16 @@ func (r RoleModel) Get(roleId int64) (*Role, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := r.Query.SetBaseTable("roles").
-		Select(
-			"roles.id AS id", "roles.createdAt AS createdAt", "roles.updatedAt AS updatedAt", "roles.startDate AS startDate",
-			"roles.endDate AS endDate", "roles.title AS title", "roles.subtitle AS subtitle", "roles.slug AS slug",
-			"roles.description AS description", "roles.skills AS skills",
-			"companies.id as companyId", "companies.name as company", "companies.icon as companyIcon").
+	row, err := r.Query.SetBaseTable("roles").Select(roleSelect...).
 		LeftJoin("companies", "companyId", "id").
 		WhereEqual("roles.deletedAt", nil).
 		WhereEqual("roles.id", roleId).
 		QueryRow()
-
-	var role Role
-	var slug sql.NullString
-
-	err = row.Scan(
-		&role.ID,
-		&role.CreatedAt,
-		&role.UpdatedAt,
-		&role.StartDate,
-		&role.EndDate,
-		&role.Title,
-		&role.Subtitle,
-		&slug,
-		&role.Description,
-		pq.Array(&role.Skills),
-		&role.CompanyId,
-		&role.Company,
-		&role.CompanyIcon,
-	)
-
-	if slug.Valid {
-		role.Slug = slug.String
-	}
-
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return nil, errors.New("record not found")
-		default:
-			return nil, err
-		}
+		return nil, err
 	}
 
-	return &role, nil
+	return scanRole(row)
 }
 
 func (r RoleModel) Update(role *Role) error {
@@ -156,54 +155,20 @@ func (r RoleModel) Update(role *Role) error {
 		return err
 	}
 
-	return row.Scan(&role.UpdatedAt, &role.CompanyId, &role.Company, &role.CompanyIcon)
+	return querybuilder.ScanStruct(row, role, "updatedAt", "companyId", "company", "companyIcon")
 }
 
 func (r RoleModel) GetBySlug(slugVal string) (*Role, error) {
-	row, err := r.Query.SetBaseTable("roles").
-		Select(
-			"roles.id AS id", "roles.createdAt AS createdAt", "roles.updatedAt AS updatedAt", "roles.startDate AS startDate",
-			"roles.endDate AS endDate", "roles.title AS title", "roles.subtitle AS subtitle", "roles.slug AS slug",
-			"roles.description AS description", "roles.skills AS skills",
-			"companies.id as companyId", "companies.name as company", "companies.icon as companyIcon").
+	row, err := r.Query.SetBaseTable("roles").Select(roleSelect...).
 		LeftJoin("companies", "companyId", "id").
 		WhereEqual("roles.deletedAt", nil).
 		WhereEqual("roles.slug", slugVal).
 		QueryRow()
-
-	var role Role
-	var slug sql.NullString
-
-	err = row.Scan(
-		&role.ID,
-		&role.CreatedAt,
-		&role.UpdatedAt,
-		&role.StartDate,
-		&role.EndDate,
-		&role.Title,
-		&role.Subtitle,
-		&slug,
-		&role.Description,
-		pq.Array(&role.Skills),
-		&role.CompanyId,
-		&role.Company,
-		&role.CompanyIcon,
-	)
-
-	if slug.Valid {
-		role.Slug = slug.String
-	}
-
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return nil, errors.New("record not found")
-		default:
-			return nil, err
-		}
+		return nil, err
 	}
 
-	return &role, nil
+	return scanRole(row)
 }
 
 func (r RoleModel) ensureUniqueSlug(role *Role) error {
@@ -263,59 +228,15 @@ func (r RoleModel) Delete(roleId int64) error {
 }
 
 func (r RoleModel) GetAll() ([]*Role, error) {
-	rows, err := r.Query.SetBaseTable("roles").Select(
-		"roles.id AS id", "roles.createdAt AS createdAt", "roles.updatedAt AS updatedAt", "roles.startDate AS startDate",
-		"roles.endDate AS endDate", "roles.title AS title", "roles.subtitle AS subtitle", "roles.slug AS slug",
-		"roles.description AS description", "roles.skills AS skills", "roles.companyId AS companyId",
-		"companies.name AS company", "companies.icon AS companyIcon",
-	).
+	rows, err := r.Query.SetBaseTable("roles").Select(roleSelect...).
 		LeftJoin("companies", "companyId", "id").
 		OrderBy("startDate", "desc").
 		Query()
-
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	roles := []*Role{}
-
-	for rows.Next() {
-		var role Role
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&role.ID,
-			&role.CreatedAt,
-			&role.UpdatedAt,
-			&role.StartDate,
-			&role.EndDate,
-			&role.Title,
-			&role.Subtitle,
-			&slug,
-			&role.Description,
-			pq.Array(&role.Skills),
-			&role.CompanyId,
-			&role.Company,
-			&role.CompanyIcon,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if slug.Valid {
-			role.Slug = slug.String
-		}
-
-		roles = append(roles, &role)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return roles, nil
+	return querybuilder.ScanAll[Role](rows, roleColumns...)
 }
 
 func (r RoleModel) GetByIDs(ids []int64) ([]*Role, error) {
@@ -323,58 +244,28 @@ func (r RoleModel) GetByIDs(ids []int64) ([]*Role, error) {
 		return []*Role{}, nil
 	}
 
-	query := `
-        SELECT
-            roles.id AS id, roles.createdAt AS createdAt, roles.updatedAt AS updatedAt, roles.startDate AS startDate,
-			roles.endDate AS endDate, roles.title AS title, roles.subtitle AS subtitle, roles.slug AS slug,
-			roles.description AS description, roles.skills AS skills, roles.companyId AS companyId,
-			companies.name AS company, companies.icon AS companyIcon
-        FROM roles
-        LEFT JOIN companies ON roles.companyId = companies.id
-        WHERE roles.deletedAt IS NULL AND roles.id = ANY($1)
-    `
-
-	rows, err := r.DB.Query(query, pq.Array(ids))
+	rows, err := r.Query.SetBaseTable("roles").Select(roleSelect...).
+		LeftJoin("companies", "companyId", "id").
+		WhereEqual("roles.deletedAt", nil).
+		WhereIn("roles.id", ids).
+		Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	roles := []*Role{}
-
-	for rows.Next() {
-		var role Role
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&role.ID,
-			&role.CreatedAt,
-			&role.UpdatedAt,
-			&role.StartDate,
-			&role.EndDate,
-			&role.Title,
-			&role.Subtitle,
-			&slug,
-			&role.Description,
-			pq.Array(&role.Skills),
-			&role.CompanyId,
-			&role.Company,
-			&role.CompanyIcon,
-		)
-		if err != nil {
-			return nil, err
-		}
 
-		if slug.Valid {
-			role.Slug = slug.String
-		}
-
-		roles = append(roles, &role)
-	}
+	return querybuilder.ScanAll[Role](rows, roleColumns...)
+}
 
-	if err = rows.Err(); err != nil {
+// scanRole reads a single role selected with roleSelect, reporting a missing row as "record
+// not found".
+func scanRole(row querybuilder.Scanner) (*Role, error) {
+	var role Role
+	if err := querybuilder.ScanStruct(row, &role, roleColumns...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
+		}
 		return nil, err
 	}
 
-	return roles, nil
+	return &role, nil
 }
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"log"
+	"strings"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/lib/pq"
+)
+
+// migration is a schema change from README.md, recognised by a column it adds.
+type migration struct {
+	name   string
+	table  string
+	column string
+}
+
+// migrations lists the schema changes in README.md in the order they were introduced. There is
+// no migrations table, so the schema's version is read off the columns that exist. A new
+// migration must be appended here along with its SQL.
+var migrations = []migration{
+	{name: "initial schema", table: "tagged_items", column: "itemtype"},
+	{name: "project image", table: "projects", column: "imageurl"},
+	{name: "notes table", table: "notes", column: "publishedat"},
+	{name: "assets table", table: "assets", column: "publicid"},
+	{name: "asset variants", table: "asset_variants", column: "assetid"},
+	{name: "asset content hash", table: "assets", column: "contenthash"},
+	{name: "asset gallery", table: "role_assets", column: "alttext"},
+	{name: "slug", table: "roles", column: "slug"},
+	{name: "webhook delivery", table: "webhook_delivery_attempts", column: "deliveryid"},
+	{name: "webhook subscriptions", table: "webhook_deliveries", column: "payload"},
+	{name: "audit events", table: "audit_events", column: "requestid"},
+}
+
+// LatestSchemaVersion is the schema version this build expects.
+var LatestSchemaVersion = len(migrations)
+
+// SchemaVersion describes how far the database schema has been migrated. Version counts the
+// migrations applied in order, stopping at the first one missing.
+type SchemaVersion struct {
+	Version int      `json:"version"`
+	Latest  int      `json:"latest"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Current reports whether every migration has been applied.
+func (v SchemaVersion) Current() bool {
+	return len(v.Missing) == 0
+}
+
+type SchemaModel struct {
+	DB     querybuilder.Executor
+	Query  *querybuilder.QueryBuilder
+	Logger *log.Logger
+}
+
+// Version reads the schema version from the columns of the tables in the current schema.
+func (m SchemaModel) Version() (*SchemaVersion, error) {
+	tables := make([]string, 0, len(migrations))
+	for _, migration := range migrations {
+		tables = append(tables, migration.table)
+	}
+
+	rows, err := m.Query.Executor().QueryContext(m.Query.Context(), `
+        SELECT lower(table_name), lower(column_name) FROM information_schema.columns
+        WHERE table_schema = current_schema() AND lower(table_name) = ANY($1)
+    `, pq.Array(tables))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		columns[table+"."+column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	version := &SchemaVersion{Latest: LatestSchemaVersion}
+	for i, migration := range migrations {
+		if columns[migration.table+"."+strings.ToLower(migration.column)] {
+			if len(version.Missing) == 0 {
+				version.Version = i + 1
+			}
+			continue
+		}
+		version.Missing = append(version.Missing, migration.name)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newSchemaModel(t *testing.T) (SchemaModel, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return SchemaModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}, mock
+}
+
+func schemaColumnRows(skip ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"table_name", "column_name"})
+next:
+	for _, migration := range migrations {
+		for _, name := range skip {
+			if migration.name == name {
+				continue next
+			}
+		}
+		rows.AddRow(migration.table, migration.column)
+	}
+	return rows
+}
+
+func TestSchemaModel_VersionIsCurrent(t *testing.T) {
+	m, mock := newSchemaModel(t)
+
+	mock.ExpectQuery(`SELECT lower\(table_name\), lower\(column_name\) FROM information_schema.columns WHERE table_schema = current_schema\(\) AND lower\(table_name\) = ANY\(\$1\)`).
+		WillReturnRows(schemaColumnRows())
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !version.Current() || version.Version != LatestSchemaVersion || version.Latest != LatestSchemaVersion {
+		t.Fatalf("unexpected version %+v", version)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestSchemaModel_VersionStopsAtFirstMissingMigration(t *testing.T) {
+	m, mock := newSchemaModel(t)
+
+	mock.ExpectQuery(`FROM information_schema.columns`).
+		WillReturnRows(schemaColumnRows("asset content hash", "audit events"))
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if version.Current() || version.Version != 5 {
+		t.Fatalf("expected the version to stop before the asset content hash, got %+v", version)
+	}
+	if len(version.Missing) != 2 || version.Missing[0] != "asset content hash" || version.Missing[1] != "audit events" {
+		t.Fatalf("unexpected missing migrations %v", version.Missing)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
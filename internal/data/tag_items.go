@@ -21,14 +21,16 @@ const (
 )
 
 type TagItem struct {
-	ID       int64    `json:"id"`
-	TagID    int64    `json:"tagId"`
-	ItemID   int64    `json:"itemId"`
-	ItemType ItemType `json:"itemType"`
+	ID       int64    `json:"id" db:"id"`
+	TagID    int64    `json:"tagId" db:"tagId"`
+	ItemID   int64    `json:"itemId" db:"itemId"`
+	ItemType ItemType `json:"itemType" db:"itemType"`
 }
 
+var tagItemColumns = querybuilder.Columns(TagItem{})
+
 type TagItemModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -49,7 +51,7 @@ func (t TagItemModel) Insert(tagItem *TagItem) error {
 		return err
 	}
 
-	return row.Scan(&tagItem.ID)
+	return querybuilder.ScanStruct(row, tagItem, "id")
 }
 
 func (t TagItemModel) Delete(id int64) error {
@@ -79,33 +81,19 @@ func (t TagItemModel) Get(id int64) (*TagItem, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := t.Query.SetBaseTable("tagged_items").Select(
-		"id",
-		"tagId",
-		"itemId",
-		"itemType",
-	).WhereEqual("id", id).QueryRow()
+	row, err := t.Query.SetBaseTable("tagged_items").Select(tagItemColumns...).WhereEqual("id", id).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
 	var tagItem TagItem
-	var itemType string
-
-	if err := row.Scan(
-		&tagItem.ID,
-		&tagItem.TagID,
-		&tagItem.ItemID,
-		&itemType,
-	); err != nil {
+	if err := querybuilder.ScanStruct(row, &tagItem); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("record not found")
 		}
 		return nil, err
 	}
 
-	tagItem.ItemType = ItemType(itemType)
-
 	return &tagItem, nil
 }
 
@@ -122,67 +110,29 @@ func (t TagItemModel) Update(tagItem *TagItem) error {
 
 	row, err := t.Query.SetBaseTable("tagged_items").Update(values).
 		WhereEqual("id", tagItem.ID).
-		Returning("id", "tagId", "itemId", "itemType").
+		Returning(tagItemColumns...).
 		QueryRow()
 	if err != nil {
 		return err
 	}
 
-	var itemType string
-
-	if err := row.Scan(
-		&tagItem.ID,
-		&tagItem.TagID,
-		&tagItem.ItemID,
-		&itemType,
-	); err != nil {
+	if err := querybuilder.ScanStruct(row, tagItem); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return errors.New("record not found")
 		}
 		return err
 	}
 
-	tagItem.ItemType = ItemType(itemType)
-
 	return nil
 }
 
 func (t TagItemModel) GetAll() ([]*TagItem, error) {
-	rows, err := t.Query.SetBaseTable("tagged_items").Select(
-		"id",
-		"tagId",
-		"itemId",
-		"itemType",
-	).Query()
+	rows, err := t.Query.SetBaseTable("tagged_items").Select(tagItemColumns...).Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	tagItems := make([]*TagItem, 0)
-
-	for rows.Next() {
-		tagItem := &TagItem{}
-		var itemType string
-
-		if err := rows.Scan(
-			&tagItem.ID,
-			&tagItem.TagID,
-			&tagItem.ItemID,
-			&itemType,
-		); err != nil {
-			return nil, err
-		}
-
-		tagItem.ItemType = ItemType(itemType)
-		tagItems = append(tagItems, tagItem)
-	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return tagItems, nil
+	return querybuilder.ScanAll[TagItem](rows)
 }
 
 func (t TagItemModel) RemoveTagFromItem(tagID, itemID int64, itemType ItemType) error {
@@ -212,17 +162,8 @@ func (t TagItemModel) RemoveTagFromItem(tagID, itemID int64, itemType ItemType)
 }
 
 func (t TagItemModel) GetNotesForTag(tagID int64, limit int) ([]*Note, error) {
-	rows, err := t.Query.SetBaseTable("tagged_items").Select(
-		"notes.id AS id",
-		"notes.createdAt AS createdAt",
-		"notes.updatedAt AS updatedAt",
-		"notes.deletedAt AS deletedAt",
-		"notes.publishedAt AS publishedAt",
-		"notes.title AS title",
-		"notes.subtitle AS subtitle",
-		"notes.slug AS slug",
-		"notes.body AS body",
-	).LeftJoin("notes", "itemId", "id").
+	rows, err := t.Query.SetBaseTable("tagged_items").Select(querybuilder.QualifiedColumns("notes", Note{})...).
+		LeftJoin("notes", "itemId", "id").
 		WhereEqual("tagged_items.tagId", tagID).
 		WhereEqual("tagged_items.itemType", string(ItemTypeNotes)).
 		WhereEqual("notes.deletedAt", nil).
@@ -230,55 +171,11 @@ func (t TagItemModel) GetNotesForTag(tagID int64, limit int) ([]*Note, error) {
 		OrderBy("notes.publishedAt", "DESC").
 		Limit(limit).
 		Query()
-
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	notes := make([]*Note, 0)
-
-	for rows.Next() {
-		note := &Note{}
-		var deletedAt sql.NullTime
-		var publishedAt sql.NullTime
-		var slug sql.NullString
-
-		err := rows.Scan(
-			&note.ID,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&deletedAt,
-			&publishedAt,
-			&note.Title,
-			&note.Subtitle,
-			&slug,
-			&note.Body,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			note.DeletedAt = &deletedAt.Time
-		}
-
-		if publishedAt.Valid {
-			note.PublishedAt = &publishedAt.Time
-		}
-
-		if slug.Valid {
-			note.Slug = slug.String
-		}
-
-		notes = append(notes, note)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
 
-	return notes, nil
+	return querybuilder.ScanAll[Note](rows)
 }
 
 func (t TagItemModel) GetTagsForItem(itemType ItemType, itemID int64) ([]*Tag, error) {
@@ -286,16 +183,8 @@ func (t TagItemModel) GetTagsForItem(itemType ItemType, itemID int64) ([]*Tag, e
 		return nil, err
 	}
 
-	rows, err := t.Query.SetBaseTable("tagged_items").Select(
-		"tags.id AS id",
-		"tags.createdAt AS createdAt",
-		"tags.updatedAt AS updatedAt",
-		"tags.deletedAt AS deletedAt",
-		"tags.name AS name",
-		"tags.slug AS slug",
-		"tags.icon AS icon",
-		"tags.theme AS theme",
-	).LeftJoin("tags", "tagId", "id").
+	rows, err := t.Query.SetBaseTable("tagged_items").Select(querybuilder.QualifiedColumns("tags", Tag{})...).
+		LeftJoin("tags", "tagId", "id").
 		WhereEqual("tagged_items.itemId", itemID).
 		WhereEqual("tagged_items.itemType", string(itemType)).
 		WhereEqual("tags.deletedAt", nil).
@@ -303,51 +192,8 @@ func (t TagItemModel) GetTagsForItem(itemType ItemType, itemID int64) ([]*Tag, e
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	tags := make([]*Tag, 0)
-
-	for rows.Next() {
-		tag := &Tag{}
-		var deletedAt sql.NullTime
-		var iconValue sql.NullString
-		var themeValue sql.NullString
-
-		if err := rows.Scan(
-			&tag.ID,
-			&tag.CreatedAt,
-			&tag.UpdatedAt,
-			&deletedAt,
-			&tag.Name,
-			&tag.Slug,
-			&iconValue,
-			&themeValue,
-		); err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			tag.DeletedAt = &deletedAt.Time
-		}
-
-		if iconValue.Valid {
-			value := iconValue.String
-			tag.Icon = &value
-		}
-
-		if themeValue.Valid {
-			value := themeValue.String
-			tag.Theme = &value
-		}
-
-		tags = append(tags, tag)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
 
-	return tags, nil
+	return querybuilder.ScanAll[Tag](rows)
 }
 
 func validateItemType(itemType ItemType) error {
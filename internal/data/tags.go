@@ -12,18 +12,20 @@ import (
 )
 
 type Tag struct {
-	ID        int64      `json:"id"`
-	CreatedAt time.Time  `json:"-"`
-	UpdatedAt time.Time  `json:"-"`
-	DeletedAt *time.Time `json:"-"`
-	Name      string     `json:"name"`
-	Slug      string     `json:"slug"`
-	Icon      *string    `json:"icon,omitempty"`
-	Theme     *string    `json:"theme,omitempty"`
+	ID        int64      `json:"id" db:"id"`
+	CreatedAt time.Time  `json:"-" db:"createdAt"`
+	UpdatedAt time.Time  `json:"-" db:"updatedAt"`
+	DeletedAt *time.Time `json:"-" db:"deletedAt"`
+	Name      string     `json:"name" db:"name"`
+	Slug      string     `json:"slug" db:"slug"`
+	Icon      *string    `json:"icon,omitempty" db:"icon"`
+	Theme     *string    `json:"theme,omitempty" db:"theme"`
 }
 
+var tagColumns = querybuilder.Columns(Tag{})
+
 type TagModel struct {
-	DB     *sql.DB
+	DB     querybuilder.Executor
 	Query  *querybuilder.QueryBuilder
 	Logger *log.Logger
 }
@@ -54,61 +56,14 @@ func (t TagModel) Insert(tag *Tag) error {
 		{ColumnName: "theme", Value: theme},
 	}
 
-	row, err := t.Query.SetBaseTable("tags").Insert(values).Returning(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"slug",
-		"icon",
-		"theme",
-	).QueryRow()
-	if err != nil {
-		return err
-	}
+	returned := []string{"id", "createdAt", "updatedAt", "deletedAt", "slug", "icon", "theme"}
 
-	var deletedAt sql.NullTime
-	var iconValue sql.NullString
-	var themeValue sql.NullString
-	var slug sql.NullString
-
-	if err := row.Scan(
-		&tag.ID,
-		&tag.CreatedAt,
-		&tag.UpdatedAt,
-		&deletedAt,
-		&slug,
-		&iconValue,
-		&themeValue,
-	); err != nil {
+	row, err := t.Query.SetBaseTable("tags").Insert(values).Returning(returned...).QueryRow()
+	if err != nil {
 		return err
 	}
 
-	if deletedAt.Valid {
-		tag.DeletedAt = &deletedAt.Time
-	} else {
-		tag.DeletedAt = nil
-	}
-
-	if iconValue.Valid {
-		value := iconValue.String
-		tag.Icon = &value
-	} else {
-		tag.Icon = nil
-	}
-
-	if themeValue.Valid {
-		value := themeValue.String
-		tag.Theme = &value
-	} else {
-		tag.Theme = nil
-	}
-
-	if slug.Valid {
-		tag.Slug = slug.String
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, tag, returned...)
 }
 
 func (t TagModel) Get(id int64) (*Tag, error) {
@@ -116,61 +71,12 @@ func (t TagModel) Get(id int64) (*Tag, error) {
 		return nil, errors.New("record not found")
 	}
 
-	row, err := t.Query.SetBaseTable("tags").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"name",
-		"slug",
-		"icon",
-		"theme",
-	).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
+	row, err := t.Query.SetBaseTable("tags").Select(tagColumns...).WhereEqual("deletedAt", nil).WhereEqual("id", id).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var tag Tag
-	var deletedAt sql.NullTime
-	var iconValue sql.NullString
-	var themeValue sql.NullString
-	var slug sql.NullString
-
-	if err := row.Scan(
-		&tag.ID,
-		&tag.CreatedAt,
-		&tag.UpdatedAt,
-		&deletedAt,
-		&tag.Name,
-		&slug,
-		&iconValue,
-		&themeValue,
-	); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		tag.DeletedAt = &deletedAt.Time
-	}
-
-	if iconValue.Valid {
-		value := iconValue.String
-		tag.Icon = &value
-	}
-
-	if themeValue.Valid {
-		value := themeValue.String
-		tag.Theme = &value
-	}
-
-	if slug.Valid {
-		tag.Slug = slug.String
-	}
-
-	return &tag, nil
+	return scanTag(row)
 }
 
 func (t TagModel) Update(tag *Tag) error {
@@ -200,121 +106,21 @@ func (t TagModel) Update(tag *Tag) error {
 		{ColumnName: "updatedAt", Value: time.Now()},
 	}
 
-	row, err := t.Query.SetBaseTable("tags").Update(values).WhereEqual("id", tag.ID).WhereEqual("deletedAt", nil).Returning(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"name",
-		"slug",
-		"icon",
-		"theme",
-	).QueryRow()
+	row, err := t.Query.SetBaseTable("tags").Update(values).WhereEqual("id", tag.ID).WhereEqual("deletedAt", nil).Returning(tagColumns...).QueryRow()
 	if err != nil {
 		return err
 	}
 
-	var deletedAt sql.NullTime
-	var iconValue sql.NullString
-	var themeValue sql.NullString
-	var slug sql.NullString
-
-	if err := row.Scan(
-		&tag.ID,
-		&tag.CreatedAt,
-		&tag.UpdatedAt,
-		&deletedAt,
-		&tag.Name,
-		&slug,
-		&iconValue,
-		&themeValue,
-	); err != nil {
-		return err
-	}
-
-	if deletedAt.Valid {
-		tag.DeletedAt = &deletedAt.Time
-	} else {
-		tag.DeletedAt = nil
-	}
-
-	if iconValue.Valid {
-		value := iconValue.String
-		tag.Icon = &value
-	} else {
-		tag.Icon = nil
-	}
-
-	if themeValue.Valid {
-		value := themeValue.String
-		tag.Theme = &value
-	} else {
-		tag.Theme = nil
-	}
-
-	if slug.Valid {
-		tag.Slug = slug.String
-	}
-
-	return nil
+	return querybuilder.ScanStruct(row, tag)
 }
 
 func (t TagModel) GetBySlug(slugVal string) (*Tag, error) {
-	row, err := t.Query.SetBaseTable("tags").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"name",
-		"slug",
-		"icon",
-		"theme",
-	).WhereEqual("deletedAt", nil).WhereEqual("slug", slugVal).QueryRow()
+	row, err := t.Query.SetBaseTable("tags").Select(tagColumns...).WhereEqual("deletedAt", nil).WhereEqual("slug", slugVal).QueryRow()
 	if err != nil {
 		return nil, err
 	}
 
-	var tag Tag
-	var deletedAt sql.NullTime
-	var iconValue sql.NullString
-	var themeValue sql.NullString
-	var slug sql.NullString
-
-	if err := row.Scan(
-		&tag.ID,
-		&tag.CreatedAt,
-		&tag.UpdatedAt,
-		&deletedAt,
-		&tag.Name,
-		&slug,
-		&iconValue,
-		&themeValue,
-	); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("record not found")
-		}
-		return nil, err
-	}
-
-	if deletedAt.Valid {
-		tag.DeletedAt = &deletedAt.Time
-	}
-
-	if iconValue.Valid {
-		value := iconValue.String
-		tag.Icon = &value
-	}
-
-	if themeValue.Valid {
-		value := themeValue.String
-		tag.Theme = &value
-	}
-
-	if slug.Valid {
-		tag.Slug = slug.String
-	}
-
-	return &tag, nil
+	return scanTag(row)
 }
 
 func (t TagModel) ensureUniqueSlug(tag *Tag) error {
@@ -374,67 +180,23 @@ func (t TagModel) Delete(id int64) error {
 }
 
 func (t TagModel) GetAll() ([]*Tag, error) {
-	rows, err := t.Query.SetBaseTable("tags").Select(
-		"id",
-		"createdAt",
-		"updatedAt",
-		"deletedAt",
-		"name",
-		"slug",
-		"icon",
-		"theme",
-	).WhereEqual("deletedAt", nil).OrderBy("name", "asc").Query()
+	rows, err := t.Query.SetBaseTable("tags").Select(tagColumns...).WhereEqual("deletedAt", nil).OrderBy("name", "asc").Query()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	tags := make([]*Tag, 0)
-
-	for rows.Next() {
-		tag := &Tag{}
-		var deletedAt sql.NullTime
-		var iconValue sql.NullString
-		var themeValue sql.NullString
-		var slug sql.NullString
-
-		if err := rows.Scan(
-			&tag.ID,
-			&tag.CreatedAt,
-			&tag.UpdatedAt,
-			&deletedAt,
-			&tag.Name,
-			&slug,
-			&iconValue,
-			&themeValue,
-		); err != nil {
-			return nil, err
-		}
-
-		if deletedAt.Valid {
-			tag.DeletedAt = &deletedAt.Time
-		}
-
-		if iconValue.Valid {
-			value := iconValue.String
-			tag.Icon = &value
-		}
 
-		if themeValue.Valid {
-			value := themeValue.String
-			tag.Theme = &value
-		}
+	return querybuilder.ScanAll[Tag](rows)
+}
 
-		if slug.Valid {
-			tag.Slug = slug.String
+// scanTag reads a single tag, reporting a missing row as "record not found".
+func scanTag(row querybuilder.Scanner) (*Tag, error) {
+	var tag Tag
+	if err := querybuilder.ScanStruct(row, &tag); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
 		}
-
-		tags = append(tags, tag)
-	}
-
-	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return tags, nil
+	return &tag, nil
 }
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"api.etin.dev/pkg/querybuilder"
+)
+
+// runInTx calls fn with a transaction begun on db, committing it if fn succeeds and rolling it
+// back otherwise. When db is already a transaction fn simply joins it, and committing is left
+// to whoever began it.
+func runInTx(ctx context.Context, db querybuilder.Executor, fn func(tx querybuilder.Executor) error) error {
+	if tx, ok := db.(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	beginner, ok := db.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return errors.New("database handle cannot begin a transaction")
+	}
+
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestModels_RunInTxCommitsOnSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	models := NewModels(db, log.New(os.Stdout, "", 0))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO item_notes \(noteId, itemId, itemType\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
+		WithArgs(int64(3), int64(5), "project").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+
+	err = models.RunInTx(context.Background(), func(tx Models) error {
+		return tx.ItemNotes.Insert(&ItemNote{NoteID: 3, ItemID: 5, ItemType: "project"})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestModels_RunInTxRollsBackOnErrorAndJoinsNestedCalls(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	models := NewModels(db, log.New(os.Stdout, "", 0))
+	failure := errors.New("link failed")
+
+	// The nested call must not begin or commit a second transaction.
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err = models.RunInTx(context.Background(), func(tx Models) error {
+		return tx.RunInTx(context.Background(), func(Models) error {
+			return failure
+		})
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestModels_RunInTxOverStatementCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	models := NewModels(querybuilder.NewStatementCache(db, 10), log.New(os.Stdout, "", 0))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO item_notes \(noteId, itemId, itemType\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+
+	err = models.RunInTx(context.Background(), func(tx Models) error {
+		return tx.ItemNotes.Insert(&ItemNote{NoteID: 3, ItemID: 5, ItemType: "project"})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestModels_TransactJoinsModelsBoundToItsContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	models := NewModels(db, log.New(os.Stdout, "", 0))
+	failure := errors.New("audit failed")
+
+	// Both inserts run inside the one transaction, which the failure rolls back.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO item_notes`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery(`INSERT INTO audit_events`).
+		WillReturnError(failure)
+	mock.ExpectRollback()
+
+	err = models.Transact(context.Background(), func(ctx context.Context) error {
+		if err := models.WithContext(ctx).ItemNotes.Insert(&ItemNote{NoteID: 3, ItemID: 5, ItemType: "project"}); err != nil {
+			return err
+		}
+		return models.WithContext(ctx).AuditEvents.Insert(&AuditEvent{ActorType: "admin", Resource: "item_note", Action: "created"})
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the audit error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
@@ -0,0 +1,387 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+)
+
+// Delivery statuses. A pending delivery waits for its next attempt. At most one pending deploy
+// delivery exists per URL so that changes made while it waits are folded into it.
+const (
+	DeliveryPending    = "pending"
+	DeliveryDelivering = "delivering"
+	DeliveryDelivered  = "delivered"
+	DeliveryFailed     = "failed"
+	// DeliverySuperseded marks a delivery that failed while a newer one for the same URL was
+	// already queued. The newer delivery covers its changes, so it is not retried.
+	DeliverySuperseded = "superseded"
+)
+
+var (
+	// ErrDeliveryNotReplayable is returned when replaying a delivery that has not failed.
+	ErrDeliveryNotReplayable = errors.New("only failed deliveries can be replayed")
+	// ErrDeployWebhookDisabled is returned when replaying a deploy delivery while no deploy
+	// webhook is configured.
+	ErrDeployWebhookDisabled = errors.New("the deploy webhook is not configured")
+)
+
+// WebhookDelivery is a queued webhook call. Deliveries without a subscription go to the deploy
+// webhook, and Events counts the content changes folded into them while they waited. The
+// others carry a single event for a subscription.
+type WebhookDelivery struct {
+	ID             int64            `json:"id" db:"id"`
+	URL            string           `json:"url" db:"url"`
+	SubscriptionID *int64           `json:"subscriptionId,omitempty" db:"subscriptionId"`
+	EventType      string           `json:"eventType,omitempty" db:"eventType"`
+	Payload        json.RawMessage  `json:"payload" db:"payload"`
+	Status         string           `json:"status" db:"status"`
+	Events         int              `json:"events" db:"events"`
+	Attempts       int              `json:"attempts" db:"attempts"`
+	ReplayOf       *int64           `json:"replayOf,omitempty" db:"replayOf"`
+	NextAttemptAt  time.Time        `json:"nextAttemptAt" db:"nextAttemptAt"`
+	LastStatusCode *int             `json:"lastStatusCode,omitempty" db:"lastStatusCode"`
+	LastError      string           `json:"lastError,omitempty" db:"lastError"`
+	CreatedAt      time.Time        `json:"createdAt" db:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt" db:"updatedAt"`
+	DeliveredAt    *time.Time       `json:"deliveredAt,omitempty" db:"deliveredAt"`
+	AttemptLog     []WebhookAttempt `json:"attemptLog,omitempty"`
+	// Secret is the subscription's signing secret, loaded when the delivery is claimed.
+	Secret string `json:"-"`
+}
+
+// WebhookEvent is the payload delivered to subscriptions when a resource changes. Data holds a
+// snapshot of the resource, taken before it was deleted for delete events.
+type WebhookEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Resource   string    `json:"resource"`
+	ResourceID int64     `json:"resourceId"`
+	Action     string    `json:"action"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data"`
+}
+
+// WebhookAttempt records the outcome of a single request to the webhook.
+type WebhookAttempt struct {
+	Attempt     int       `json:"attempt" db:"attempt"`
+	StatusCode  *int      `json:"statusCode,omitempty" db:"statusCode"`
+	Error       string    `json:"error,omitempty" db:"error"`
+	DurationMs  int64     `json:"durationMs" db:"durationMs"`
+	AttemptedAt time.Time `json:"attemptedAt" db:"attemptedAt"`
+}
+
+type WebhookDeliveryModel struct {
+	DB     querybuilder.Executor
+	Query  *querybuilder.QueryBuilder
+	Logger *log.Logger
+}
+
+var (
+	webhookDeliveryColumns = querybuilder.Columns(WebhookDelivery{})
+	webhookAttemptColumns  = querybuilder.Columns(WebhookAttempt{})
+)
+
+const webhookDeliveryReturning = `RETURNING id, url, subscriptionId, eventType, payload, status, events, attempts, replayOf, nextAttemptAt, lastStatusCode, lastError, createdAt, updatedAt, deliveredAt`
+
+// Enqueue schedules a delivery to url once debounce has passed without further changes. While
+// a pending delivery exists its attempt is pushed back instead, but never beyond maxWait after
+// it was queued, so a steady stream of edits still deploys.
+func (m WebhookDeliveryModel) Enqueue(url string, debounce, maxWait time.Duration) (*WebhookDelivery, error) {
+	query := `
+        INSERT INTO webhook_deliveries (url, nextAttemptAt)
+        VALUES ($1, NOW() + $2 * interval '1 second')
+        ON CONFLICT (url) WHERE status = 'pending' AND subscriptionId IS NULL DO UPDATE SET
+            events = webhook_deliveries.events + 1,
+            nextAttemptAt = GREATEST(
+                webhook_deliveries.nextAttemptAt,
+                LEAST(EXCLUDED.nextAttemptAt, webhook_deliveries.createdAt + $3 * interval '1 second')
+            ),
+            updatedAt = NOW()
+        ` + webhookDeliveryReturning
+
+	return scanWebhookDelivery(m.Query.Executor().QueryRowContext(m.Query.Context(), query, url, debounce.Seconds(), maxWait.Seconds()))
+}
+
+// Publish queues a delivery of event to every active subscription listening for its type and
+// reports how many were queued.
+func (m WebhookDeliveryModel) Publish(event WebhookEvent) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := m.Query.Executor().ExecContext(m.Query.Context(), `
+        INSERT INTO webhook_deliveries (url, subscriptionId, eventType, payload, nextAttemptAt)
+        SELECT url, id, $1::text, $2::jsonb, NOW() FROM webhook_subscriptions
+        WHERE active AND ($1::text = ANY(events) OR '*' = ANY(events))
+    `, event.Type, string(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Claim marks the pending delivery that is due soonest as delivering and counts the attempt,
+// loading the signing secret of its subscription. It returns a not found error when nothing is
+// due.
+func (m WebhookDeliveryModel) Claim() (*WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries SET status = 'delivering', attempts = attempts + 1, updatedAt = NOW()
+        WHERE id = (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND nextAttemptAt <= NOW()
+            ORDER BY nextAttemptAt
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        ` + webhookDeliveryReturning + `,
+        (SELECT secret FROM webhook_subscriptions s WHERE s.id = webhook_deliveries.subscriptionId)`
+
+	var secret sql.NullString
+	delivery, err := scanWebhookDelivery(m.Query.Executor().QueryRowContext(m.Query.Context(), query), &secret)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
+		}
+		return nil, err
+	}
+	delivery.Secret = secret.String
+
+	return delivery, nil
+}
+
+// RecordAttempt logs an attempt and moves the delivery to status. A delivery sent back to
+// pending is retried at retryAt, unless it is a deploy delivery and a newer one for the same
+// URL has been queued in the meantime, in which case it is superseded by it.
+func (m WebhookDeliveryModel) RecordAttempt(delivery *WebhookDelivery, attempt WebhookAttempt, status string, retryAt time.Time) error {
+	ctx := m.Query.Context()
+	return runInTx(ctx, m.DB, func(tx querybuilder.Executor) error {
+		tx = querybuilder.Instrument(tx, m.Query.Hooks...)
+
+		_, err := tx.ExecContext(ctx, `
+        INSERT INTO webhook_delivery_attempts (deliveryId, attempt, statusCode, error, durationMs, attemptedAt)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, delivery.ID, attempt.Attempt, nullableInt(attempt.StatusCode), attempt.Error, attempt.DurationMs, attempt.AttemptedAt)
+		if err != nil {
+			return err
+		}
+
+		query := `
+        UPDATE webhook_deliveries d SET
+            status = CASE
+                WHEN $2 = 'pending' AND d.subscriptionId IS NULL AND EXISTS (
+                    SELECT 1 FROM webhook_deliveries o
+                    WHERE o.url = d.url AND o.status = 'pending' AND o.subscriptionId IS NULL AND o.id <> d.id
+                ) THEN 'superseded'
+                ELSE $2
+            END,
+            nextAttemptAt = $3,
+            lastStatusCode = $4,
+            lastError = $5,
+            deliveredAt = CASE WHEN $2 = 'delivered' THEN $6 ELSE d.deliveredAt END,
+            updatedAt = NOW()
+        WHERE d.id = $1
+        ` + webhookDeliveryReturning
+
+		updated, err := scanWebhookDelivery(tx.QueryRowContext(ctx, query, delivery.ID, status, retryAt, nullableInt(attempt.StatusCode), attempt.Error, attempt.AttemptedAt))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("record not found")
+			}
+			return err
+		}
+
+		*delivery = *updated
+		return nil
+	})
+}
+
+// Recover returns deliveries left delivering by a process that stopped mid-request to the
+// queue, and reports how many there were.
+func (m WebhookDeliveryModel) Recover() (int64, error) {
+	result, err := m.Query.Executor().ExecContext(m.Query.Context(), `
+        UPDATE webhook_deliveries d SET
+            status = CASE
+                WHEN d.subscriptionId IS NULL AND EXISTS (
+                    SELECT 1 FROM webhook_deliveries o
+                    WHERE o.url = d.url AND o.status = 'pending' AND o.subscriptionId IS NULL
+                ) THEN 'superseded'
+                ELSE 'pending'
+            END,
+            updatedAt = NOW()
+        WHERE d.status = 'delivering'
+    `)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// WebhookBacklog summarises the pending deliveries whose attempt is already due.
+type WebhookBacklog struct {
+	Due int64
+	// OldestOverdue is how long the longest waiting due delivery has been overdue.
+	OldestOverdue time.Duration
+}
+
+// Backlog reports how many pending deliveries are due and how far behind the oldest one is. A
+// growing backlog means the dispatcher is stuck or cannot keep up.
+func (m WebhookDeliveryModel) Backlog() (*WebhookBacklog, error) {
+	var backlog WebhookBacklog
+	var overdue float64
+	err := m.Query.Executor().QueryRowContext(m.Query.Context(), `
+        SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(nextAttemptAt)), 0)::float8
+        FROM webhook_deliveries
+        WHERE status = 'pending' AND nextAttemptAt <= NOW()
+    `).Scan(&backlog.Due, &overdue)
+	if err != nil {
+		return nil, err
+	}
+
+	backlog.OldestOverdue = time.Duration(overdue * float64(time.Second))
+	return &backlog, nil
+}
+
+// Replay queues a copy of a failed delivery, due immediately. Subscription deliveries go to the
+// subscription's current URL and deploy deliveries to deployURL. When a deploy delivery is
+// already pending it is brought forward instead and returned.
+func (m WebhookDeliveryModel) Replay(id int64, deployURL string) (*WebhookDelivery, error) {
+	original, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Status != DeliveryFailed && original.Status != DeliverySuperseded {
+		return nil, ErrDeliveryNotReplayable
+	}
+
+	if original.SubscriptionID == nil && deployURL == "" {
+		return nil, ErrDeployWebhookDisabled
+	}
+
+	query := `
+        INSERT INTO webhook_deliveries (url, subscriptionId, eventType, payload, replayOf, nextAttemptAt)
+        SELECT COALESCE(s.url, $2), d.subscriptionId, d.eventType, d.payload, d.id, NOW()
+        FROM webhook_deliveries d
+        LEFT JOIN webhook_subscriptions s ON s.id = d.subscriptionId
+        WHERE d.id = $1
+        ON CONFLICT (url) WHERE status = 'pending' AND subscriptionId IS NULL DO UPDATE SET
+            events = webhook_deliveries.events + 1,
+            nextAttemptAt = NOW(),
+            updatedAt = NOW()
+        ` + webhookDeliveryReturning
+
+	return scanWebhookDelivery(m.Query.Executor().QueryRowContext(m.Query.Context(), query, id, deployURL))
+}
+
+// Get returns a delivery along with its attempt log.
+func (m WebhookDeliveryModel) Get(id int64) (*WebhookDelivery, error) {
+	if id < 1 {
+		return nil, errors.New("record not found")
+	}
+
+	row, err := m.Query.SetBaseTable("webhook_deliveries").Select(webhookDeliveryColumns...).WhereEqual("id", id).QueryRow()
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := scanWebhookDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
+		}
+		return nil, err
+	}
+
+	rows, err := m.Query.SetBaseTable("webhook_delivery_attempts").Select(webhookAttemptColumns...).
+		WhereEqual("deliveryId", id).
+		OrderBy("attempt", "asc").
+		Query()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := querybuilder.ScanAll[WebhookAttempt](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.AttemptLog = make([]WebhookAttempt, len(attempts))
+	for i, attempt := range attempts {
+		delivery.AttemptLog[i] = *attempt
+	}
+
+	return delivery, nil
+}
+
+// WebhookDeliveryFilters narrows a delivery listing.
+type WebhookDeliveryFilters struct {
+	CursorFilters
+	Status         string
+	SubscriptionID int64
+}
+
+// GetAll lists deliveries newest first.
+func (m WebhookDeliveryModel) GetAll(filters WebhookDeliveryFilters) ([]*WebhookDelivery, Metadata, error) {
+	query := m.Query.SetBaseTable("webhook_deliveries").Select(webhookDeliveryColumns...)
+
+	if filters.Status != "" {
+		query.WhereEqual("status", filters.Status)
+	}
+
+	if filters.SubscriptionID > 0 {
+		query.WhereEqual("subscriptionId", filters.SubscriptionID)
+	}
+
+	if filters.Cursor != "" {
+		query.WhereLessThan("id", filters.Cursor)
+	}
+
+	rows, err := query.Limit(filters.Limit).OrderBy("id", "desc").Query()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	deliveries, err := querybuilder.ScanAll[WebhookDelivery](rows)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var metadata Metadata
+	if len(deliveries) > 0 && len(deliveries) >= filters.Limit {
+		metadata.NextCursor = strconv.FormatInt(deliveries[len(deliveries)-1].ID, 10)
+	}
+
+	return deliveries, metadata, nil
+}
+
+// scanWebhookDelivery scans the columns of webhookDeliveryColumns followed by any extra
+// destinations.
+func scanWebhookDelivery(row querybuilder.Scanner, extra ...any) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	targets, finish, err := querybuilder.Targets(&delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := row.Scan(append(targets, extra...)...); err != nil {
+		return nil, err
+	}
+	finish()
+
+	return &delivery, nil
+}
+
+func nullableInt(value *int) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newWebhookDeliveryModel(t *testing.T) (WebhookDeliveryModel, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return WebhookDeliveryModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}, mock
+}
+
+func webhookDeliveryRows(status string, attempts int) *sqlmock.Rows {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return sqlmock.NewRows(webhookDeliveryColumns).
+		AddRow(4, "https://hooks.example.com/build", nil, nil, []byte("{}"), status, 3, attempts, nil, now, nil, nil, now, now, nil)
+}
+
+func TestWebhookDeliveryModel_EnqueueFoldsIntoPendingDelivery(t *testing.T) {
+	m, mock := newWebhookDeliveryModel(t)
+
+	mock.ExpectQuery(`INSERT INTO webhook_deliveries \(url, nextAttemptAt\) VALUES \(\$1, NOW\(\) \+ \$2 \* interval '1 second'\) ON CONFLICT \(url\) WHERE status = 'pending' AND subscriptionId IS NULL DO UPDATE SET events = webhook_deliveries.events \+ 1`).
+		WithArgs("https://hooks.example.com/build", 10.0, 120.0).
+		WillReturnRows(webhookDeliveryRows(DeliveryPending, 0))
+
+	delivery, err := m.Enqueue("https://hooks.example.com/build", 10*time.Second, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if delivery.ID != 4 || delivery.Events != 3 || delivery.Status != DeliveryPending {
+		t.Fatalf("unexpected delivery %+v", delivery)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWebhookDeliveryModel_PublishQueuesMatchingSubscriptions(t *testing.T) {
+	m, mock := newWebhookDeliveryModel(t)
+
+	event := WebhookEvent{ID: "evt", Type: "note.published", Resource: "note", ResourceID: 12, Action: "published", Data: map[string]string{"title": "Hello"}}
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries \(url, subscriptionId, eventType, payload, nextAttemptAt\) SELECT url, id, \$1::text, \$2::jsonb, NOW\(\) FROM webhook_subscriptions WHERE active AND \(\$1::text = ANY\(events\) OR '\*' = ANY\(events\)\)`).
+		WithArgs("note.published", `{"id":"evt","type":"note.published","resource":"note","resourceId":12,"action":"published","occurredAt":"0001-01-01T00:00:00Z","data":{"title":"Hello"}}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	queued, err := m.Publish(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if queued != 2 {
+		t.Fatalf("expected two deliveries, got %d", queued)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWebhookDeliveryModel_ClaimReportsNothingDue(t *testing.T) {
+	m, mock := newWebhookDeliveryModel(t)
+
+	mock.ExpectQuery(`UPDATE webhook_deliveries SET status = 'delivering', attempts = attempts \+ 1`).
+		WillReturnRows(sqlmock.NewRows(webhookDeliveryColumns))
+
+	if _, err := m.Claim(); err == nil || err.Error() != "record not found" {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWebhookDeliveryModel_RecordAttemptLogsAndUpdates(t *testing.T) {
+	m, mock := newWebhookDeliveryModel(t)
+
+	attemptedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	retryAt := attemptedAt.Add(30 * time.Second)
+	statusCode := 502
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO webhook_delivery_attempts \(deliveryId, attempt, statusCode, error, durationMs, attemptedAt\)`).
+		WithArgs(int64(4), 2, 502, "unexpected status 502", int64(120), attemptedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET status = CASE`).
+		WithArgs(int64(4), DeliveryPending, retryAt, 502, "unexpected status 502", attemptedAt).
+		WillReturnRows(webhookDeliveryRows(DeliverySuperseded, 2))
+	mock.ExpectCommit()
+
+	delivery := &WebhookDelivery{ID: 4, Attempts: 2}
+	attempt := WebhookAttempt{Attempt: 2, StatusCode: &statusCode, Error: "unexpected status 502", DurationMs: 120, AttemptedAt: attemptedAt}
+	if err := m.RecordAttempt(delivery, attempt, DeliveryPending, retryAt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if delivery.Status != DeliverySuperseded {
+		t.Fatalf("expected the delivery to be refreshed from the update, got status %q", delivery.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWebhookDeliveryModel_ReplayOnlyFailedDeliveries(t *testing.T) {
+	m, mock := newWebhookDeliveryModel(t)
+
+	mock.ExpectQuery(`SELECT id, url, subscriptionId, eventType, payload, status, events, attempts, replayOf, nextAttemptAt, lastStatusCode, lastError, createdAt, updatedAt, deliveredAt FROM webhook_deliveries WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnRows(webhookDeliveryRows(DeliveryDelivered, 1))
+	mock.ExpectQuery(`SELECT attempt, statusCode, error, durationMs, attemptedAt FROM webhook_delivery_attempts WHERE deliveryId = \$1 ORDER BY attempt asc`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"attempt", "statusCode", "error", "durationMs", "attemptedAt"}))
+
+	if _, err := m.Replay(4, "https://hooks.example.com/build"); !errors.Is(err, ErrDeliveryNotReplayable) {
+		t.Fatalf("expected ErrDeliveryNotReplayable, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWebhookDeliveryModel_BacklogReportsOverdueDeliveries(t *testing.T) {
+	m, mock := newWebhookDeliveryModel(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(EXTRACT\(EPOCH FROM NOW\(\) - MIN\(nextAttemptAt\)\), 0\)::float8 FROM webhook_deliveries WHERE status = 'pending' AND nextAttemptAt <= NOW\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "overdue"}).AddRow(3, 90.5))
+
+	backlog, err := m.Backlog()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if backlog.Due != 3 || backlog.OldestOverdue != 90500*time.Millisecond {
+		t.Fatalf("unexpected backlog %+v", backlog)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
@@ -0,0 +1,172 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/lib/pq"
+)
+
+// WebhookEventTypes lists the events subscriptions can listen for. Each is named
+// "<resource>.<action>"; a subscription listing "*" receives all of them.
+var WebhookEventTypes = []string{
+	"note.created",
+	"note.updated",
+	"note.published",
+	"note.deleted",
+	"project.created",
+	"project.updated",
+	"project.deleted",
+	"role.created",
+	"role.updated",
+	"role.deleted",
+	"company.created",
+	"company.updated",
+	"company.deleted",
+	"tag.created",
+	"tag.updated",
+	"tag.deleted",
+	"asset.created",
+}
+
+// IsWebhookEventType reports whether eventType can be subscribed to.
+func IsWebhookEventType(eventType string) bool {
+	if eventType == "*" {
+		return true
+	}
+	for _, known := range WebhookEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription registers a URL to receive signed payloads for the listed events.
+type WebhookSubscription struct {
+	ID          int64     `json:"id" db:"id"`
+	URL         string    `json:"url" db:"url"`
+	Events      []string  `json:"events" db:"events"`
+	Description string    `json:"description" db:"description"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updatedAt"`
+	// Secret signs payloads. It is only returned when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookSubscriptionModel struct {
+	DB     querybuilder.Executor
+	Query  *querybuilder.QueryBuilder
+	Logger *log.Logger
+}
+
+var webhookSubscriptionColumns = querybuilder.Columns(WebhookSubscription{})
+
+func (m WebhookSubscriptionModel) Insert(subscription *WebhookSubscription) error {
+	values := querybuilder.Clauses{
+		querybuilder.Clause{ColumnName: "url", Value: subscription.URL},
+		querybuilder.Clause{ColumnName: "secret", Value: querybuilder.Sensitive(subscription.Secret)},
+		querybuilder.Clause{ColumnName: "events", Value: pq.Array(subscription.Events)},
+		querybuilder.Clause{ColumnName: "description", Value: subscription.Description},
+		querybuilder.Clause{ColumnName: "active", Value: subscription.Active},
+	}
+
+	returned := []string{"id", "createdAt", "updatedAt"}
+
+	row, err := m.Query.SetBaseTable("webhook_subscriptions").Insert(values).Returning(returned...).QueryRow()
+	if err != nil {
+		return err
+	}
+
+	return querybuilder.ScanStruct(row, subscription, returned...)
+}
+
+func (m WebhookSubscriptionModel) Get(id int64) (*WebhookSubscription, error) {
+	if id < 1 {
+		return nil, errors.New("record not found")
+	}
+
+	row, err := m.Query.SetBaseTable("webhook_subscriptions").Select(webhookSubscriptionColumns...).WhereEqual("id", id).QueryRow()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := scanWebhookSubscription(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("record not found")
+		}
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (m WebhookSubscriptionModel) GetAll() ([]*WebhookSubscription, error) {
+	rows, err := m.Query.SetBaseTable("webhook_subscriptions").Select(webhookSubscriptionColumns...).OrderBy("id", "asc").Query()
+	if err != nil {
+		return nil, err
+	}
+	return querybuilder.ScanAll[WebhookSubscription](rows)
+}
+
+// Update saves the URL, events, description and active flag. The secret is only written when
+// it is set, which rotates it.
+func (m WebhookSubscriptionModel) Update(subscription *WebhookSubscription) error {
+	values := querybuilder.Clauses{
+		querybuilder.Clause{ColumnName: "url", Value: subscription.URL},
+		querybuilder.Clause{ColumnName: "events", Value: pq.Array(subscription.Events)},
+		querybuilder.Clause{ColumnName: "description", Value: subscription.Description},
+		querybuilder.Clause{ColumnName: "active", Value: subscription.Active},
+		querybuilder.Clause{ColumnName: "updatedAt", Value: time.Now()},
+	}
+	if subscription.Secret != "" {
+		values = append(values, querybuilder.Clause{ColumnName: "secret", Value: querybuilder.Sensitive(subscription.Secret)})
+	}
+
+	row, err := m.Query.SetBaseTable("webhook_subscriptions").Update(values).WhereEqual("id", subscription.ID).Returning("updatedAt").QueryRow()
+	if err != nil {
+		return err
+	}
+
+	if err := querybuilder.ScanStruct(row, subscription, "updatedAt"); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("record not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a subscription along with its delivery log.
+func (m WebhookSubscriptionModel) Delete(id int64) error {
+	results, err := m.Query.SetBaseTable("webhook_subscriptions").Delete().WhereEqual("id", id).Exec()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("record not found")
+	}
+
+	return nil
+}
+
+func scanWebhookSubscription(row querybuilder.Scanner) (*WebhookSubscription, error) {
+	var subscription WebhookSubscription
+	if err := querybuilder.ScanStruct(row, &subscription); err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
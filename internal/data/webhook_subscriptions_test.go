@@ -0,0 +1,95 @@
+package data
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"api.etin.dev/pkg/querybuilder"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func newWebhookSubscriptionModel(t *testing.T) (WebhookSubscriptionModel, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return WebhookSubscriptionModel{
+		DB:     db,
+		Query:  &querybuilder.QueryBuilder{DB: db},
+		Logger: log.New(os.Stdout, "", 0),
+	}, mock
+}
+
+func TestWebhookSubscriptionModel_InsertStoresEventsAsArray(t *testing.T) {
+	m, mock := newWebhookSubscriptionModel(t)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO webhook_subscriptions \(url, secret, events, description, active\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id, createdAt, updatedAt`).
+		WithArgs("https://example.com/hook", "secret", pq.Array([]string{"note.published", "asset.created"}), "Search index", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "createdAt", "updatedAt"}).AddRow(3, now, now))
+
+	subscription := &WebhookSubscription{
+		URL:         "https://example.com/hook",
+		Secret:      "secret",
+		Events:      []string{"note.published", "asset.created"},
+		Description: "Search index",
+		Active:      true,
+	}
+	if err := m.Insert(subscription); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if subscription.ID != 3 || !subscription.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected subscription %+v", subscription)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestWebhookSubscriptionModel_GetAllOmitsSecrets(t *testing.T) {
+	m, mock := newWebhookSubscriptionModel(t)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, url, events, description, active, createdAt, updatedAt FROM webhook_subscriptions ORDER BY id asc`).
+		WillReturnRows(sqlmock.NewRows(webhookSubscriptionColumns).
+			AddRow(3, "https://example.com/hook", "{note.published,*}", "", true, now, now))
+
+	subscriptions, err := m.GetAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(subscriptions) != 1 || len(subscriptions[0].Events) != 2 || subscriptions[0].Events[1] != "*" {
+		t.Fatalf("unexpected subscriptions %+v", subscriptions)
+	}
+	if subscriptions[0].Secret != "" {
+		t.Fatalf("expected the secret to be left out of listings")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestIsWebhookEventType(t *testing.T) {
+	for eventType, expected := range map[string]bool{
+		"note.published": true,
+		"asset.created":  true,
+		"*":              true,
+		"asset.deleted":  false,
+		"":               false,
+	} {
+		if got := IsWebhookEventType(eventType); got != expected {
+			t.Fatalf("IsWebhookEventType(%q) = %t, expected %t", eventType, got, expected)
+		}
+	}
+}
@@ -47,6 +47,11 @@ func buildSecuritySchemes() map[string]any {
 			"bearerFormat": "opaque token",
 			"description":  "Bearer token issued by the admin login endpoint.",
 		},
+		"metricsAuth": map[string]any{
+			"type":        "http",
+			"scheme":      "bearer",
+			"description": "Static token configured with -metrics-token, required only when one is set.",
+		},
 	}
 }
 
@@ -115,6 +120,66 @@ func buildSchemas() map[string]any {
 				"status":      stringSchema("Service availability status."),
 				"environment": stringSchema("Deployment environment for the running service."),
 				"version":     stringSchema("Semantic version of the running service."),
+				"database":    ref("DatabasePoolStats"),
+				"replica":     ref("DatabasePoolStats"),
+			},
+		},
+		"HealthLiveResponse": map[string]any{
+			"type":     "object",
+			"required": []string{"status", "version"},
+			"properties": map[string]any{
+				"status":  stringSchema("Always alive while the process is serving requests."),
+				"version": stringSchema("Semantic version of the running service."),
+			},
+		},
+		"HealthReadyResponse": map[string]any{
+			"type":     "object",
+			"required": []string{"status", "version", "components"},
+			"properties": map[string]any{
+				"status": map[string]any{
+					"type":        "string",
+					"description": "ready when every component is up, degraded when only non-critical components are down and unavailable when a critical one is.",
+					"enum":        []string{"ready", "degraded", "unavailable"},
+				},
+				"version": stringSchema("Semantic version of the running service."),
+				"components": map[string]any{
+					"type":                 "object",
+					"description":          "Health of each dependency, keyed by database, migrations, assets, webhooks and, when one is configured, replica.",
+					"additionalProperties": ref("ComponentHealth"),
+				},
+			},
+		},
+		"ComponentHealth": map[string]any{
+			"type":     "object",
+			"required": []string{"status", "critical", "latencyMs"},
+			"properties": map[string]any{
+				"status": map[string]any{
+					"type":        "string",
+					"description": "Whether the dependency answered the check.",
+					"enum":        []string{"up", "down"},
+				},
+				"critical":  boolSchema("Indicates whether the server is unavailable while this dependency is down."),
+				"latencyMs": map[string]any{"type": "number", "description": "Time the check took, in milliseconds."},
+				"error":     stringSchema("Why the check failed, when it did."),
+				"details": map[string]any{
+					"type":        "object",
+					"description": "Component specific details, such as pool statistics, the schema version or the webhook backlog.",
+				},
+			},
+		},
+		"DatabasePoolStats": map[string]any{
+			"type":        "object",
+			"description": "Connection pool statistics. The replica pool is only reported when one is configured.",
+			"properties": map[string]any{
+				"maxOpenConnections": int64Schema("Maximum open connections allowed, or 0 for unlimited."),
+				"openConnections":    int64Schema("Connections currently open, in use or idle."),
+				"inUse":              int64Schema("Connections currently in use."),
+				"idle":               int64Schema("Connections currently idle."),
+				"waitCount":          int64Schema("Total times a caller waited for a connection."),
+				"waitDuration":       stringSchema("Total time spent waiting for a connection, such as 1.5s."),
+				"maxIdleClosed":      int64Schema("Connections closed because too many were idle."),
+				"maxIdleTimeClosed":  int64Schema("Connections closed for being idle too long."),
+				"maxLifetimeClosed":  int64Schema("Connections closed for reaching their maximum lifetime."),
 			},
 		},
 		"Company": map[string]any{
@@ -177,6 +242,11 @@ func buildSchemas() map[string]any {
 					"type":  "array",
 					"items": stringSchema("Skill associated with the role."),
 				},
+				"gallery": map[string]any{
+					"type":        "array",
+					"description": "Images attached to the role, in display order.",
+					"items":       ref("PublicGalleryImage"),
+				},
 			},
 		},
 		"CreateRoleRequest": map[string]any{
@@ -193,6 +263,11 @@ func buildSchemas() map[string]any {
 					"type":  "array",
 					"items": stringSchema("Skill associated with the role."),
 				},
+				"gallery": map[string]any{
+					"type":        "array",
+					"description": "Images attached to the role, in display order.",
+					"items":       ref("PublicGalleryImage"),
+				},
 			},
 		},
 		"UpdateRoleRequest": map[string]any{
@@ -208,6 +283,11 @@ func buildSchemas() map[string]any {
 					"type":  "array",
 					"items": stringSchema("Skill associated with the role."),
 				},
+				"gallery": map[string]any{
+					"type":        "array",
+					"description": "Images attached to the role, in display order.",
+					"items":       ref("PublicGalleryImage"),
+				},
 			},
 		},
 		"RoleResponse": map[string]any{
@@ -336,6 +416,16 @@ func buildSchemas() map[string]any {
 					"type":  "array",
 					"items": ref("PublicNote"),
 				},
+				"images": map[string]any{
+					"type":        "array",
+					"description": "Responsive metadata for uploaded images referenced in the note body.",
+					"items":       ref("PublicImage"),
+				},
+				"gallery": map[string]any{
+					"type":        "array",
+					"description": "Images attached to the note, in display order.",
+					"items":       ref("PublicGalleryImage"),
+				},
 			},
 		},
 		"PublicProject": map[string]any{
@@ -351,7 +441,12 @@ func buildSchemas() map[string]any {
 				}(),
 				"title": stringSchema("Project title."),
 				"image": stringSchema("Lead image URL for the project."),
-				"slug":  stringSchema("URL-friendly project slug."),
+				"imageSet": func() map[string]any {
+					schema := ref("PublicImage")
+					schema["description"] = "Responsive metadata for the lead image when it was uploaded through the asset pipeline."
+					return schema
+				}(),
+				"slug": stringSchema("URL-friendly project slug."),
 				"status": func() map[string]any {
 					schema := ref("PublicTag")
 					schema["nullable"] = true
@@ -363,6 +458,53 @@ func buildSchemas() map[string]any {
 					"type":  "array",
 					"items": stringSchema("Technology associated with the project."),
 				},
+				"gallery": map[string]any{
+					"type":        "array",
+					"description": "Images attached to the project, in display order.",
+					"items":       ref("PublicGalleryImage"),
+				},
+			},
+		},
+		"PublicImageVariant": map[string]any{
+			"type":     "object",
+			"required": []string{"name", "format", "url", "width", "height"},
+			"properties": map[string]any{
+				"name":   stringSchema("Variant name such as thumb, card or hero."),
+				"format": stringSchema("File format of the variant."),
+				"url":    stringSchema("Delivery URL for the variant."),
+				"width":  map[string]any{"type": "integer", "description": "Pixel width of the variant."},
+				"height": map[string]any{"type": "integer", "description": "Pixel height of the variant."},
+			},
+		},
+		"PublicImage": map[string]any{
+			"type":     "object",
+			"required": []string{"url", "width", "height", "variants", "srcset"},
+			"properties": map[string]any{
+				"url":    stringSchema("Delivery URL for the original image."),
+				"width":  map[string]any{"type": "integer", "description": "Pixel width of the original image."},
+				"height": map[string]any{"type": "integer", "description": "Pixel height of the original image."},
+				"variants": map[string]any{
+					"type":  "array",
+					"items": ref("PublicImageVariant"),
+				},
+				"srcset": map[string]any{
+					"type":                 "object",
+					"description":          "srcset attribute values keyed by image format.",
+					"additionalProperties": map[string]any{"type": "string"},
+				},
+			},
+		},
+		"PublicGalleryImage": map[string]any{
+			"allOf": []map[string]any{
+				ref("PublicImage"),
+				{
+					"type":     "object",
+					"required": []string{"caption", "altText"},
+					"properties": map[string]any{
+						"caption": stringSchema("Caption shown with the image."),
+						"altText": stringSchema("Alternative text describing the image."),
+					},
+				},
 			},
 		},
 		"PublicRole": map[string]any{
@@ -390,6 +532,11 @@ func buildSchemas() map[string]any {
 					"type":  "array",
 					"items": stringSchema("Skill associated with the role."),
 				},
+				"gallery": map[string]any{
+					"type":        "array",
+					"description": "Images attached to the role, in display order.",
+					"items":       ref("PublicGalleryImage"),
+				},
 			},
 		},
 		"PublicNotesResponse": map[string]any{
@@ -585,6 +732,109 @@ func buildSchemas() map[string]any {
 				"bytes":        int64Schema("File size in bytes."),
 				"width":        map[string]any{"type": "integer", "description": "Pixel width when available."},
 				"height":       map[string]any{"type": "integer", "description": "Pixel height when available."},
+				"contentHash":  stringSchema("Hex encoded SHA-256 of the uploaded bytes. Absent for assets uploaded before hashes were recorded."),
+				"variants": map[string]any{
+					"type":        "array",
+					"description": "Named renditions recorded for image assets.",
+					"items":       ref("AssetVariant"),
+				},
+			},
+		},
+		"AssetLink": map[string]any{
+			"type":     "object",
+			"required": []string{"itemType", "itemId", "assetId", "position", "caption", "altText"},
+			"properties": map[string]any{
+				"itemType": stringSchema("Type of item the asset is attached to."),
+				"itemId":   int64Schema("Identifier of the note, project or role."),
+				"assetId":  int64Schema("Identifier of the attached asset."),
+				"position": map[string]any{"type": "integer", "description": "Zero-based position in the gallery."},
+				"caption":  stringSchema("Caption shown with the image."),
+				"altText":  stringSchema("Alternative text describing the image."),
+				"asset":    ref("Asset"),
+			},
+		},
+		"AssetLinkResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"asset": ref("AssetLink"),
+			},
+		},
+		"AssetLinksResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"assets": map[string]any{
+					"type":  "array",
+					"items": ref("AssetLink"),
+				},
+			},
+		},
+		"CreateAssetLinkRequest": map[string]any{
+			"type":     "object",
+			"required": []string{"assetId"},
+			"properties": map[string]any{
+				"assetId": int64Schema("Identifier of the asset to attach. It is added to the end of the gallery."),
+				"caption": stringSchema("Caption shown with the image."),
+				"altText": stringSchema("Alternative text describing the image."),
+			},
+		},
+		"UpdateAssetLinkRequest": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"caption": stringSchema("Caption shown with the image."),
+				"altText": stringSchema("Alternative text describing the image."),
+			},
+		},
+		"ReorderAssetLinksRequest": map[string]any{
+			"type":     "object",
+			"required": []string{"assetIds"},
+			"properties": map[string]any{
+				"assetIds": map[string]any{
+					"type":        "array",
+					"description": "Every attached asset identifier, in the desired order.",
+					"items":       int64Schema("Asset identifier."),
+				},
+			},
+		},
+		"AssetDuplicateGroup": map[string]any{
+			"type":     "object",
+			"required": []string{"match", "bytes", "format", "width", "height", "assets"},
+			"properties": map[string]any{
+				"match": map[string]any{
+					"type":        "string",
+					"enum":        []string{"contentHash", "metadata"},
+					"description": "contentHash when the assets have identical contents, metadata when they were uploaded before hashes were recorded and only share size, format and dimensions.",
+				},
+				"contentHash": stringSchema("Content hash shared by the assets in the group. Absent for metadata matches."),
+				"bytes":       int64Schema("File size shared by the assets in the group."),
+				"format":      stringSchema("File format shared by the assets in the group."),
+				"width":       map[string]any{"type": "integer", "description": "Pixel width shared by the assets in the group."},
+				"height":      map[string]any{"type": "integer", "description": "Pixel height shared by the assets in the group."},
+				"assets": map[string]any{
+					"type":        "array",
+					"description": "Matching assets, oldest first.",
+					"items":       ref("Asset"),
+				},
+			},
+		},
+		"AssetDuplicatesResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"duplicates": map[string]any{
+					"type":  "array",
+					"items": ref("AssetDuplicateGroup"),
+				},
+			},
+		},
+		"AssetVariant": map[string]any{
+			"type":     "object",
+			"required": []string{"name", "format", "url", "width", "height", "bytes"},
+			"properties": map[string]any{
+				"name":   stringSchema("Variant name such as thumb, card or hero."),
+				"format": stringSchema("File format of the variant."),
+				"url":    stringSchema("Delivery URL for the variant."),
+				"width":  map[string]any{"type": "integer", "description": "Pixel width of the variant."},
+				"height": map[string]any{"type": "integer", "description": "Pixel height of the variant."},
+				"bytes":  int64Schema("File size in bytes. Zero when the backend renders the variant on demand."),
 			},
 		},
 		"AssetUploadRequest": map[string]any{
@@ -604,6 +854,255 @@ func buildSchemas() map[string]any {
 				"asset": ref("Asset"),
 			},
 		},
+		"PresignAssetRequest": map[string]any{
+			"type":     "object",
+			"required": []string{"filename", "contentType"},
+			"properties": map[string]any{
+				"filename":    stringSchema("Original file name. Its extension becomes part of the object key and must match the file's detected type."),
+				"contentType": stringSchema("Media type of the file. It must be on the allow-list, and S3 uploads must be sent with exactly this Content-Type."),
+			},
+		},
+		"PresignedUpload": map[string]any{
+			"type":     "object",
+			"required": []string{"key", "method", "url", "expiresAt"},
+			"properties": map[string]any{
+				"key":    stringSchema("Object key to pass to the confirm endpoint once the upload finishes."),
+				"method": stringSchema("HTTP method of the upload request: PUT for S3, POST for Cloudinary."),
+				"url":    stringSchema("URL the file is sent to."),
+				"headers": map[string]any{
+					"type":                 "object",
+					"description":          "Headers that must accompany a PUT of the raw file.",
+					"additionalProperties": map[string]any{"type": "string"},
+				},
+				"fields": map[string]any{
+					"type":                 "object",
+					"description":          "Form fields to send alongside a multipart file part in a POST.",
+					"additionalProperties": map[string]any{"type": "string"},
+				},
+				"expiresAt": dateTimeSchema("When the signed parameters stop being accepted by the backend."),
+			},
+		},
+		"PresignedUploadResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"upload": ref("PresignedUpload"),
+			},
+		},
+		"ConfirmAssetRequest": map[string]any{
+			"type":     "object",
+			"required": []string{"key"},
+			"properties": map[string]any{
+				"key": stringSchema("Key returned by the presign endpoint."),
+			},
+		},
+		"CreateUploadRequest": map[string]any{
+			"type":     "object",
+			"required": []string{"filename", "length"},
+			"properties": map[string]any{
+				"filename": stringSchema("Original file name. Its extension must match the file's detected type."),
+				"length":   int64Schema("Total size of the file in bytes."),
+			},
+		},
+		"UploadSession": map[string]any{
+			"type":     "object",
+			"required": []string{"id", "filename", "length", "offset", "createdAt", "expiresAt"},
+			"properties": map[string]any{
+				"id":        stringSchema("Upload session identifier."),
+				"filename":  stringSchema("File name supplied when the session was created."),
+				"length":    int64Schema("Declared total size in bytes."),
+				"offset":    int64Schema("Number of bytes received so far; the next chunk must start here."),
+				"createdAt": dateTimeSchema("When the session was created."),
+				"expiresAt": dateTimeSchema("When the session is discarded unless another chunk arrives."),
+			},
+		},
+		"UploadSessionResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"upload": ref("UploadSession"),
+			},
+		},
+		"WebhookAttempt": map[string]any{
+			"type":     "object",
+			"required": []string{"attempt", "durationMs", "attemptedAt"},
+			"properties": map[string]any{
+				"attempt":     map[string]any{"type": "integer", "description": "One-based attempt number."},
+				"statusCode":  map[string]any{"type": "integer", "description": "HTTP status returned by the webhook, absent when no response was received."},
+				"error":       stringSchema("Why the attempt failed, empty on success."),
+				"durationMs":  int64Schema("How long the request took in milliseconds."),
+				"attemptedAt": dateTimeSchema("When the attempt started."),
+			},
+		},
+		"WebhookDelivery": map[string]any{
+			"type":     "object",
+			"required": []string{"id", "url", "status", "events", "attempts", "nextAttemptAt", "createdAt", "updatedAt"},
+			"properties": map[string]any{
+				"id":             int64Schema("Delivery identifier."),
+				"url":            stringSchema("Webhook URL the delivery is sent to."),
+				"subscriptionId": int64Schema("Subscription the event is delivered to, absent for deploy webhook deliveries."),
+				"eventType":      stringSchema("Event carried by a subscription delivery, such as note.published."),
+				"payload": map[string]any{
+					"type":        "object",
+					"description": "JSON body sent to the webhook: a WebhookEvent for subscriptions, an empty object for the deploy webhook.",
+				},
+				"status": map[string]any{
+					"type":        "string",
+					"description": "Delivery state. Superseded deliveries failed while a newer delivery was queued and are not retried.",
+					"enum":        []string{"pending", "delivering", "delivered", "failed", "superseded"},
+				},
+				"events":         map[string]any{"type": "integer", "description": "Number of content changes folded into the delivery."},
+				"attempts":       map[string]any{"type": "integer", "description": "Number of attempts made so far."},
+				"replayOf":       int64Schema("Failed delivery this one replays."),
+				"nextAttemptAt":  dateTimeSchema("When a pending delivery is next attempted."),
+				"lastStatusCode": map[string]any{"type": "integer", "description": "HTTP status of the latest attempt."},
+				"lastError":      stringSchema("Error of the latest failed attempt."),
+				"createdAt":      dateTimeSchema("When the delivery was queued."),
+				"updatedAt":      dateTimeSchema("When the delivery last changed."),
+				"deliveredAt":    dateTimeSchema("When the webhook accepted the delivery."),
+				"attemptLog": map[string]any{
+					"type":        "array",
+					"description": "Every attempt in order. Only included when a single delivery is requested.",
+					"items":       ref("WebhookAttempt"),
+				},
+			},
+		},
+		"WebhookEvent": map[string]any{
+			"type":     "object",
+			"required": []string{"id", "type", "resource", "resourceId", "action", "occurredAt", "data"},
+			"properties": map[string]any{
+				"id":         stringSchema("Unique event identifier, stable across retries."),
+				"type":       stringSchema("Event type, <resource>.<action>."),
+				"resource":   stringSchema("Resource that changed: note, project, role, company, tag or asset."),
+				"resourceId": int64Schema("Identifier of the resource."),
+				"action":     stringSchema("created, updated, published or deleted."),
+				"occurredAt": dateTimeSchema("When the change was made."),
+				"data": map[string]any{
+					"type":        "object",
+					"description": "Snapshot of the resource after the change, or before it for deletes.",
+				},
+			},
+		},
+		"WebhookSubscription": map[string]any{
+			"type":     "object",
+			"required": []string{"id", "url", "events", "description", "active", "createdAt", "updatedAt"},
+			"properties": map[string]any{
+				"id":  int64Schema("Subscription identifier."),
+				"url": stringSchema("URL events are posted to."),
+				"events": map[string]any{
+					"type":        "array",
+					"description": "Event types delivered to the URL. \"*\" subscribes to every event.",
+					"items":       map[string]any{"type": "string"},
+				},
+				"description": stringSchema("Free text describing the receiver."),
+				"active":      map[string]any{"type": "boolean", "description": "Inactive subscriptions receive no new events."},
+				"createdAt":   dateTimeSchema("When the subscription was created."),
+				"updatedAt":   dateTimeSchema("When the subscription last changed."),
+				"secret":      stringSchema("HMAC signing secret. Only returned on creation and when rotated."),
+			},
+		},
+		"CreateWebhookRequest": map[string]any{
+			"type":     "object",
+			"required": []string{"url", "events"},
+			"properties": map[string]any{
+				"url": stringSchema("Absolute http or https URL to post events to."),
+				"events": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"type": "string"},
+				},
+				"description": stringSchema("Free text describing the receiver."),
+				"active":      map[string]any{"type": "boolean", "description": "Defaults to true."},
+			},
+		},
+		"UpdateWebhookRequest": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"url": stringSchema("Absolute http or https URL to post events to."),
+				"events": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"type": "string"},
+				},
+				"description":  stringSchema("Free text describing the receiver."),
+				"active":       map[string]any{"type": "boolean"},
+				"rotateSecret": map[string]any{"type": "boolean", "description": "Replace the signing secret and return the new one."},
+			},
+		},
+		"WebhookResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"webhook": ref("WebhookSubscription"),
+			},
+		},
+		"WebhooksResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"webhooks": map[string]any{
+					"type":  "array",
+					"items": ref("WebhookSubscription"),
+				},
+				"eventTypes": map[string]any{
+					"type":        "array",
+					"description": "Every event type a subscription can listen for.",
+					"items":       map[string]any{"type": "string"},
+				},
+			},
+		},
+		"WebhookDeliveryResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"delivery": ref("WebhookDelivery"),
+			},
+		},
+		"AuditEvent": map[string]any{
+			"type":     "object",
+			"required": []string{"id", "occurredAt", "actorType", "actor", "requestId", "resource", "resourceId", "action"},
+			"properties": map[string]any{
+				"id":         int64Schema("Event identifier."),
+				"occurredAt": dateTimeSchema("When the change was made."),
+				"actorType":  stringSchema("How the actor authenticated: admin for a valid session, or anonymous."),
+				"actor":      stringSchema("Email of the admin who made the change. Empty for anonymous actors."),
+				"requestId":  stringSchema("X-Request-ID of the request that made the change."),
+				"resource":   stringSchema("Changed resource, such as role, note, tagged_item or webhook. Gallery changes use the owning item's type."),
+				"resourceId": int64Schema("Identifier of the changed resource."),
+				"action":     stringSchema("created, updated or deleted, or a gallery action such as asset_attached."),
+				"before": map[string]any{
+					"type":        "object",
+					"description": "The resource before the change. Absent for creations.",
+				},
+				"after": map[string]any{
+					"type":        "object",
+					"description": "The resource after the change. Absent for deletions.",
+				},
+			},
+		},
+		"AuditEventsResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"events": map[string]any{
+					"type":  "array",
+					"items": ref("AuditEvent"),
+				},
+				"metadata": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"nextCursor": stringSchema("Cursor for the next page, absent on the last page."),
+					},
+				},
+			},
+		},
+		"WebhookDeliveriesResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"deliveries": map[string]any{
+					"type":  "array",
+					"items": ref("WebhookDelivery"),
+				},
+				"metadata": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"nextCursor": stringSchema("Cursor for the next page, absent on the last page."),
+					},
+				},
+			},
+		},
 		"CreateTagRequest": map[string]any{
 			"type":     "object",
 			"required": []string{"name", "slug"},
@@ -667,6 +1166,28 @@ func buildPaths() map[string]any {
 		}
 	}
 
+	galleryContentTypeParam := map[string]any{
+		"name":        "contentType",
+		"in":          "path",
+		"required":    true,
+		"description": "Type of item that owns the gallery.",
+		"schema": map[string]any{
+			"type": "string",
+			"enum": []string{"notes", "roles", "projects"},
+		},
+	}
+
+	galleryParams := []map[string]any{galleryContentTypeParam, intPathParam("id", "Identifier of the note, project or role.")}
+	galleryAssetParams := append(append([]map[string]any{}, galleryParams...), intPathParam("assetId", "Identifier of the attached asset."))
+
+	uploadIDParam := map[string]any{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "Upload session identifier.",
+		"schema":      map[string]any{"type": "string"},
+	}
+
 	itemTypeParam := map[string]any{
 		"name":        "itemType",
 		"in":          "path",
@@ -691,6 +1212,49 @@ func buildPaths() map[string]any {
 				},
 			},
 		},
+		"/v1/health/live": map[string]any{
+			"get": map[string]any{
+				"operationId": "getHealthLive",
+				"summary":     "Check the service is running",
+				"description": "Liveness probe. Checks no dependencies, so it only fails when the process cannot serve requests.",
+				"tags":        []string{"Health"},
+				"responses": map[string]any{
+					"200": jsonResponse("Service is running.", "HealthLiveResponse"),
+				},
+			},
+		},
+		"/v1/health/ready": map[string]any{
+			"get": map[string]any{
+				"operationId": "getHealthReady",
+				"summary":     "Check the service can take traffic",
+				"description": "Readiness probe. Checks database connectivity, the schema version, the asset backend, the webhook backlog and the read replica, reporting each component's status and latency.",
+				"tags":        []string{"Health"},
+				"responses": map[string]any{
+					"200": jsonResponse("Every critical dependency is up.", "HealthReadyResponse"),
+					"503": jsonResponse("A critical dependency is down.", "HealthReadyResponse"),
+				},
+			},
+		},
+		"/metrics": map[string]any{
+			"get": map[string]any{
+				"operationId": "getMetrics",
+				"summary":     "Retrieve Prometheus metrics",
+				"description": "Request counts and latencies by route pattern, query durations, connection pool statistics, webhook delivery outcomes, upload volumes and session counts. Served on -metrics-addr instead when that is set.",
+				"tags":        []string{"Health"},
+				"security":    []map[string]any{{}, {"metricsAuth": []string{}}},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Metrics in the Prometheus text exposition format.",
+						"content": map[string]any{
+							"text/plain": map[string]any{
+								"schema": map[string]any{"type": "string"},
+							},
+						},
+					},
+					"401": noContent("Missing or invalid metrics token."),
+				},
+			},
+		},
 		"/swagger": map[string]any{
 			"get": map[string]any{
 				"operationId": "getSwaggerDocument",
@@ -823,15 +1387,524 @@ func buildPaths() map[string]any {
 					},
 				},
 				"responses": map[string]any{
+					"200": jsonResponse("A file with identical content was uploaded before; the existing asset is returned.", "AssetUploadResponse"),
 					"201": jsonResponse("Asset uploaded.", "AssetUploadResponse"),
 					"400": noContent("Invalid upload payload or missing file."),
 					"403": noContent("Missing or invalid bearer token."),
 					"413": noContent("Uploaded file exceeds the maximum allowed size."),
+					"415": noContent("The detected file type is not on the allow-list."),
+					"422": noContent("The file failed validation, e.g. a mismatched extension, embedded markup or oversized dimensions."),
 					"500": noContent("Failed to persist asset metadata."),
 					"502": noContent("Failed to upload asset to storage provider."),
 				},
 			},
 		},
+		"/v1/assets/presign": map[string]any{
+			"post": map[string]any{
+				"operationId": "presignAssetUpload",
+				"summary":     "Issue signed parameters for a direct-to-storage upload",
+				"description": "Lets the admin UI send a file straight to the storage backend instead of through the API. Confirm the upload afterwards to create the asset.",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("PresignAssetRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"201": jsonResponse("Signed upload parameters issued.", "PresignedUploadResponse"),
+					"400": noContent("Invalid payload, missing file name or malformed content type."),
+					"401": noContent("Missing or invalid bearer token."),
+					"415": noContent("The declared content type is not on the allow-list."),
+					"501": noContent("The configured asset backend does not support direct uploads."),
+					"502": noContent("Failed to sign the upload."),
+				},
+			},
+		},
+		"/v1/assets/confirm": map[string]any{
+			"post": map[string]any{
+				"operationId": "confirmAssetUpload",
+				"summary":     "Create an asset from a direct-to-storage upload",
+				"description": "Reads the object back from storage, validates and deduplicates it like any other upload, and records it. Rejected files and duplicates are deleted from storage.",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("ConfirmAssetRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("A file with identical content was uploaded before; the existing asset is returned and the new object deleted.", "AssetUploadResponse"),
+					"201": jsonResponse("Asset recorded.", "AssetUploadResponse"),
+					"400": noContent("Invalid payload."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("The key was not issued by the presign endpoint, has expired or was already confirmed."),
+					"409": noContent("The file has not been uploaded to storage yet."),
+					"413": noContent("The stored file exceeds the maximum upload size."),
+					"415": noContent("The detected file type is not on the allow-list."),
+					"422": noContent("The file failed validation."),
+					"500": noContent("Failed to persist asset metadata."),
+					"501": noContent("The configured asset backend does not support direct uploads."),
+					"502": noContent("Failed to read the object from the storage provider."),
+				},
+			},
+		},
+		"/v1/uploads": map[string]any{
+			"post": map[string]any{
+				"operationId": "createUpload",
+				"summary":     "Start a resumable chunked upload",
+				"description": "Creates an upload session for files too large for a single request. Send the file with PATCH requests to the returned Location, then complete the session to create the asset.",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("CreateUploadRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"201": jsonResponse("Upload session created. The Location header addresses the session.", "UploadSessionResponse"),
+					"400": noContent("Invalid payload, missing file name or non-positive length."),
+					"401": noContent("Missing or invalid bearer token."),
+					"413": noContent("The declared length exceeds the configured maximum."),
+					"500": noContent("Failed to create the session."),
+				},
+			},
+		},
+		"/v1/uploads/{id}": map[string]any{
+			"get": map[string]any{
+				"operationId": "getUpload",
+				"summary":     "Get the progress of a chunked upload",
+				"description": "Also answers HEAD requests. The Upload-Offset and Upload-Length headers report progress so that an interrupted client can resume.",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{uploadIDParam},
+				"responses": map[string]any{
+					"200": jsonResponse("Upload session retrieved.", "UploadSessionResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Unknown or expired upload session."),
+				},
+			},
+			"patch": map[string]any{
+				"operationId": "appendUpload",
+				"summary":     "Append a chunk to a chunked upload",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters": []map[string]any{
+					uploadIDParam,
+					{
+						"name":        "Upload-Offset",
+						"in":          "header",
+						"required":    true,
+						"description": "Offset the chunk starts at. Must equal the session's current offset.",
+						"schema":      map[string]any{"type": "integer", "format": "int64"},
+					},
+					{
+						"name":        "Upload-Checksum",
+						"in":          "header",
+						"required":    false,
+						"description": "Optional chunk digest as \"sha256 <base64>\" or \"sha1 <base64>\". Chunks with a checksum are stored all or nothing.",
+						"schema":      map[string]any{"type": "string"},
+					},
+				},
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/offset+octet-stream": map[string]any{
+							"schema": map[string]any{"type": "string", "format": "binary"},
+						},
+					},
+				},
+				"responses": map[string]any{
+					"204": noContent("Chunk stored. Upload-Offset holds the new offset."),
+					"400": noContent("Missing headers or the chunk was interrupted; bytes received without a checksum are kept."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Unknown or expired upload session."),
+					"409": noContent("The offset does not match, or another chunk is being received."),
+					"413": noContent("The chunk runs past the declared length."),
+					"415": noContent("The chunk was not sent as application/offset+octet-stream."),
+					"422": noContent("The chunk does not match its checksum and was discarded."),
+				},
+			},
+			"delete": map[string]any{
+				"operationId": "deleteUpload",
+				"summary":     "Abort a chunked upload",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{uploadIDParam},
+				"responses": map[string]any{
+					"204": noContent("Upload session and its data deleted."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Unknown or expired upload session."),
+					"409": noContent("A chunk is being received."),
+				},
+			},
+		},
+		"/v1/uploads/{id}/complete": map[string]any{
+			"post": map[string]any{
+				"operationId": "completeUpload",
+				"summary":     "Turn a fully received chunked upload into an asset",
+				"description": "Validates, deduplicates and stores the file exactly like a direct upload. The session is removed once the asset is stored or the file is rejected; storage failures leave it in place for a retry.",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{uploadIDParam},
+				"responses": map[string]any{
+					"200": jsonResponse("A file with identical content was uploaded before; the existing asset is returned.", "AssetUploadResponse"),
+					"201": jsonResponse("Asset uploaded.", "AssetUploadResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Unknown or expired upload session."),
+					"409": noContent("Not all bytes have been received yet."),
+					"415": noContent("The detected file type is not on the allow-list."),
+					"422": noContent("The file failed validation."),
+					"500": noContent("Failed to read the upload or persist asset metadata."),
+					"502": noContent("Failed to upload asset to storage provider."),
+				},
+			},
+		},
+		"/v1/audit": map[string]any{
+			"get": map[string]any{
+				"operationId": "listAuditEvents",
+				"summary":     "List admin changes",
+				"description": "Every successful admin mutation, newest first. Page through older events by passing the returned nextCursor as cursor.",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters": []map[string]any{
+					{
+						"name":        "resource",
+						"in":          "query",
+						"description": "Only return changes to this kind of resource.",
+						"schema":      map[string]any{"type": "string"},
+					},
+					{
+						"name":        "resourceId",
+						"in":          "query",
+						"description": "Only return changes to the resource with this identifier.",
+						"schema":      map[string]any{"type": "integer", "format": "int64"},
+					},
+					{
+						"name":        "actor",
+						"in":          "query",
+						"description": "Only return changes made by this actor.",
+						"schema":      map[string]any{"type": "string"},
+					},
+					{
+						"name":        "from",
+						"in":          "query",
+						"description": "Only return changes made at or after this time.",
+						"schema":      map[string]any{"type": "string", "format": "date-time"},
+					},
+					{
+						"name":        "to",
+						"in":          "query",
+						"description": "Only return changes made before this time.",
+						"schema":      map[string]any{"type": "string", "format": "date-time"},
+					},
+					{
+						"name":        "cursor",
+						"in":          "query",
+						"description": "Return events older than this identifier.",
+						"schema":      map[string]any{"type": "string"},
+					},
+					{
+						"name":        "limit",
+						"in":          "query",
+						"description": "Page size, 50 by default and at most 200.",
+						"schema":      map[string]any{"type": "integer"},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Audit events retrieved.", "AuditEventsResponse"),
+					"400": noContent("Invalid resource identifier, timestamp or cursor."),
+					"401": noContent("Missing or invalid bearer token."),
+					"500": noContent("Server error retrieving audit events."),
+				},
+			},
+		},
+		"/v1/webhooks": map[string]any{
+			"get": map[string]any{
+				"operationId": "listWebhooks",
+				"summary":     "List webhook subscriptions",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"responses": map[string]any{
+					"200": jsonResponse("Subscriptions and the available event types.", "WebhooksResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"500": noContent("Server error retrieving subscriptions."),
+				},
+			},
+			"post": map[string]any{
+				"operationId": "createWebhook",
+				"summary":     "Subscribe a URL to events",
+				"description": "Payloads are WebhookEvent objects. Each request carries X-Webhook-Event, X-Webhook-Timestamp (Unix seconds) and X-Webhook-Signature: sha256=<hex HMAC-SHA256 of \"<timestamp>.<body>\" keyed with the secret>.",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("CreateWebhookRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"201": jsonResponse("Subscription created. The response is the only time the secret is returned.", "WebhookResponse"),
+					"400": noContent("Invalid payload."),
+					"401": noContent("Missing or invalid bearer token."),
+					"422": noContent("Invalid URL or unknown event type."),
+					"500": noContent("Server error creating the subscription."),
+				},
+			},
+		},
+		"/v1/webhooks/{id}": map[string]any{
+			"get": map[string]any{
+				"operationId": "getWebhook",
+				"summary":     "Get a webhook subscription",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{intPathParam("id", "Identifier of the subscription.")},
+				"responses": map[string]any{
+					"200": jsonResponse("Subscription retrieved.", "WebhookResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Subscription not found."),
+					"500": noContent("Server error retrieving the subscription."),
+				},
+			},
+			"put": map[string]any{
+				"operationId": "updateWebhook",
+				"summary":     "Update a webhook subscription",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{intPathParam("id", "Identifier of the subscription.")},
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("UpdateWebhookRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Subscription updated. Includes the new secret when it was rotated.", "WebhookResponse"),
+					"400": noContent("Invalid payload."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Subscription not found."),
+					"422": noContent("Invalid URL or unknown event type."),
+					"500": noContent("Server error updating the subscription."),
+				},
+			},
+			"delete": map[string]any{
+				"operationId": "deleteWebhook",
+				"summary":     "Delete a webhook subscription and its deliveries",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{intPathParam("id", "Identifier of the subscription.")},
+				"responses": map[string]any{
+					"204": noContent("Subscription deleted."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Subscription not found."),
+					"500": noContent("Server error deleting the subscription."),
+				},
+			},
+		},
+		"/v1/webhook-deliveries": map[string]any{
+			"get": map[string]any{
+				"operationId": "listWebhookDeliveries",
+				"summary":     "List webhook deliveries",
+				"description": "Covers both deploy webhook and subscription deliveries, newest first. Page through older deliveries by passing the returned nextCursor as cursor.",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters": []map[string]any{
+					{
+						"name":        "status",
+						"in":          "query",
+						"description": "Only return deliveries in this state.",
+						"schema": map[string]any{
+							"type": "string",
+							"enum": []string{"pending", "delivering", "delivered", "failed", "superseded"},
+						},
+					},
+					{
+						"name":        "subscriptionId",
+						"in":          "query",
+						"description": "Only return deliveries for this subscription.",
+						"schema":      map[string]any{"type": "integer", "format": "int64"},
+					},
+					{
+						"name":        "cursor",
+						"in":          "query",
+						"description": "Return deliveries older than this identifier.",
+						"schema":      map[string]any{"type": "string"},
+					},
+					{
+						"name":        "limit",
+						"in":          "query",
+						"description": "Page size, 50 by default and at most 200.",
+						"schema":      map[string]any{"type": "integer"},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Deliveries retrieved.", "WebhookDeliveriesResponse"),
+					"400": noContent("Unknown status, invalid subscription or invalid cursor."),
+					"401": noContent("Missing or invalid bearer token."),
+					"500": noContent("Server error retrieving deliveries."),
+				},
+			},
+		},
+		"/v1/webhook-deliveries/{id}": map[string]any{
+			"get": map[string]any{
+				"operationId": "getWebhookDelivery",
+				"summary":     "Get a deploy webhook delivery and its attempts",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{intPathParam("id", "Identifier of the delivery.")},
+				"responses": map[string]any{
+					"200": jsonResponse("Delivery retrieved.", "WebhookDeliveryResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Delivery not found."),
+					"500": noContent("Server error retrieving the delivery."),
+				},
+			},
+		},
+		"/v1/webhook-deliveries/{id}/replay": map[string]any{
+			"post": map[string]any{
+				"operationId": "replayWebhookDelivery",
+				"summary":     "Replay a failed webhook delivery",
+				"description": "Queues a copy of the delivery, due immediately, to the subscription's current URL or the configured deploy webhook. If a deploy delivery is already pending it is brought forward and returned instead.",
+				"tags":        []string{"Administration"},
+				"security":    bearerSecurity,
+				"parameters":  []map[string]any{intPathParam("id", "Identifier of the failed delivery.")},
+				"responses": map[string]any{
+					"202": jsonResponse("Delivery queued.", "WebhookDeliveryResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Delivery not found."),
+					"409": noContent("The delivery has not failed."),
+					"500": noContent("Server error queueing the delivery."),
+					"503": noContent("The deploy webhook is not configured."),
+				},
+			},
+		},
+		"/v1/assets/duplicates": map[string]any{
+			"get": map[string]any{
+				"operationId": "listAssetDuplicates",
+				"summary":     "Report likely duplicate assets",
+				"description": "Groups assets that share a file size, format and dimensions. New uploads are deduplicated by content hash, so the report mainly surfaces copies created before hashing was introduced.",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"responses": map[string]any{
+					"200": jsonResponse("Duplicate groups retrieved.", "AssetDuplicatesResponse"),
+					"401": noContent("Missing or invalid bearer token."),
+					"500": noContent("Server error retrieving assets."),
+				},
+			},
+		},
+		"/v1/{contentType}/{id}/assets": map[string]any{
+			"get": map[string]any{
+				"operationId": "listAssetLinks",
+				"summary":     "List the gallery of a note, project or role",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  galleryParams,
+				"responses": map[string]any{
+					"200": jsonResponse("Gallery retrieved.", "AssetLinksResponse"),
+					"400": noContent("Invalid content type or identifier."),
+					"401": noContent("Missing or invalid bearer token."),
+					"500": noContent("Server error retrieving the gallery."),
+				},
+			},
+			"post": map[string]any{
+				"operationId": "attachAsset",
+				"summary":     "Attach an asset to a gallery",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  galleryParams,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("CreateAssetLinkRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"201": jsonResponse("Asset attached.", "AssetLinkResponse"),
+					"400": noContent("Invalid payload, content type or identifier."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("Asset or item not found."),
+					"409": noContent("The asset is already in the gallery."),
+					"500": noContent("Server error attaching the asset."),
+				},
+			},
+		},
+		"/v1/{contentType}/{id}/assets/order": map[string]any{
+			"put": map[string]any{
+				"operationId": "reorderAssetLinks",
+				"summary":     "Reorder a gallery",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  galleryParams,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("ReorderAssetLinksRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Gallery reordered.", "AssetLinksResponse"),
+					"400": noContent("Invalid payload, content type or identifier."),
+					"401": noContent("Missing or invalid bearer token."),
+					"422": noContent("The order does not list every attached asset exactly once."),
+					"500": noContent("Server error reordering the gallery."),
+				},
+			},
+		},
+		"/v1/{contentType}/{id}/assets/{assetId}": map[string]any{
+			"put": map[string]any{
+				"operationId": "updateAssetLink",
+				"summary":     "Update the caption and alt text of an attached asset",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  galleryAssetParams,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": ref("UpdateAssetLinkRequest"),
+						},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Attached asset updated.", "AssetLinkResponse"),
+					"400": noContent("Invalid payload, content type or identifier."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("The asset is not attached to the item."),
+					"500": noContent("Server error updating the attached asset."),
+				},
+			},
+			"delete": map[string]any{
+				"operationId": "detachAsset",
+				"summary":     "Detach an asset from a gallery",
+				"tags":        []string{"Assets"},
+				"security":    bearerSecurity,
+				"parameters":  galleryAssetParams,
+				"responses": map[string]any{
+					"204": noContent("Asset detached."),
+					"400": noContent("Invalid content type or identifier."),
+					"401": noContent("Missing or invalid bearer token."),
+					"404": noContent("The asset is not attached to the item."),
+					"500": noContent("Server error detaching the asset."),
+				},
+			},
+		},
 		"/v1/roles": map[string]any{
 			"get": map[string]any{
 				"operationId": "listRoles",
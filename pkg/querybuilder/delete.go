@@ -1,6 +1,7 @@
 package querybuilder
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -12,6 +13,15 @@ type DeleteQueryBuilder struct {
 	conditions   Clauses
 }
 
+func (q *DeleteQueryBuilder) buildPreparedStatementValues() []interface{} {
+	values := q.queryBuilder.buildCommonTableExpressionParameters()
+	return append(values, q.queryBuilder.buildParameters(q.conditions)...)
+}
+
+func (q *DeleteQueryBuilder) base() *QueryBuilder {
+	return q.queryBuilder
+}
+
 func (q *DeleteQueryBuilder) buildQuery() (*string, error) {
 	if len(q.conditions) == 0 {
 		err := errors.New("Incorrectly formatted query. Ensure fields are set")
@@ -23,7 +33,20 @@ func (q *DeleteQueryBuilder) buildQuery() (*string, error) {
 		return nil, err
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s", q.table)
+	if err := validateTable(q.table); err != nil {
+		return nil, err
+	}
+
+	if err := validatePredicates(q.conditions); err != nil {
+		return nil, err
+	}
+
+	commonTableExpressions, err := q.queryBuilder.buildCommonTableExpressions()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("%sDELETE FROM %s", commonTableExpressions, q.table)
 	query += q.queryBuilder.buildConditionalStatement(q.conditions)
 
 	return &query, nil
@@ -38,12 +61,47 @@ func (q *DeleteQueryBuilder) WhereEqual(column string, value interface{}) *Delet
 	return q
 }
 
+// Where adds a predicate, such as an Or group, to the conditions.
+func (q *DeleteQueryBuilder) Where(predicate Predicate) *DeleteQueryBuilder {
+	q.queryBuilder.addPredicate(predicate, &q.conditions)
+	return q
+}
+
+func (q *DeleteQueryBuilder) WhereIn(column string, values ...interface{}) *DeleteQueryBuilder {
+	return q.Where(In(column, values...))
+}
+
+func (q *DeleteQueryBuilder) WhereNotIn(column string, values ...interface{}) *DeleteQueryBuilder {
+	return q.Where(NotIn(column, values...))
+}
+
+func (q *DeleteQueryBuilder) WhereLike(column string, pattern string) *DeleteQueryBuilder {
+	return q.Where(Like(column, pattern))
+}
+
+func (q *DeleteQueryBuilder) WhereILike(column string, pattern string) *DeleteQueryBuilder {
+	return q.Where(ILike(column, pattern))
+}
+
+func (q *DeleteQueryBuilder) WhereBetween(column string, low, high interface{}) *DeleteQueryBuilder {
+	return q.Where(Between(column, low, high))
+}
+
+// WhereRaw adds a hand-written predicate; see Raw.
+func (q *DeleteQueryBuilder) WhereRaw(sql string, values ...interface{}) *DeleteQueryBuilder {
+	return q.Where(Raw(sql, values...))
+}
+
 func (q *DeleteQueryBuilder) Exec() (sql.Result, error) {
+	return q.ExecContext(q.queryBuilder.Context())
+}
+
+func (q *DeleteQueryBuilder) ExecContext(ctx context.Context) (sql.Result, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 
-	values := q.queryBuilder.buildParameters(q.conditions)
-	return q.queryBuilder.DB.Exec(*query, values...)
+	values := q.buildPreparedStatementValues()
+	return q.queryBuilder.Executor().ExecContext(ctx, *query, values...)
 }
@@ -49,3 +49,24 @@ func TestDeleteQueryBuilder_NoTable(t *testing.T) {
 		t.Error("Expected error for missing table, got nil")
 	}
 }
+
+func TestDeleteQueryBuilder_WhereOrGroup(t *testing.T) {
+	qb := QueryBuilder{}
+
+	deleteQB := qb.SetBaseTable("sessions").Delete().Where(Or(LessThan("expiresAt", "2024-01-01"), Equal("revoked", true))).WhereLike("userAgent", "bot%")
+
+	query, err := deleteQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedQuery := "DELETE FROM sessions WHERE (expiresAt < $1 OR revoked = $2) AND userAgent LIKE $3"
+	if *query != expectedQuery {
+		t.Errorf("Expected query to be '%s', got '%s'", expectedQuery, *query)
+	}
+
+	expectedValues := []interface{}{"2024-01-01", true, "bot%"}
+	if values := qb.buildParameters(deleteQB.conditions); !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("Expected values %v, got %v", expectedValues, values)
+	}
+}
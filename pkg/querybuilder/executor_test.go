@@ -0,0 +1,63 @@
+package querybuilder
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQueryBuilder_RunsInsideTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO notes \\(title\\) VALUES \\(\\$1\\)").
+		WithArgs("Hello").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("unexpected error beginning transaction: %s", err)
+	}
+
+	qb := QueryBuilder{DB: tx}
+	if _, err := qb.SetBaseTable("notes").Insert(Clauses{{ColumnName: "title", Value: "Hello"}}).Exec(); err != nil {
+		t.Fatalf("unexpected error inserting: %s", err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("unexpected error rolling back: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestQueryBuilder_WithContextIsUsedByQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id FROM notes").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	qb := (&QueryBuilder{DB: db}).WithContext(ctx)
+	if _, err := qb.SetBaseTable("notes").Select("id").Query(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled context to stop the query, got %v", err)
+	}
+
+	if (&QueryBuilder{}).Context() != context.Background() {
+		t.Fatalf("expected builders without a context to use context.Background")
+	}
+}
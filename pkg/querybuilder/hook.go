@@ -0,0 +1,172 @@
+package querybuilder
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Redacted stands in for Sensitive values in query events.
+const Redacted = "[REDACTED]"
+
+// QueryEvent describes a statement once it has run.
+type QueryEvent struct {
+	SQL string
+	// Args holds the statement's arguments, with Sensitive values replaced by Redacted.
+	Args     []any
+	Duration time.Duration
+	// RowsAffected is only known for Exec; it is -1 for queries, whose rows are read later.
+	RowsAffected int64
+	Err          error
+}
+
+// Hook is told about every statement run through a QueryBuilder or an Instrument-ed Executor.
+// Hooks run synchronously on the calling goroutine, so they should be quick.
+type Hook interface {
+	AfterQuery(ctx context.Context, event QueryEvent)
+}
+
+// HookFunc adapts a function to the Hook interface.
+type HookFunc func(ctx context.Context, event QueryEvent)
+
+func (f HookFunc) AfterQuery(ctx context.Context, event QueryEvent) {
+	f(ctx, event)
+}
+
+type sensitive struct {
+	value any
+}
+
+// Sensitive marks an argument, such as a signing secret, that hooks must not see. The database
+// still receives the value itself.
+func Sensitive(value any) driver.Valuer {
+	return sensitive{value: value}
+}
+
+func (s sensitive) Value() (driver.Value, error) {
+	if valuer, ok := s.value.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(s.value)
+}
+
+func redact(args []any) []any {
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		if _, ok := arg.(sensitive); ok {
+			redacted[i] = Redacted
+			continue
+		}
+		redacted[i] = arg
+	}
+	return redacted
+}
+
+type instrumentedExecutor struct {
+	db    Executor
+	hooks []Hook
+}
+
+// Instrument wraps db so that every statement run through it is reported to hooks. It is for
+// hand-written SQL; builders report to their QueryBuilder's Hooks themselves.
+func Instrument(db Executor, hooks ...Hook) Executor {
+	if len(hooks) == 0 {
+		return db
+	}
+	return instrumentedExecutor{db: db, hooks: hooks}
+}
+
+func (e instrumentedExecutor) report(ctx context.Context, query string, args []any, start time.Time, rowsAffected int64, err error) {
+	event := QueryEvent{
+		SQL:          query,
+		Args:         redact(args),
+		Duration:     time.Since(start),
+		RowsAffected: rowsAffected,
+		Err:          err,
+	}
+
+	for _, hook := range e.hooks {
+		hook.AfterQuery(ctx, event)
+	}
+}
+
+func (e instrumentedExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := e.db.ExecContext(ctx, query, args...)
+
+	rowsAffected := int64(-1)
+	if err == nil {
+		if affected, err := result.RowsAffected(); err == nil {
+			rowsAffected = affected
+		}
+	}
+
+	e.report(ctx, query, args, start, rowsAffected, err)
+	return result, err
+}
+
+func (e instrumentedExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	e.report(ctx, query, args, start, -1, err)
+	return rows, err
+}
+
+func (e instrumentedExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := e.db.QueryRowContext(ctx, query, args...)
+	e.report(ctx, query, args, start, -1, row.Err())
+	return row
+}
+
+// SlowQueryLogger returns a hook that passes statements taking at least threshold to logf,
+// along with their arguments and any error. A threshold of zero logs every statement.
+func SlowQueryLogger(threshold time.Duration, logf func(ctx context.Context, format string, v ...any)) Hook {
+	return HookFunc(func(ctx context.Context, event QueryEvent) {
+		if event.Duration < threshold {
+			return
+		}
+
+		line := fmt.Sprintf("query took %s: %s %v", event.Duration.Round(time.Microsecond), event.SQL, event.Args)
+		if event.Err != nil {
+			line += fmt.Sprintf(" failed: %s", event.Err)
+		}
+		logf(ctx, "%s", line)
+	})
+}
+
+// QueryStats counts the statements run with a context from WithQueryStats.
+type QueryStats struct {
+	queries  atomic.Int64
+	duration atomic.Int64
+}
+
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context that collects QueryStats for the statements run with it, or
+// with contexts derived from it, when CountQueries is among the hooks.
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+// Queries reports how many statements have run.
+func (s *QueryStats) Queries() int64 {
+	return s.queries.Load()
+}
+
+// Duration reports the time spent running statements.
+func (s *QueryStats) Duration() time.Duration {
+	return time.Duration(s.duration.Load())
+}
+
+// CountQueries is a hook that adds each statement to the QueryStats of its context, if any.
+var CountQueries Hook = HookFunc(func(ctx context.Context, event QueryEvent) {
+	if stats, ok := ctx.Value(queryStatsKey{}).(*QueryStats); ok {
+		stats.queries.Add(1)
+		stats.duration.Add(int64(event.Duration))
+	}
+})
@@ -0,0 +1,130 @@
+package querybuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type recordedHook struct {
+	events []QueryEvent
+}
+
+func (h *recordedHook) AfterQuery(ctx context.Context, event QueryEvent) {
+	h.events = append(h.events, event)
+}
+
+func TestHooks_ReportBuilderStatements(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET secret = $1, name = $2 WHERE id = $3")).
+		WithArgs("hunter2", "Alice", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE name = $1")).
+		WithArgs("Bob").
+		WillReturnError(errors.New("connection reset"))
+
+	hook := &recordedHook{}
+	qb := QueryBuilder{DB: db, Hooks: []Hook{hook}}
+
+	values := Clauses{{ColumnName: "secret", Value: Sensitive("hunter2")}, {ColumnName: "name", Value: "Alice"}}
+	if _, err := qb.SetBaseTable("users").Update(values).WhereEqual("id", 7).Exec(); err != nil {
+		t.Fatalf("unexpected error updating: %s", err)
+	}
+
+	if _, err := qb.SetBaseTable("users").Select("id").WhereEqual("name", "Bob").Query(); err == nil {
+		t.Fatalf("expected the query to fail")
+	}
+
+	if len(hook.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(hook.events))
+	}
+
+	update := hook.events[0]
+	if update.SQL != "UPDATE users SET secret = $1, name = $2 WHERE id = $3" || update.RowsAffected != 1 || update.Err != nil {
+		t.Errorf("unexpected update event %+v", update)
+	}
+	if expected := []any{Redacted, "Alice", 7}; !reflect.DeepEqual(update.Args, expected) {
+		t.Errorf("expected args %v, got %v", expected, update.Args)
+	}
+
+	query := hook.events[1]
+	if query.RowsAffected != -1 || query.Err == nil || query.Err.Error() != "connection reset" {
+		t.Errorf("unexpected query event %+v", query)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestInstrument_ReportsHandWrittenSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"one"}).AddRow(1))
+
+	hook := &recordedHook{}
+	if Instrument(db) != Executor(db) {
+		t.Fatalf("expected Instrument without hooks to return db unchanged")
+	}
+
+	var one int
+	if err := Instrument(db, hook).QueryRowContext(context.Background(), "SELECT 1").Scan(&one); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(hook.events) != 1 || hook.events[0].SQL != "SELECT 1" || hook.events[0].Duration <= 0 {
+		t.Fatalf("unexpected events %+v", hook.events)
+	}
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	var lines []string
+	logf := func(ctx context.Context, format string, v ...any) {
+		lines = append(lines, fmt.Sprintf(format, v...))
+	}
+
+	hook := SlowQueryLogger(100*time.Millisecond, logf)
+	hook.AfterQuery(context.Background(), QueryEvent{SQL: "SELECT 1", Duration: 20 * time.Millisecond})
+	hook.AfterQuery(context.Background(), QueryEvent{SQL: "SELECT pg_sleep($1)", Args: []any{1}, Duration: 1500 * time.Millisecond, Err: errors.New("canceled")})
+
+	if len(lines) != 1 {
+		t.Fatalf("expected only the slow query to be logged, got %v", lines)
+	}
+
+	if expected := "query took 1.5s: SELECT pg_sleep($1) [1] failed: canceled"; lines[0] != expected {
+		t.Fatalf("expected %q, got %q", expected, lines[0])
+	}
+
+	SlowQueryLogger(0, logf).AfterQuery(context.Background(), QueryEvent{SQL: "SELECT 1"})
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "query took 0s: SELECT 1") {
+		t.Fatalf("expected a zero threshold to log every query, got %v", lines)
+	}
+}
+
+func TestCountQueries(t *testing.T) {
+	ctx, stats := WithQueryStats(context.Background())
+
+	CountQueries.AfterQuery(ctx, QueryEvent{Duration: 2 * time.Millisecond})
+	CountQueries.AfterQuery(ctx, QueryEvent{Duration: 3 * time.Millisecond})
+	CountQueries.AfterQuery(context.Background(), QueryEvent{Duration: time.Second})
+
+	if stats.Queries() != 2 || stats.Duration() != 5*time.Millisecond {
+		t.Fatalf("expected 2 queries in 5ms, got %d in %s", stats.Queries(), stats.Duration())
+	}
+}
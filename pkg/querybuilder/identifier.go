@@ -0,0 +1,158 @@
+package querybuilder
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidIdentifier is returned when a table, column or alias name is not a plain or quoted
+// SQL identifier, and so cannot safely be written into a statement.
+var ErrInvalidIdentifier = errors.New("invalid SQL identifier")
+
+// ErrInvalidSortDirection is returned for sort directions other than ASC or DESC.
+var ErrInvalidSortDirection = errors.New("sort direction must be ASC or DESC")
+
+// ErrInvalidSort is returned when a sort parameter names a key that is not allowed.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// identifierPart matches an unquoted identifier, or a double-quoted one in which any embedded
+// quotes are doubled.
+const identifierPart = `(?:[A-Za-z_][A-Za-z0-9_]*|"(?:[^"\x00]|"")+")`
+
+var identifierPattern = regexp.MustCompile(`^` + identifierPart + `(?:\.` + identifierPart + `)*$`)
+
+// ValidateIdentifier checks that name is an identifier, optionally qualified as in
+// "notes.publishedAt".
+func ValidateIdentifier(name string) error {
+	if !identifierPattern.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+	}
+	return nil
+}
+
+// QuoteIdentifier quotes name so that it is read as a single identifier whatever it contains.
+// Quoted identifiers are case sensitive, unlike the unquoted names used throughout the schema.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(name, "\x00", ""), `"`, `""`) + `"`
+}
+
+// validateTable checks a table reference, which may carry an alias as in "companies AS c" or
+// "companies c".
+func validateTable(table string) error {
+	parts := strings.Fields(table)
+	if len(parts) == 3 && strings.EqualFold(parts[1], "AS") {
+		parts = []string{parts[0], parts[2]}
+	}
+	if len(parts) == 0 || len(parts) > 2 || (len(parts) == 2 && strings.EqualFold(parts[1], "AS")) {
+		return fmt.Errorf("%w: %q", ErrInvalidIdentifier, table)
+	}
+
+	for _, part := range parts {
+		if err := ValidateIdentifier(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSortDirection(direction string) error {
+	if !strings.EqualFold(direction, "ASC") && !strings.EqualFold(direction, "DESC") {
+		return fmt.Errorf("%w, got %q", ErrInvalidSortDirection, direction)
+	}
+	return nil
+}
+
+func validateColumns(values Clauses) error {
+	for _, value := range values {
+		if err := ValidateIdentifier(value.ColumnName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateReturning checks the columns of a RETURNING clause, which may also be "*".
+func validateReturning(fields []string) error {
+	for _, field := range fields {
+		if field == "*" {
+			continue
+		}
+		if err := ValidateIdentifier(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePredicates checks every column named by conditions. The SQL of raw predicates is
+// trusted, but their placeholders must still match their values.
+func validatePredicates(conditions Clauses) error {
+	for _, clause := range conditions {
+		predicate := clausePredicate(clause)
+		for _, column := range predicate.columns() {
+			if err := ValidateIdentifier(column); err != nil {
+				return err
+			}
+		}
+		if err := validatePlaceholders(predicate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePlaceholders checks the raw predicates within predicate. A raw predicate with too few
+// or too many values would bind every later argument of the statement to the wrong placeholder.
+func validatePlaceholders(predicate Predicate) error {
+	switch p := predicate.(type) {
+	case raw:
+		return p.validate()
+	case group:
+		for _, nested := range p.predicates {
+			if err := validatePlaceholders(nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sort is one column of a sort order parsed from request input by SortColumns.Parse.
+type Sort struct {
+	column    string
+	direction string
+}
+
+// SortColumns maps the keys clients may sort by to the columns or expressions they stand for.
+// Only the map's values are ever written into a statement, so request input never reaches the
+// SQL directly.
+type SortColumns map[string]string
+
+// Parse reads a comma-separated sort parameter such as "-publishedAt,title", where a leading
+// "-" sorts that key in descending order. Keys missing from the allow-list are rejected.
+func (s SortColumns) Parse(param string) ([]Sort, error) {
+	sorts := []Sort{}
+	if strings.TrimSpace(param) == "" {
+		return sorts, nil
+	}
+
+	for _, key := range strings.Split(param, ",") {
+		key = strings.TrimSpace(key)
+
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+			key = key[1:]
+		}
+
+		column, ok := s[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, key)
+		}
+		sorts = append(sorts, Sort{column: column, direction: direction})
+	}
+
+	return sorts, nil
+}
@@ -0,0 +1,144 @@
+package querybuilder
+
+import (
+	"errors"
+	"testing"
+)
+
+var maliciousIdentifiers = []string{
+	"id; DROP TABLE users",
+	"id--",
+	"name DESC, (SELECT password FROM users)",
+	"users WHERE 1=1",
+	`"id"" OR 1=1 --`,
+	"1id",
+	"id)",
+	"",
+}
+
+func TestValidateIdentifier(t *testing.T) {
+	for _, name := range []string{"id", "createdAt", "notes.publishedAt", "_private", `"Odd Name"`, `"with ""quotes"""`} {
+		if err := ValidateIdentifier(name); err != nil {
+			t.Errorf("Expected %q to be a valid identifier, got %s", name, err)
+		}
+	}
+
+	for _, name := range maliciousIdentifiers {
+		if err := ValidateIdentifier(name); !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	quoted := QuoteIdentifier(`weird"; DROP TABLE users; --`)
+	if quoted != `"weird""; DROP TABLE users; --"` {
+		t.Fatalf("Unexpected quoted identifier %s", quoted)
+	}
+
+	if err := ValidateIdentifier(quoted); err != nil {
+		t.Fatalf("Expected a quoted identifier to validate, got %s", err)
+	}
+}
+
+func TestBuilders_RejectMaliciousIdentifiers(t *testing.T) {
+	for _, name := range maliciousIdentifiers {
+		qb := QueryBuilder{}
+
+		builders := map[string]interface{ buildQuery() (*string, error) }{
+			"select table":     qb.Select("id").From(name),
+			"select join":      qb.Select("id").From("users").LeftJoin(name, "id", "userId"),
+			"join own key":     qb.Select("id").From("users").LeftJoin("roles", name, "userId"),
+			"join foreign key": qb.Select("id").From("users").InnerJoin("roles", "id", name),
+			"order by column":  qb.Select("id").From("users").OrderBy(name, "ASC"),
+			"group by column":  qb.Select("id").From("users").GroupBy(name),
+			"where column":     qb.Select("id").From("users").WhereEqual(name, 1),
+			"where null":       qb.Select("id").From("users").WhereEqual(name, nil),
+			"where in":         qb.Select("id").From("users").WhereIn(name, 1, 2),
+			"where between":    qb.Select("id").From("users").WhereBetween(name, 1, 2),
+			"where group":      qb.Select("id").From("users").Where(Or(Equal("id", 1), ILike(name, "%x%"))),
+			"update where":     qb.SetBaseTable("users").Update(Clauses{{ColumnName: "name", Value: "x"}}).WhereIn(name, 1),
+			"delete where":     qb.SetBaseTable("users").Delete().WhereNotIn(name, 1),
+			"update table":     qb.SetBaseTable(name).Update(Clauses{{ColumnName: "name", Value: "x"}}).WhereEqual("id", 1),
+			"update column":    qb.SetBaseTable("users").Update(Clauses{{ColumnName: name, Value: "x"}}).WhereEqual("id", 1),
+			"update returning": qb.SetBaseTable("users").Update(Clauses{{ColumnName: "name", Value: "x"}}).Returning("id", name),
+			"insert table":     qb.SetBaseTable(name).Insert(Clauses{{ColumnName: "name", Value: "x"}}),
+			"insert column":    qb.SetBaseTable("users").Insert(Clauses{{ColumnName: name, Value: "x"}}),
+			"insert returning": qb.SetBaseTable("users").Insert(Clauses{{ColumnName: "name", Value: "x"}}).Returning(name),
+			"delete table":     qb.SetBaseTable(name).Delete().WhereEqual("id", 1),
+			"cte name":         qb.With(qb.SetBaseTable("users").Select("id"), name).SetBaseTable("users").Select("id"),
+		}
+
+		for builder, query := range builders {
+			if _, err := query.buildQuery(); err == nil {
+				t.Errorf("%s: expected %q to be rejected", builder, name)
+			}
+		}
+	}
+}
+
+func TestSelectQueryBuilder_TableAliases(t *testing.T) {
+	for _, table := range []string{"roles AS r", "roles as r", "roles r"} {
+		qb := QueryBuilder{}
+		if _, err := qb.Select("r.id").From(table).buildQuery(); err != nil {
+			t.Errorf("Expected %q to be accepted, got %s", table, err)
+		}
+	}
+
+	for _, table := range []string{"roles AS r extra", "roles AS", "roles AS r; --"} {
+		qb := QueryBuilder{}
+		if _, err := qb.Select("r.id").From(table).buildQuery(); !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("Expected %q to be rejected, got %v", table, err)
+		}
+	}
+}
+
+func TestSelectQueryBuilder_SortDirection(t *testing.T) {
+	for _, direction := range []string{"ASC", "desc", "Desc"} {
+		qb := QueryBuilder{}
+		if _, err := qb.Select("id").From("users").OrderBy("id", direction).buildQuery(); err != nil {
+			t.Errorf("Expected %q to be accepted, got %s", direction, err)
+		}
+	}
+
+	for _, direction := range []string{"", "DESC; DROP TABLE users", "ASC NULLS FIRST", "1"} {
+		qb := QueryBuilder{}
+		query, err := qb.Select("id").From("users").OrderByExpression("COALESCE(a, b)", direction).buildQuery()
+		if query != nil || !errors.Is(err, ErrInvalidSortDirection) {
+			t.Errorf("Expected %q to be rejected, got %v", direction, err)
+		}
+	}
+}
+
+func TestSortColumns_Parse(t *testing.T) {
+	allowed := SortColumns{
+		"publishedAt": "COALESCE(publishedAt, createdAt)",
+		"title":       "title",
+	}
+
+	sorts, err := allowed.Parse("-publishedAt, title")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing sort, got %s", err)
+	}
+
+	qb := QueryBuilder{}
+	query, err := qb.Select("id").From("notes").OrderBySorts(sorts...).buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error when building select query, got %s", err)
+	}
+
+	expected := "SELECT id FROM notes ORDER BY COALESCE(publishedAt, createdAt) DESC, title ASC"
+	if *query != expected {
+		t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", expected, *query)
+	}
+
+	for _, param := range []string{"body", "title;DROP TABLE notes", "-", "title,", "--title"} {
+		if _, err := allowed.Parse(param); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("Expected %q to be rejected, got %v", param, err)
+		}
+	}
+
+	if sorts, err := allowed.Parse(""); err != nil || len(sorts) != 0 {
+		t.Fatalf("Expected an empty sort to parse to nothing, got %v %v", sorts, err)
+	}
+}
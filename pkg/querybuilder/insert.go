@@ -1,42 +1,98 @@
 package querybuilder
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 type InsertQueryBuilder struct {
-	queryBuilder *QueryBuilder
-	table        string
-	values       Clauses
-	fields       []string
+	queryBuilder    *QueryBuilder
+	table           string
+	rows            []Clauses
+	fields          []string
+	conflictColumns []string
+	conflictAction  string
+	updateColumns   []string
+}
+
+func (q *InsertQueryBuilder) base() *QueryBuilder {
+	return q.queryBuilder
 }
 
 func (q *InsertQueryBuilder) buildPreparedStatementValues() []interface{} {
 	values := q.queryBuilder.buildCommonTableExpressionParameters()
-	values = append(values, q.queryBuilder.buildParameters(q.values)...)
+	for _, row := range q.rows {
+		values = append(values, q.queryBuilder.buildParameters(row)...)
+	}
 
 	return values
 }
 
 func (q *InsertQueryBuilder) buildColumnNameStatement() string {
-	stmt := ""
+	columns := make([]string, len(q.rows[0]))
+	for i, column := range q.rows[0] {
+		columns[i] = column.ColumnName
+	}
 
-	if len(q.values) != 0 {
-		for i, column := range q.values {
-			if i > 0 {
-				stmt += ", "
+	return strings.Join(columns, ", ")
+}
+
+// validateRows checks that every row sets the same columns, in the same order, as the first.
+func (q *InsertQueryBuilder) validateRows() error {
+	for _, row := range q.rows {
+		if len(row) != len(q.rows[0]) {
+			return errors.New("Incorrectly formatted query. Ensure every row sets the same columns")
+		}
+		for i, column := range row {
+			if column.ColumnName != q.rows[0][i].ColumnName {
+				return errors.New("Incorrectly formatted query. Ensure every row sets the same columns")
 			}
-			stmt += fmt.Sprintf("%s", column.ColumnName)
 		}
 	}
 
-	return stmt
+	return validateColumns(q.rows[0])
+}
+
+func (q *InsertQueryBuilder) buildConflictStatement() (string, error) {
+	if q.conflictAction == "" {
+		if len(q.conflictColumns) > 0 {
+			return "", errors.New("Incorrectly formatted query. ON CONFLICT needs DoNothing or DoUpdate")
+		}
+		return "", nil
+	}
+
+	for _, column := range append(q.conflictColumns, q.updateColumns...) {
+		if err := ValidateIdentifier(column); err != nil {
+			return "", err
+		}
+	}
+
+	stmt := " ON CONFLICT"
+	if len(q.conflictColumns) > 0 {
+		stmt += fmt.Sprintf(" (%s)", strings.Join(q.conflictColumns, ", "))
+	}
+
+	if q.conflictAction == "NOTHING" {
+		return stmt + " DO NOTHING", nil
+	}
+
+	if len(q.conflictColumns) == 0 || len(q.updateColumns) == 0 {
+		return "", errors.New("Incorrectly formatted query. DoUpdate needs conflict columns and columns to update")
+	}
+
+	assignments := make([]string, len(q.updateColumns))
+	for i, column := range q.updateColumns {
+		assignments[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	return stmt + " DO UPDATE SET " + strings.Join(assignments, ", "), nil
 }
 
 func (q *InsertQueryBuilder) buildQuery() (*string, error) {
-	if len(q.values) == 0 {
+	if len(q.rows) == 0 || len(q.rows[0]) == 0 {
 		err := errors.New("Incorrectly formatted query. Ensure fields are set")
 		return nil, err
 	}
@@ -45,9 +101,37 @@ func (q *InsertQueryBuilder) buildQuery() (*string, error) {
 		return nil, err
 	}
 
+	if err := validateTable(q.table); err != nil {
+		return nil, err
+	}
+
+	if err := q.validateRows(); err != nil {
+		return nil, err
+	}
+
+	if err := validateReturning(q.fields); err != nil {
+		return nil, err
+	}
+
+	commonTableExpressions, err := q.queryBuilder.buildCommonTableExpressions()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]string, len(q.rows))
+	for i, row := range q.rows {
+		rows[i] = fmt.Sprintf("(%s)", q.queryBuilder.buildValuesStatement(row))
+	}
+
 	columnNames := q.buildColumnNameStatement()
-	columnValues := q.queryBuilder.buildValuesStatement(q.values)
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", q.table, columnNames, columnValues)
+	query := fmt.Sprintf("%sINSERT INTO %s (%s) VALUES %s", commonTableExpressions, q.table, columnNames, strings.Join(rows, ", "))
+
+	conflict, err := q.buildConflictStatement()
+	if err != nil {
+		return nil, err
+	}
+	query += conflict
+
 	if len(q.fields) > 0 {
 		returnedColumns := q.queryBuilder.buildReturnedColumns(q.fields)
 		query += fmt.Sprintf(" RETURNING %s", returnedColumns)
@@ -56,36 +140,71 @@ func (q *InsertQueryBuilder) buildQuery() (*string, error) {
 	return &query, nil
 }
 
+// Returning lists the columns to return for each inserted row. Rows skipped by DoNothing are
+// not returned.
 func (q *InsertQueryBuilder) Returning(fields ...string) *InsertQueryBuilder {
 	q.fields = fields
 	return q
 }
 
+// OnConflict names the columns of the unique constraint that DoNothing or DoUpdate reacts to.
+func (q *InsertQueryBuilder) OnConflict(columns ...string) *InsertQueryBuilder {
+	q.conflictColumns = columns
+	return q
+}
+
+// DoNothing skips rows that conflict with an existing one.
+func (q *InsertQueryBuilder) DoNothing() *InsertQueryBuilder {
+	q.conflictAction = "NOTHING"
+	return q
+}
+
+// DoUpdate overwrites columns of the existing row with the values that were to be inserted.
+// It needs the conflict columns to be set with OnConflict.
+func (q *InsertQueryBuilder) DoUpdate(columns ...string) *InsertQueryBuilder {
+	q.conflictAction = "UPDATE"
+	q.updateColumns = columns
+	return q
+}
+
 func (q *InsertQueryBuilder) Query() (*sql.Rows, error) {
+	return q.QueryContext(q.queryBuilder.Context())
+}
+
+func (q *InsertQueryBuilder) QueryContext(ctx context.Context) (*sql.Rows, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
+
 	values := q.buildPreparedStatementValues()
-	return q.queryBuilder.DB.Query(*query, values...)
+	return q.queryBuilder.Executor().QueryContext(ctx, *query, values...)
 }
 
 func (q *InsertQueryBuilder) QueryRow() (*sql.Row, error) {
+	return q.QueryRowContext(q.queryBuilder.Context())
+}
+
+func (q *InsertQueryBuilder) QueryRowContext(ctx context.Context) (*sql.Row, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 
 	values := q.buildPreparedStatementValues()
-	return q.queryBuilder.DB.QueryRow(*query, values...), nil
+	return q.queryBuilder.Executor().QueryRowContext(ctx, *query, values...), nil
 }
 
 func (q *InsertQueryBuilder) Exec() (sql.Result, error) {
+	return q.ExecContext(q.queryBuilder.Context())
+}
+
+func (q *InsertQueryBuilder) ExecContext(ctx context.Context) (sql.Result, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 
 	values := q.buildPreparedStatementValues()
-	return q.queryBuilder.DB.Exec(*query, values...)
+	return q.queryBuilder.Executor().ExecContext(ctx, *query, values...)
 }
@@ -1,14 +1,18 @@
 package querybuilder
 
 import (
+	"reflect"
+	"regexp"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
 )
 
 func TestInsertQueryBuilder_buildColumnNameStatement(t *testing.T) {
 	qb := QueryBuilder{}
 	values := append(Clauses{}, Clause{ColumnName: "name", Value: "John"}, Clause{ColumnName: "age", Value: 30})
 
-	insertQB := InsertQueryBuilder{queryBuilder: &qb, values: values}
+	insertQB := InsertQueryBuilder{queryBuilder: &qb, rows: []Clauses{values}}
 
 	columnStmt := insertQB.buildColumnNameStatement()
 
@@ -64,3 +68,177 @@ func TestInsertQueryBuilder_Returning(t *testing.T) {
 		t.Errorf("Expected query to be '%s', got '%s'", expectedQuery, *query)
 	}
 }
+
+func TestInsertQueryBuilder_MultipleRows(t *testing.T) {
+	qb := QueryBuilder{}
+	rows := []Clauses{
+		{{ColumnName: "name", Value: "Go"}, {ColumnName: "icon", Value: "go.svg"}},
+		{{ColumnName: "name", Value: "SQL"}, {ColumnName: "icon", Value: nil}},
+		{{ColumnName: "name", Value: "Rust"}, {ColumnName: "icon", Value: "rust.svg"}},
+	}
+
+	insertQB := qb.SetBaseTable("tags").Insert(rows...).Returning("id", "name")
+
+	query, err := insertQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedQuery := "INSERT INTO tags (name, icon) VALUES ($1, $2), ($3, $4), ($5, $6) RETURNING id, name"
+	if *query != expectedQuery {
+		t.Errorf("Expected query to be '%s', got '%s'", expectedQuery, *query)
+	}
+
+	expectedValues := []interface{}{"Go", "go.svg", "SQL", nil, "Rust", "rust.svg"}
+	if values := insertQB.buildPreparedStatementValues(); !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("Expected values to be %v, got %v", expectedValues, values)
+	}
+}
+
+func TestInsertQueryBuilder_MismatchedRows(t *testing.T) {
+	qb := QueryBuilder{}
+	mismatched := [][]Clauses{
+		{{{ColumnName: "name", Value: "Go"}}, {{ColumnName: "name", Value: "SQL"}, {ColumnName: "icon", Value: nil}}},
+		{{{ColumnName: "name", Value: "Go"}}, {{ColumnName: "icon", Value: "sql.svg"}}},
+	}
+
+	for _, rows := range mismatched {
+		if _, err := qb.SetBaseTable("tags").Insert(rows...).buildQuery(); err == nil {
+			t.Errorf("Expected an error for rows %v", rows)
+		}
+	}
+}
+
+func TestInsertQueryBuilder_OnConflict(t *testing.T) {
+	values := Clauses{{ColumnName: "tagId", Value: 1}, {ColumnName: "itemId", Value: 2}, {ColumnName: "itemType", Value: "note"}}
+
+	tests := []struct {
+		name     string
+		build    func(qb *QueryBuilder) *InsertQueryBuilder
+		expected string
+	}{
+		{
+			name: "do nothing",
+			build: func(qb *QueryBuilder) *InsertQueryBuilder {
+				return qb.SetBaseTable("tagged_items").Insert(values).OnConflict("tagId", "itemId", "itemType").DoNothing().Returning("id")
+			},
+			expected: "INSERT INTO tagged_items (tagId, itemId, itemType) VALUES ($1, $2, $3) ON CONFLICT (tagId, itemId, itemType) DO NOTHING RETURNING id",
+		},
+		{
+			name: "do nothing without a target",
+			build: func(qb *QueryBuilder) *InsertQueryBuilder {
+				return qb.SetBaseTable("tagged_items").Insert(values).DoNothing()
+			},
+			expected: "INSERT INTO tagged_items (tagId, itemId, itemType) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		},
+		{
+			name: "do update",
+			build: func(qb *QueryBuilder) *InsertQueryBuilder {
+				return qb.SetBaseTable("tags").Insert(
+					Clauses{{ColumnName: "name", Value: "Go"}, {ColumnName: "icon", Value: "go.svg"}},
+					Clauses{{ColumnName: "name", Value: "SQL"}, {ColumnName: "icon", Value: "sql.svg"}},
+				).OnConflict("name").DoUpdate("icon").Returning("id")
+			},
+			expected: "INSERT INTO tags (name, icon) VALUES ($1, $2), ($3, $4) ON CONFLICT (name) DO UPDATE SET icon = EXCLUDED.icon RETURNING id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := QueryBuilder{}
+			query, err := tt.build(&qb).buildQuery()
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if *query != tt.expected {
+				t.Errorf("Expected query was not generated\nexpected: %s\ngot: %s", tt.expected, *query)
+			}
+		})
+	}
+
+	invalid := map[string]*InsertQueryBuilder{
+		"update without target":  (&QueryBuilder{}).SetBaseTable("tags").Insert(values).DoUpdate("icon"),
+		"update without columns": (&QueryBuilder{}).SetBaseTable("tags").Insert(values).OnConflict("name").DoUpdate(),
+		"target without action":  (&QueryBuilder{}).SetBaseTable("tags").Insert(values).OnConflict("name"),
+		"malicious target":       (&QueryBuilder{}).SetBaseTable("tags").Insert(values).OnConflict("name) DO NOTHING; --").DoNothing(),
+		"malicious update":       (&QueryBuilder{}).SetBaseTable("tags").Insert(values).OnConflict("name").DoUpdate("icon = 'x'; --"),
+	}
+
+	for name, insertQB := range invalid {
+		if _, err := insertQB.buildQuery(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestInsertQueryBuilder_CommonTableExpressionPlaceholders(t *testing.T) {
+	qb := QueryBuilder{}
+
+	query := qb.With(
+		qb.SetBaseTable("tags").Select("id").WhereEqual("name", "Go"),
+		"go_tag",
+	).With(
+		qb.SetBaseTable("notes").Select("id").WhereIn("slug", "a", "b"),
+		"picked_notes",
+	).SetBaseTable("tagged_items").Insert(
+		Clauses{{ColumnName: "itemType", Value: "note"}, {ColumnName: "position", Value: 1}},
+		Clauses{{ColumnName: "itemType", Value: "note"}, {ColumnName: "position", Value: 2}},
+	).OnConflict("itemType", "position").DoNothing().Returning("id")
+
+	for i := 0; i < 2; i++ {
+		built, err := query.buildQuery()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		expected := "WITH go_tag AS (SELECT id FROM tags WHERE name = $1), picked_notes AS (SELECT id FROM notes WHERE slug IN ($2, $3)) " +
+			"INSERT INTO tagged_items (itemType, position) VALUES ($4, $5), ($6, $7) ON CONFLICT (itemType, position) DO NOTHING RETURNING id"
+		if *built != expected {
+			t.Fatalf("Expected query was not generated\nexpected: %s\ngot: %s", expected, *built)
+		}
+	}
+
+	expectedValues := []interface{}{"Go", "a", "b", "note", 1, "note", 2}
+	if values := query.buildPreparedStatementValues(); !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("Expected values to be %v, got %v", expectedValues, values)
+	}
+}
+
+func TestInsertQueryBuilder_QueryReturnsEveryRow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO tags (name) VALUES ($1), ($2) ON CONFLICT (name) DO NOTHING RETURNING id, name")).
+		WithArgs("Go", "SQL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Go").AddRow(2, "SQL"))
+
+	qb := QueryBuilder{DB: db}
+	rows, err := qb.SetBaseTable("tags").Insert(
+		Clauses{{ColumnName: "name", Value: "Go"}},
+		Clauses{{ColumnName: "name", Value: "SQL"}},
+	).OnConflict("name").DoNothing().Returning("id", "name").Query()
+	if err != nil {
+		t.Fatalf("unexpected error querying: %s", err)
+	}
+
+	type tag struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	tags, err := ScanAll[tag](rows)
+	if err != nil {
+		t.Fatalf("unexpected error scanning: %s", err)
+	}
+
+	if len(tags) != 2 || tags[0].ID != 1 || tags[1].Name != "SQL" {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
@@ -0,0 +1,291 @@
+package querybuilder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Predicate is a condition that can be combined with others in a WHERE clause. Use the
+// constructors below to build them and Or or And to group them.
+type Predicate interface {
+	// build renders the predicate, calling placeholder once for every argument it binds.
+	build(placeholder func() string) string
+	args() []interface{}
+	// columns lists the column names the predicate writes into the statement, so they can be
+	// checked before it is built.
+	columns() []string
+}
+
+type comparison struct {
+	column   string
+	comparer string
+	value    interface{}
+}
+
+func (c comparison) build(placeholder func() string) string {
+	if c.comparer == "IS NULL" || c.comparer == "IS NOT NULL" {
+		return fmt.Sprintf("%s %s", c.column, c.comparer)
+	}
+	return fmt.Sprintf("%s %s %s", c.column, c.comparer, placeholder())
+}
+
+func (c comparison) columns() []string {
+	return []string{c.column}
+}
+
+func (c comparison) args() []interface{} {
+	if c.comparer == "IS NULL" || c.comparer == "IS NOT NULL" {
+		return nil
+	}
+	return []interface{}{c.value}
+}
+
+// Equal matches rows where column equals value, or is NULL when value is nil.
+func Equal(column string, value interface{}) Predicate {
+	if value == nil {
+		return comparison{column: column, comparer: "IS NULL"}
+	}
+	return comparison{column: column, comparer: "=", value: value}
+}
+
+// NotEqual matches rows where column differs from value, or is not NULL when value is nil.
+func NotEqual(column string, value interface{}) Predicate {
+	if value == nil {
+		return comparison{column: column, comparer: "IS NOT NULL"}
+	}
+	return comparison{column: column, comparer: "!=", value: value}
+}
+
+func LessThan(column string, value interface{}) Predicate {
+	return comparison{column: column, comparer: "<", value: value}
+}
+
+func LessThanEqual(column string, value interface{}) Predicate {
+	return comparison{column: column, comparer: "<=", value: value}
+}
+
+func GreaterThan(column string, value interface{}) Predicate {
+	return comparison{column: column, comparer: ">", value: value}
+}
+
+func GreaterThanEqual(column string, value interface{}) Predicate {
+	return comparison{column: column, comparer: ">=", value: value}
+}
+
+// Like matches column against a LIKE pattern. ILike is its case-insensitive counterpart.
+func Like(column string, pattern string) Predicate {
+	return comparison{column: column, comparer: "LIKE", value: pattern}
+}
+
+func ILike(column string, pattern string) Predicate {
+	return comparison{column: column, comparer: "ILIKE", value: pattern}
+}
+
+type inList struct {
+	column string
+	not    bool
+	values []interface{}
+}
+
+func (p inList) build(placeholder func() string) string {
+	// An empty list matches nothing, and excluding an empty list matches everything.
+	if len(p.values) == 0 {
+		if p.not {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+
+	placeholders := make([]string, len(p.values))
+	for i := range p.values {
+		placeholders[i] = placeholder()
+	}
+
+	operator := "IN"
+	if p.not {
+		operator = "NOT IN"
+	}
+	return fmt.Sprintf("%s %s (%s)", p.column, operator, strings.Join(placeholders, ", "))
+}
+
+func (p inList) columns() []string {
+	return []string{p.column}
+}
+
+func (p inList) args() []interface{} {
+	return p.values
+}
+
+// In matches rows where column is one of values, which may be a slice of any type or
+// individual arguments.
+func In(column string, values ...interface{}) Predicate {
+	return inList{column: column, values: flatten(values)}
+}
+
+// NotIn matches rows where column is none of values.
+func NotIn(column string, values ...interface{}) Predicate {
+	return inList{column: column, not: true, values: flatten(values)}
+}
+
+type between struct {
+	column    string
+	low, high interface{}
+}
+
+func (p between) build(placeholder func() string) string {
+	return fmt.Sprintf("%s BETWEEN %s AND %s", p.column, placeholder(), placeholder())
+}
+
+func (p between) columns() []string {
+	return []string{p.column}
+}
+
+func (p between) args() []interface{} {
+	return []interface{}{p.low, p.high}
+}
+
+// Between matches rows where column lies between low and high, inclusive.
+func Between(column string, low, high interface{}) Predicate {
+	return between{column: column, low: low, high: high}
+}
+
+// ErrPlaceholderMismatch is returned when a raw predicate has a different number of ? markers
+// than values to bind to them.
+var ErrPlaceholderMismatch = errors.New("raw predicate placeholders do not match its values")
+
+type raw struct {
+	sql    string
+	values []interface{}
+}
+
+func (p raw) build(placeholder func() string) string {
+	var stmt strings.Builder
+	p.scan(func(literal string) { stmt.WriteString(literal) }, func() { stmt.WriteString(placeholder()) })
+	return stmt.String()
+}
+
+// scan walks the SQL, passing text to literal and calling marker for every ? that stands for a
+// value. A doubled ?? is the escape for a literal ?, as used by jsonb operators.
+func (p raw) scan(literal func(string), marker func()) {
+	start := 0
+	for i := 0; i < len(p.sql); i++ {
+		if p.sql[i] != '?' {
+			continue
+		}
+		literal(p.sql[start:i])
+		if i+1 < len(p.sql) && p.sql[i+1] == '?' {
+			literal("?")
+			i++
+		} else {
+			marker()
+		}
+		start = i + 1
+	}
+	literal(p.sql[start:])
+}
+
+func (p raw) validate() error {
+	markers := 0
+	p.scan(func(string) {}, func() { markers++ })
+	if markers != len(p.values) {
+		return fmt.Errorf("%w: %q has %d placeholders but %d values", ErrPlaceholderMismatch, p.sql, markers, len(p.values))
+	}
+	return nil
+}
+
+// columns is empty because a raw predicate is written out as given, and so must never come
+// from request input.
+func (p raw) columns() []string {
+	return nil
+}
+
+func (p raw) args() []interface{} {
+	return p.values
+}
+
+// Raw is a hand-written predicate. Each ? in sql is replaced with a numbered placeholder bound
+// to the matching value, so values are never interpolated into the statement. Write ?? for a
+// literal ?, such as the jsonb key operator. Building fails with ErrPlaceholderMismatch unless
+// there is exactly one value per placeholder.
+func Raw(sql string, values ...interface{}) Predicate {
+	return raw{sql: sql, values: values}
+}
+
+type group struct {
+	joiner     string
+	predicates []Predicate
+}
+
+func (g group) build(placeholder func() string) string {
+	if len(g.predicates) == 0 {
+		if g.joiner == "OR" {
+			return "FALSE"
+		}
+		return "TRUE"
+	}
+
+	parts := make([]string, len(g.predicates))
+	for i, predicate := range g.predicates {
+		parts[i] = predicate.build(placeholder)
+	}
+	return "(" + strings.Join(parts, " "+g.joiner+" ") + ")"
+}
+
+func (g group) columns() []string {
+	columns := make([]string, 0)
+	for _, predicate := range g.predicates {
+		columns = append(columns, predicate.columns()...)
+	}
+	return columns
+}
+
+func (g group) args() []interface{} {
+	values := make([]interface{}, 0)
+	for _, predicate := range g.predicates {
+		values = append(values, predicate.args()...)
+	}
+	return values
+}
+
+// Or matches rows satisfying any of predicates.
+func Or(predicates ...Predicate) Predicate {
+	return group{joiner: "OR", predicates: predicates}
+}
+
+// And matches rows satisfying all of predicates. Conditions added to a builder are already
+// joined with AND; use And to nest a group inside Or.
+func And(predicates ...Predicate) Predicate {
+	return group{joiner: "AND", predicates: predicates}
+}
+
+// flatten expands slice arguments so In("id", ids) and In("id", 1, 2) bind the same values.
+func flatten(values []interface{}) []interface{} {
+	flattened := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < v.Len(); i++ {
+				flattened = append(flattened, v.Index(i).Interface())
+			}
+			continue
+		}
+		flattened = append(flattened, value)
+	}
+	return flattened
+}
+
+// clausePredicate reads a stored condition: either a Predicate, or a "column:comparer" clause
+// added by the Where helpers.
+func clausePredicate(clause Clause) Predicate {
+	if predicate, ok := clause.Value.(Predicate); ok {
+		return predicate
+	}
+
+	parts := strings.SplitN(clause.ColumnName, ":", 2)
+	if len(parts) != 2 {
+		return comparison{column: clause.ColumnName, comparer: "=", value: clause.Value}
+	}
+	return comparison{column: parts[0], comparer: parts[1], value: clause.Value}
+}
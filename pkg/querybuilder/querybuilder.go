@@ -1,6 +1,7 @@
 package querybuilder
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -17,18 +18,53 @@ type CommonQuery struct {
 	Table   string
 }
 
+// Executor runs statements. Both *sql.DB and *sql.Tx satisfy it, so the same builders work
+// inside and outside a transaction.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type QueryBuilder struct {
-	DB                     *sql.DB
+	DB Executor
+	// Hooks are told about every statement the builder runs.
+	Hooks                  []Hook
+	ctx                    context.Context
 	table                  string
 	preparedVariableOffset int
+	// placeholderStart is the number of placeholders used before this statement, which is
+	// non-zero for statements written out as a common table expression of another.
+	placeholderStart       int
 	commonTableExpressions []CommonQuery
 }
 
 type CommonQueryBuilder interface {
+	base() *QueryBuilder
 	buildQuery() (*string, error)
 	buildPreparedStatementValues() []interface{}
 }
 
+// WithContext returns a copy of the builder whose Query, QueryRow and Exec calls run with ctx.
+func (q *QueryBuilder) WithContext(ctx context.Context) *QueryBuilder {
+	cloned := *q
+	cloned.ctx = ctx
+	return &cloned
+}
+
+// Context returns the context bound by WithContext, or context.Background if there is none.
+func (q *QueryBuilder) Context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+// Executor returns DB, instrumented with the builder's Hooks, for running hand-written SQL.
+func (q *QueryBuilder) Executor() Executor {
+	return Instrument(q.DB, q.Hooks...)
+}
+
 func (q *QueryBuilder) SetBaseTable(table string) *QueryBuilder {
 	q.table = table
 	return q
@@ -37,6 +73,8 @@ func (q *QueryBuilder) SetBaseTable(table string) *QueryBuilder {
 func (q *QueryBuilder) clone() *QueryBuilder {
 	return &QueryBuilder{
 		DB:                     q.DB,
+		Hooks:                  q.Hooks,
+		ctx:                    q.ctx,
 		table:                  q.table,
 		commonTableExpressions: q.commonTableExpressions,
 		preparedVariableOffset: 0,
@@ -53,9 +91,11 @@ func (q *QueryBuilder) Update(values Clauses) *UpdateQueryBuilder {
 	return &UpdateQueryBuilder{queryBuilder: cloned, table: cloned.table, values: values}
 }
 
-func (q *QueryBuilder) Insert(values Clauses) *InsertQueryBuilder {
+// Insert inserts one row for each set of values given. Every row must set the same columns in
+// the same order.
+func (q *QueryBuilder) Insert(rows ...Clauses) *InsertQueryBuilder {
 	cloned := q.clone()
-	return &InsertQueryBuilder{queryBuilder: cloned, table: cloned.table, values: values}
+	return &InsertQueryBuilder{queryBuilder: cloned, table: cloned.table, rows: rows}
 }
 
 func (q *QueryBuilder) Delete() *DeleteQueryBuilder {
@@ -102,50 +142,59 @@ func (q *QueryBuilder) buildColumnUpdateStatement(values Clauses) string {
 
 func (q *QueryBuilder) buildValuesStatement(values Clauses) string {
 	stmt := ""
-	i := 0
 
-	if len(values) != 0 {
-		for i = range values {
-			if i > 0 {
-				stmt += ", "
-			}
-			stmt += fmt.Sprintf("$%d", i+q.preparedVariableOffset+1)
+	for i := range values {
+		if i > 0 {
+			stmt += ", "
 		}
+		stmt += fmt.Sprintf("$%d", i+q.preparedVariableOffset+1)
 	}
 
-	q.preparedVariableOffset += i + 1
+	q.preparedVariableOffset += len(values)
 	return stmt
 }
 
+func (q *QueryBuilder) addPredicate(predicate Predicate, conditions *Clauses) {
+	if *conditions == nil {
+		*conditions = make(Clauses, 0)
+	}
+	*conditions = append(*conditions, Clause{Value: predicate})
+}
+
 func (q *QueryBuilder) buildConditionalStatement(conditions Clauses) string {
-	stmt := ""
+	if len(conditions) == 0 {
+		return ""
+	}
 
-	if len(conditions) != 0 {
-		stmt += " WHERE"
-		preparedStatementCount := 0
-		for conditionIndex, clause := range conditions {
-			if conditionIndex > 0 {
-				stmt += " AND"
-			}
-			columnAndComparator := strings.Split(clause.ColumnName, ":")
-			column, comparer := columnAndComparator[0], columnAndComparator[1]
-			if comparer == "IS NULL" || comparer == "IS NOT NULL" {
-				stmt += fmt.Sprintf(" %s %s", column, comparer)
-			} else {
-				stmt += fmt.Sprintf(" %s %s $%d", column, comparer, preparedStatementCount+q.preparedVariableOffset+1)
-				preparedStatementCount++
-			}
-		}
+	return " WHERE " + q.buildPredicates(conditions)
+}
 
-		q.preparedVariableOffset += preparedStatementCount
+// buildPredicates joins conditions with AND, numbering placeholders on from those already used
+// by the statement.
+func (q *QueryBuilder) buildPredicates(conditions Clauses) string {
+	preparedStatementCount := 0
+	placeholder := func() string {
+		preparedStatementCount++
+		return fmt.Sprintf("$%d", preparedStatementCount+q.preparedVariableOffset)
 	}
 
-	return stmt
+	predicates := make([]string, len(conditions))
+	for i, clause := range conditions {
+		predicates[i] = clausePredicate(clause).build(placeholder)
+	}
+
+	q.preparedVariableOffset += preparedStatementCount
+	return strings.Join(predicates, " AND ")
 }
 
 func (q *QueryBuilder) buildParameters(parameters Clauses) []interface{} {
 	values := make([]interface{}, 0, len(parameters))
 	for _, clause := range parameters {
+		if predicate, ok := clause.Value.(Predicate); ok {
+			values = append(values, predicate.args()...)
+			continue
+		}
+
 		if strings.Contains(clause.ColumnName, ":") {
 			parts := strings.SplitN(clause.ColumnName, ":", 2)
 			if len(parts) == 2 {
@@ -188,18 +237,33 @@ func (q *QueryBuilder) buildCommonTableExpressionParameters() []interface{} {
 	return values
 }
 
+// buildCommonTableExpressions writes out the WITH clause and starts the statement's placeholder
+// numbering, so that the expressions' placeholders come first and the statement's own follow
+// on from them. It must be called before anything else that numbers placeholders.
 func (q *QueryBuilder) buildCommonTableExpressions() (string, error) {
-	stmt := ""
+	q.preparedVariableOffset = q.placeholderStart
 
-	if len(q.commonTableExpressions) > 0 {
-		for _, cte := range q.commonTableExpressions {
-			query, err := cte.Builder.buildQuery()
-			if err != nil {
-				return "", err
-			}
-			stmt += fmt.Sprintf("WITH %s AS (%s) ", cte.Table, *query)
+	if len(q.commonTableExpressions) == 0 {
+		return "", nil
+	}
+
+	expressions := make([]string, len(q.commonTableExpressions))
+	for i, cte := range q.commonTableExpressions {
+		if err := ValidateIdentifier(cte.Table); err != nil {
+			return "", err
 		}
+
+		inner := cte.Builder.base()
+		inner.placeholderStart = q.preparedVariableOffset
+
+		query, err := cte.Builder.buildQuery()
+		if err != nil {
+			return "", err
+		}
+
+		q.preparedVariableOffset = inner.preparedVariableOffset
+		expressions[i] = fmt.Sprintf("%s AS (%s)", cte.Table, *query)
 	}
 
-	return stmt, nil
+	return "WITH " + strings.Join(expressions, ", ") + " ", nil
 }
@@ -24,8 +24,8 @@ func TestInsertQueryBuilder(t *testing.T) {
 	}
 
 	expectedValues := append(Clauses{}, Clause{ColumnName: "name", Value: "John Doe"}, Clause{ColumnName: "age", Value: 30})
-	if !reflect.DeepEqual(insertQB.values, expectedValues) {
-		t.Errorf("Expected values to be %v, got %v", expectedValues, insertQB.values)
+	if !reflect.DeepEqual(insertQB.rows[0], expectedValues) {
+		t.Errorf("Expected values to be %v, got %v", expectedValues, insertQB.rows[0])
 	}
 
 	query, err := insertQB.buildQuery()
@@ -198,6 +198,31 @@ func Test_CommonTableExpression_WithSelect_AndSelect(t *testing.T) {
 	}
 }
 
+func Test_CommonTableExpression_PlaceholdersFollowOn(t *testing.T) {
+	qb := QueryBuilder{}
+	values := Clauses{{ColumnName: "name", Value: "John"}}
+
+	query := qb.With(
+		qb.SetBaseTable("users").Update(values).WhereEqual("id", 3).Returning("*"), "updated_user").
+		With(qb.SetBaseTable("companies").Select("id").WhereEqual("name", "Acme"), "acme").
+		Select("updated_user.id").From("updated_user").WhereGreaterThan("updated_user.age", 30)
+
+	built, err := query.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error when building select query, got %s", err)
+	}
+
+	expected := "WITH updated_user AS (UPDATE users SET name = $1 WHERE id = $2 RETURNING *), acme AS (SELECT id FROM companies WHERE name = $3) SELECT updated_user.id FROM updated_user WHERE updated_user.age > $4"
+	if *built != expected {
+		t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", expected, *built)
+	}
+
+	expectedValues := []interface{}{"John", 3, "Acme", 30}
+	if values := query.buildPreparedStatementValues(); !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("Expected values to be %v, got %v", expectedValues, values)
+	}
+}
+
 func TestPreparedVariableOffset_IsResetPerQuery(t *testing.T) {
 	qb := QueryBuilder{}
 	values := Clauses{
@@ -0,0 +1,185 @@
+package querybuilder
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// Scanner reads the current row. Both *sql.Row and *sql.Rows satisfy it.
+type Scanner interface {
+	Scan(dest ...any) error
+}
+
+type structField struct {
+	column string
+	index  []int
+}
+
+// structFields caches the db-tagged fields of each struct type.
+var structFields sync.Map
+
+func fieldsOf(t reflect.Type) []structField {
+	if cached, ok := structFields.Load(t); ok {
+		return cached.([]structField)
+	}
+
+	fields := []structField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		column := field.Tag.Get("db")
+		if column == "" || column == "-" || !field.IsExported() {
+			continue
+		}
+		fields = append(fields, structField{column: column, index: field.Index})
+	}
+
+	structFields.Store(t, fields)
+	return fields
+}
+
+func structType(dest any) (reflect.Type, error) {
+	t := reflect.TypeOf(dest)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("querybuilder: expected a struct or pointer to a struct, got %T", dest)
+	}
+	return t, nil
+}
+
+// Columns lists the db tags of dest's fields in the order they are declared. dest is a struct
+// or a pointer to one; fields without a db tag, or tagged db:"-", are left out.
+func Columns(dest any) []string {
+	t, err := structType(dest)
+	if err != nil {
+		panic(err)
+	}
+
+	fields := fieldsOf(t)
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.column
+	}
+	return columns
+}
+
+// QualifiedColumns is Columns with each column read from table and aliased back to its own
+// name, as in "notes.id AS id", for selects that join other tables.
+func QualifiedColumns(table string, dest any) []string {
+	columns := Columns(dest)
+	for i, column := range columns {
+		columns[i] = fmt.Sprintf("%s.%s AS %s", table, column, column)
+	}
+	return columns
+}
+
+// Targets returns Scan destinations for dest's fields, in Columns order or in the order of
+// columns when any are given, along with a function to call once Scan has succeeded. It lets
+// a struct be scanned alongside other values from the same row; ScanStruct covers the common
+// case.
+//
+// Pointer fields are set to nil for NULL and other fields to their zero value. Slice fields
+// other than []byte are read as Postgres arrays.
+func Targets(dest any, columns ...string) ([]any, func(), error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("querybuilder: scan destination must be a pointer to a struct, got %T", dest)
+	}
+	v = v.Elem()
+
+	fields := fieldsOf(v.Type())
+	if len(columns) > 0 {
+		picked := make([]structField, len(columns))
+		for i, column := range columns {
+			found := false
+			for _, field := range fields {
+				if field.column == column {
+					picked[i] = field
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, nil, fmt.Errorf("querybuilder: %s has no field tagged db:%q", v.Type(), column)
+			}
+		}
+		fields = picked
+	}
+
+	targets := make([]any, len(fields))
+	finishers := []func(){}
+
+	for i, field := range fields {
+		value := v.FieldByIndex(field.index)
+
+		switch {
+		case value.Kind() == reflect.Pointer:
+			targets[i] = value.Addr().Interface()
+		case value.Addr().Type().Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()):
+			targets[i] = value.Addr().Interface()
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8:
+			targets[i] = pq.Array(value.Addr().Interface())
+		default:
+			// Scanning into a pointer lets database/sql report NULL, which is then left as
+			// the field's zero value.
+			holder := reflect.New(reflect.PointerTo(value.Type()))
+			targets[i] = holder.Interface()
+			finishers = append(finishers, func() {
+				if scanned := holder.Elem(); !scanned.IsNil() {
+					value.Set(scanned.Elem())
+				} else {
+					value.Set(reflect.Zero(value.Type()))
+				}
+			})
+		}
+	}
+
+	finish := func() {
+		for _, finisher := range finishers {
+			finisher()
+		}
+	}
+
+	return targets, finish, nil
+}
+
+// ScanStruct scans the current row into dest, a pointer to a struct. The row's columns must
+// be in Columns order, or in the order of columns when any are given.
+func ScanStruct(row Scanner, dest any, columns ...string) error {
+	targets, finish, err := Targets(dest, columns...)
+	if err != nil {
+		return err
+	}
+
+	if err := row.Scan(targets...); err != nil {
+		return err
+	}
+
+	finish()
+	return nil
+}
+
+// ScanAll reads every remaining row into a new T and closes rows.
+func ScanAll[T any](rows *sql.Rows, columns ...string) ([]*T, error) {
+	defer rows.Close()
+
+	items := []*T{}
+	for rows.Next() {
+		item := new(T)
+		if err := ScanStruct(rows, item, columns...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
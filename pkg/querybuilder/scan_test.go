@@ -0,0 +1,109 @@
+package querybuilder
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type kind string
+
+type scannedRecord struct {
+	ID          int64           `db:"id"`
+	PublishedAt *time.Time      `db:"publishedAt"`
+	Title       string          `db:"title"`
+	Subtitle    string          `db:"subtitle"`
+	Kind        kind            `db:"kind"`
+	Skills      []string        `db:"skills"`
+	Payload     json.RawMessage `db:"payload"`
+	Computed    string
+	Ignored     string `db:"-"`
+}
+
+func TestColumns(t *testing.T) {
+	expected := []string{"id", "publishedAt", "title", "subtitle", "kind", "skills", "payload"}
+	if columns := Columns(scannedRecord{}); !reflect.DeepEqual(columns, expected) {
+		t.Fatalf("Expected columns %v, got %v", expected, columns)
+	}
+
+	if columns := QualifiedColumns("notes", &scannedRecord{}); columns[1] != "notes.publishedAt AS publishedAt" {
+		t.Fatalf("Expected qualified columns, got %v", columns)
+	}
+}
+
+func TestScanAll_MapsNullsAndArrays(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	published := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, publishedAt, title, subtitle, kind, skills, payload FROM records").
+		WillReturnRows(sqlmock.NewRows(Columns(scannedRecord{})).
+			AddRow(1, published, "First", "Sub", "note", []byte(`{"Go","SQL"}`), []byte(`{"a":1}`)).
+			AddRow(2, nil, "Second", nil, "role", nil, nil))
+
+	qb := QueryBuilder{DB: db}
+	rows, err := qb.SetBaseTable("records").Select(Columns(scannedRecord{})...).Query()
+	if err != nil {
+		t.Fatalf("unexpected error querying: %s", err)
+	}
+
+	records, err := ScanAll[scannedRecord](rows)
+	if err != nil {
+		t.Fatalf("unexpected error scanning: %s", err)
+	}
+
+	first := scannedRecord{ID: 1, PublishedAt: &published, Title: "First", Subtitle: "Sub", Kind: "note", Skills: []string{"Go", "SQL"}, Payload: json.RawMessage(`{"a":1}`)}
+	second := scannedRecord{ID: 2, Title: "Second", Kind: "role"}
+
+	if len(records) != 2 || !reflect.DeepEqual(*records[0], first) || !reflect.DeepEqual(*records[1], second) {
+		t.Fatalf("unexpected records %+v %+v", records[0], records[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestScanStruct_SelectedColumnsOverwriteNulls(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO records").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subtitle", "publishedAt"}).AddRow(7, nil, nil))
+
+	published := time.Now()
+	record := scannedRecord{Title: "Kept", Subtitle: "Cleared", PublishedAt: &published}
+
+	qb := QueryBuilder{DB: db}
+	row, err := qb.SetBaseTable("records").Insert(Clauses{{ColumnName: "title", Value: record.Title}}).Returning("id", "subtitle", "publishedAt").QueryRow()
+	if err != nil {
+		t.Fatalf("unexpected error querying: %s", err)
+	}
+
+	if err := ScanStruct(row, &record, "id", "subtitle", "publishedAt"); err != nil {
+		t.Fatalf("unexpected error scanning: %s", err)
+	}
+
+	if record.ID != 7 || record.Title != "Kept" || record.Subtitle != "" || record.PublishedAt != nil {
+		t.Fatalf("unexpected record %+v", record)
+	}
+}
+
+func TestTargets_RejectsUnknownColumnsAndNonStructs(t *testing.T) {
+	if _, _, err := Targets(&scannedRecord{}, "missing"); err == nil {
+		t.Fatalf("expected an error for a column without a field")
+	}
+
+	if _, _, err := Targets(scannedRecord{}); err == nil {
+		t.Fatalf("expected an error for a non-pointer destination")
+	}
+}
@@ -1,6 +1,7 @@
 package querybuilder
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -12,16 +13,34 @@ type SelectQueryBuilder struct {
 
 	fields     []string
 	table      string
+	distinct   bool
+	joins      []join
 	conditions Clauses
 
-	sortDirection string
-	sortColumn    string
+	groupBy []string
+	having  Clauses
 
-	leftJoinTable      string
-	leftJoinOwnKey     string
-	leftJoinForeignKey string
+	orderings []ordering
 
-	limit int
+	limit  int
+	offset int
+}
+
+type join struct {
+	kind       string
+	table      string
+	conditions []string
+	// keys are the columns named by LeftJoin and the other key joins. Conditions given to
+	// LeftJoinOn and its siblings are trusted.
+	keys []string
+}
+
+type ordering struct {
+	column    string
+	direction string
+	// trusted orderings come from code or an allow-list, and may be expressions rather than
+	// plain column names.
+	trusted bool
 }
 
 func (q *SelectQueryBuilder) From(table string) *SelectQueryBuilder {
@@ -29,18 +48,85 @@ func (q *SelectQueryBuilder) From(table string) *SelectQueryBuilder {
 	return q
 }
 
+// Distinct drops duplicate rows from the result.
+func (q *SelectQueryBuilder) Distinct() *SelectQueryBuilder {
+	q.distinct = true
+	return q
+}
+
+// LeftJoin joins table on the base table's ownKey matching table's foreignKey. Either table may
+// carry an alias, as in "companies AS c", in which case the alias is used in the ON condition.
 func (q *SelectQueryBuilder) LeftJoin(table string, ownKey string, foreignKey string) *SelectQueryBuilder {
-	q.leftJoinTable = table
-	q.leftJoinOwnKey = ownKey
-	q.leftJoinForeignKey = foreignKey
+	return q.addKeyJoin("LEFT JOIN", table, ownKey, foreignKey)
+}
+
+func (q *SelectQueryBuilder) InnerJoin(table string, ownKey string, foreignKey string) *SelectQueryBuilder {
+	return q.addKeyJoin("INNER JOIN", table, ownKey, foreignKey)
+}
 
+func (q *SelectQueryBuilder) RightJoin(table string, ownKey string, foreignKey string) *SelectQueryBuilder {
+	return q.addKeyJoin("RIGHT JOIN", table, ownKey, foreignKey)
+}
+
+// LeftJoinOn joins table on conditions, which are written out as given and combined with AND.
+func (q *SelectQueryBuilder) LeftJoinOn(table string, conditions ...string) *SelectQueryBuilder {
+	return q.addJoin("LEFT JOIN", table, conditions)
+}
+
+func (q *SelectQueryBuilder) InnerJoinOn(table string, conditions ...string) *SelectQueryBuilder {
+	return q.addJoin("INNER JOIN", table, conditions)
+}
+
+func (q *SelectQueryBuilder) RightJoinOn(table string, conditions ...string) *SelectQueryBuilder {
+	return q.addJoin("RIGHT JOIN", table, conditions)
+}
+
+func (q *SelectQueryBuilder) addKeyJoin(kind string, table string, ownKey string, foreignKey string) *SelectQueryBuilder {
+	condition := fmt.Sprintf("%s.%s = %s.%s", tableReference(q.table), ownKey, tableReference(table), foreignKey)
+	q.addJoin(kind, table, []string{condition})
+	q.joins[len(q.joins)-1].keys = []string{ownKey, foreignKey}
 	return q
 }
 
+func (q *SelectQueryBuilder) addJoin(kind string, table string, conditions []string) *SelectQueryBuilder {
+	q.joins = append(q.joins, join{kind: kind, table: table, conditions: conditions})
+	return q
+}
+
+// GroupBy groups rows by columns, each of which must be an identifier.
+func (q *SelectQueryBuilder) GroupBy(columns ...string) *SelectQueryBuilder {
+	q.groupBy = append(q.groupBy, columns...)
+	return q
+}
+
+// Having filters grouped rows, typically on an aggregate such as GreaterThan("COUNT(*)", 1).
+// Repeated calls are combined with AND. Unlike Where, the columns of these predicates are written
+// out as given so that they can be aggregates, so they must never come from request input.
+func (q *SelectQueryBuilder) Having(predicate Predicate) *SelectQueryBuilder {
+	q.queryBuilder.addPredicate(predicate, &q.having)
+	return q
+}
+
+// OrderBy adds a sort column. Each call adds another column, so rows are sorted by the first
+// and ties broken by those that follow. column must be an identifier and sortDirection ASC or
+// DESC; anything else fails when the query is built.
 func (q *SelectQueryBuilder) OrderBy(column string, sortDirection string) *SelectQueryBuilder {
-	q.sortColumn = column
-	q.sortDirection = sortDirection
+	q.orderings = append(q.orderings, ordering{column: column, direction: sortDirection})
+	return q
+}
+
+// OrderByExpression sorts by an expression such as "COALESCE(publishedAt, createdAt)". The
+// expression is written out as given, so it must never come from request input.
+func (q *SelectQueryBuilder) OrderByExpression(expression string, sortDirection string) *SelectQueryBuilder {
+	q.orderings = append(q.orderings, ordering{column: expression, direction: sortDirection, trusted: true})
+	return q
+}
 
+// OrderBySorts adds sort columns parsed from request input by SortColumns.Parse.
+func (q *SelectQueryBuilder) OrderBySorts(sorts ...Sort) *SelectQueryBuilder {
+	for _, sort := range sorts {
+		q.orderings = append(q.orderings, ordering{column: sort.column, direction: sort.direction, trusted: true})
+	}
 	return q
 }
 
@@ -49,6 +135,11 @@ func (q *SelectQueryBuilder) Limit(limit int) *SelectQueryBuilder {
 	return q
 }
 
+func (q *SelectQueryBuilder) Offset(offset int) *SelectQueryBuilder {
+	q.offset = offset
+	return q
+}
+
 func (q *SelectQueryBuilder) WhereEqual(column string, value interface{}) *SelectQueryBuilder {
 	if value == nil {
 		q.queryBuilder.addCondition(column, nil, "IS NULL", &q.conditions)
@@ -73,6 +164,11 @@ func (q *SelectQueryBuilder) WhereGreaterThan(column string, value interface{})
 	return q
 }
 
+func (q *SelectQueryBuilder) WhereGreaterThanEqual(column string, value interface{}) *SelectQueryBuilder {
+	q.queryBuilder.addCondition(column, value, ">=", &q.conditions)
+	return q
+}
+
 func (q *SelectQueryBuilder) WhereNotEqual(column string, value interface{}) *SelectQueryBuilder {
 	if value == nil {
 		q.queryBuilder.addCondition(column, nil, "IS NOT NULL", &q.conditions)
@@ -82,19 +178,75 @@ func (q *SelectQueryBuilder) WhereNotEqual(column string, value interface{}) *Se
 	return q
 }
 
+// Where adds a predicate, such as an Or group, to the conditions.
+func (q *SelectQueryBuilder) Where(predicate Predicate) *SelectQueryBuilder {
+	q.queryBuilder.addPredicate(predicate, &q.conditions)
+	return q
+}
+
+func (q *SelectQueryBuilder) WhereIn(column string, values ...interface{}) *SelectQueryBuilder {
+	return q.Where(In(column, values...))
+}
+
+func (q *SelectQueryBuilder) WhereNotIn(column string, values ...interface{}) *SelectQueryBuilder {
+	return q.Where(NotIn(column, values...))
+}
+
+func (q *SelectQueryBuilder) WhereLike(column string, pattern string) *SelectQueryBuilder {
+	return q.Where(Like(column, pattern))
+}
+
+func (q *SelectQueryBuilder) WhereILike(column string, pattern string) *SelectQueryBuilder {
+	return q.Where(ILike(column, pattern))
+}
+
+func (q *SelectQueryBuilder) WhereBetween(column string, low, high interface{}) *SelectQueryBuilder {
+	return q.Where(Between(column, low, high))
+}
+
+// WhereRaw adds a hand-written predicate; see Raw.
+func (q *SelectQueryBuilder) WhereRaw(sql string, values ...interface{}) *SelectQueryBuilder {
+	return q.Where(Raw(sql, values...))
+}
+
 func (q *SelectQueryBuilder) buildPreparedStatementValues() []interface{} {
 	values := q.queryBuilder.buildCommonTableExpressionParameters()
 	values = append(values, q.queryBuilder.buildParameters(q.conditions)...)
+	values = append(values, q.queryBuilder.buildParameters(q.having)...)
 
 	return values
 }
 
+func (q *SelectQueryBuilder) base() *QueryBuilder {
+	return q.queryBuilder
+}
+
 func (q *SelectQueryBuilder) buildQuery() (*string, error) {
 	if len(q.fields) == 0 || q.table == "" {
 		err := errors.New("Incorrectly formatted query. Ensure fields and base tables are set")
 		return nil, err
 	}
 
+	if err := validateTable(q.table); err != nil {
+		return nil, err
+	}
+
+	if err := validatePredicates(q.conditions); err != nil {
+		return nil, err
+	}
+
+	for _, column := range q.groupBy {
+		if err := ValidateIdentifier(column); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, clause := range q.having {
+		if err := validatePlaceholders(clausePredicate(clause)); err != nil {
+			return nil, err
+		}
+	}
+
 	commonTableExpressions, err := q.queryBuilder.buildCommonTableExpressions()
 	if err != nil {
 		return nil, err
@@ -102,45 +254,97 @@ func (q *SelectQueryBuilder) buildQuery() (*string, error) {
 
 	fields := strings.Join(q.fields, ", ")
 
-	query := fmt.Sprintf("%sSELECT %s FROM %s", commonTableExpressions, fields, q.table)
+	selectKeyword := "SELECT"
+	if q.distinct {
+		selectKeyword = "SELECT DISTINCT"
+	}
+
+	query := fmt.Sprintf("%s%s %s FROM %s", commonTableExpressions, selectKeyword, fields, q.table)
 
-	if q.leftJoinTable != "" {
-		query += fmt.Sprintf(" LEFT JOIN %s ON %s.%s = %s.%s", q.leftJoinTable, q.table, q.leftJoinOwnKey, q.leftJoinTable, q.leftJoinForeignKey)
+	for _, join := range q.joins {
+		if len(join.conditions) == 0 {
+			return nil, fmt.Errorf("Incorrectly formatted query. %s %s has no ON condition", join.kind, join.table)
+		}
+		if err := validateTable(join.table); err != nil {
+			return nil, err
+		}
+		for _, key := range join.keys {
+			if err := ValidateIdentifier(key); err != nil {
+				return nil, err
+			}
+		}
+		query += fmt.Sprintf(" %s %s ON %s", join.kind, join.table, strings.Join(join.conditions, " AND "))
 	}
 
 	query += q.queryBuilder.buildConditionalStatement(q.conditions)
 
-	if q.sortColumn != "" {
-		query += fmt.Sprintf(" ORDER BY %s %s", q.sortColumn, q.sortDirection)
+	if len(q.groupBy) > 0 {
+		query += " GROUP BY " + strings.Join(q.groupBy, ", ")
+	}
+
+	if len(q.having) > 0 {
+		query += " HAVING " + q.queryBuilder.buildPredicates(q.having)
+	}
+
+	if len(q.orderings) > 0 {
+		columns := make([]string, len(q.orderings))
+		for i, ordering := range q.orderings {
+			if !ordering.trusted {
+				if err := ValidateIdentifier(ordering.column); err != nil {
+					return nil, err
+				}
+			}
+			if err := validateSortDirection(ordering.direction); err != nil {
+				return nil, err
+			}
+			columns[i] = ordering.column + " " + ordering.direction
+		}
+		query += " ORDER BY " + strings.Join(columns, ", ")
 	}
 
 	if q.limit > 0 {
 		query += fmt.Sprintf(" LIMIT %d", q.limit)
 	}
 
+	if q.offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", q.offset)
+	}
+
 	return &query, nil
 }
 
 func (q *SelectQueryBuilder) Query() (*sql.Rows, error) {
+	return q.QueryContext(q.queryBuilder.Context())
+}
+
+func (q *SelectQueryBuilder) QueryContext(ctx context.Context) (*sql.Rows, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 	values := q.buildPreparedStatementValues()
-	if len(values) > 0 {
-		return q.queryBuilder.DB.Query(*query, values...)
-	}
-	return q.queryBuilder.DB.Query(*query)
+	return q.queryBuilder.Executor().QueryContext(ctx, *query, values...)
 }
 
 func (q *SelectQueryBuilder) QueryRow() (*sql.Row, error) {
+	return q.QueryRowContext(q.queryBuilder.Context())
+}
+
+func (q *SelectQueryBuilder) QueryRowContext(ctx context.Context) (*sql.Row, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 	values := q.buildPreparedStatementValues()
-	if len(values) > 0 {
-		return q.queryBuilder.DB.QueryRow(*query, values...), nil
+	return q.queryBuilder.Executor().QueryRowContext(ctx, *query, values...), nil
+}
+
+// tableReference returns the name a table is referred to by elsewhere in the statement: its alias
+// when it has one, as in "companies AS c" or "companies c", and otherwise its name.
+func tableReference(table string) string {
+	parts := strings.Fields(table)
+	if len(parts) == 0 {
+		return table
 	}
-	return q.queryBuilder.DB.QueryRow(*query), nil
+	return parts[len(parts)-1]
 }
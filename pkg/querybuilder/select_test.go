@@ -1,6 +1,7 @@
 package querybuilder
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -72,12 +73,9 @@ func TestSelectQueryBuilder_LeftJoin(t *testing.T) {
 	qb := QueryBuilder{}
 	selectQB := qb.Select("id", "name").From("users").LeftJoin("orders", "id", "user_id")
 
-	if selectQB.leftJoinTable != "orders" {
-		t.Errorf("Expected left join table to be 'orders', got %s", selectQB.leftJoinTable)
-	}
-
-	if selectQB.leftJoinOwnKey != "id" || selectQB.leftJoinForeignKey != "user_id" {
-		t.Errorf("Expected join keys to be 'id' and 'user_id', got %s and %s", selectQB.leftJoinOwnKey, selectQB.leftJoinForeignKey)
+	expectedJoins := []join{{kind: "LEFT JOIN", table: "orders", conditions: []string{"users.id = orders.user_id"}, keys: []string{"id", "user_id"}}}
+	if !reflect.DeepEqual(selectQB.joins, expectedJoins) {
+		t.Errorf("Expected joins to be %v, got %v", expectedJoins, selectQB.joins)
 	}
 
 	query, err := selectQB.buildQuery()
@@ -94,8 +92,9 @@ func TestSelectQueryBuilder_OrderBy(t *testing.T) {
 	qb := QueryBuilder{}
 	selectQB := qb.Select("id", "name").From("users").OrderBy("id", "DESC")
 
-	if selectQB.sortColumn != "id" || selectQB.sortDirection != "DESC" {
-		t.Errorf("Expected order by 'id DESC', got %s %s", selectQB.sortColumn, selectQB.sortDirection)
+	expectedOrderings := []ordering{{column: "id", direction: "DESC"}}
+	if !reflect.DeepEqual(selectQB.orderings, expectedOrderings) {
+		t.Errorf("Expected order by 'id DESC', got %v", selectQB.orderings)
 	}
 
 	query, err := selectQB.buildQuery()
@@ -151,3 +150,190 @@ func TestSelectQueryBuilder_WhereEqual_Null(t *testing.T) {
 		t.Fatalf("Expected query was not generated\nexpected: %s got: %s", expected, *query)
 	}
 }
+
+func TestSelectQueryBuilder_WhereIn(t *testing.T) {
+	qb := QueryBuilder{}
+	selectQB := qb.Select("id").From("projects").WhereEqual("deletedAt", nil).WhereIn("id", []int64{4, 8, 15})
+
+	query, err := selectQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error when building select query, got %s", err)
+	}
+
+	expected := "SELECT id FROM projects WHERE deletedAt IS NULL AND id IN ($1, $2, $3)"
+	if *query != expected {
+		t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", expected, *query)
+	}
+
+	expectedValues := []interface{}{int64(4), int64(8), int64(15)}
+	if values := selectQB.buildPreparedStatementValues(); !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("Expected values %v, got %v", expectedValues, values)
+	}
+}
+
+func TestSelectQueryBuilder_WhereInEmptyMatchesNothing(t *testing.T) {
+	qb := QueryBuilder{}
+	selectQB := qb.Select("id").From("projects").WhereIn("id").WhereNotIn("slug", []string{}).WhereEqual("title", "Go")
+
+	query, err := selectQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error when building select query, got %s", err)
+	}
+
+	expected := "SELECT id FROM projects WHERE FALSE AND TRUE AND title = $1"
+	if *query != expected {
+		t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", expected, *query)
+	}
+}
+
+func TestSelectQueryBuilder_LikeBetweenAndGroups(t *testing.T) {
+	qb := QueryBuilder{}
+	from := "2024-01-01"
+	to := "2024-12-31"
+
+	selectQB := qb.Select("id").From("notes").
+		WhereEqual("deletedAt", nil).
+		Where(Or(
+			ILike("title", "%go%"),
+			And(Like("body", "%go%"), NotEqual("publishedAt", nil)),
+		)).
+		WhereBetween("createdAt", from, to).
+		WhereNotIn("id", 1, 2).
+		WhereRaw("char_length(body) > ?", 100)
+
+	query, err := selectQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error when building select query, got %s", err)
+	}
+
+	expected := "SELECT id FROM notes WHERE deletedAt IS NULL AND (title ILIKE $1 OR (body LIKE $2 AND publishedAt IS NOT NULL)) AND createdAt BETWEEN $3 AND $4 AND id NOT IN ($5, $6) AND char_length(body) > $7"
+	if *query != expected {
+		t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", expected, *query)
+	}
+
+	expectedValues := []interface{}{"%go%", "%go%", from, to, 1, 2, 100}
+	if values := selectQB.buildPreparedStatementValues(); !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("Expected values %v, got %v", expectedValues, values)
+	}
+}
+
+func TestSelectQueryBuilder_RawPlaceholders(t *testing.T) {
+	qb := QueryBuilder{}
+
+	selectQB := qb.Select("id").From("notes").
+		WhereEqual("id", 4).
+		WhereRaw("metadata ?? ? AND body != '??'", "draft")
+
+	query, err := selectQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error when building select query, got %s", err)
+	}
+
+	expected := "SELECT id FROM notes WHERE id = $1 AND metadata ? $2 AND body != '?'"
+	if *query != expected {
+		t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", expected, *query)
+	}
+
+	mismatched := []*SelectQueryBuilder{
+		qb.Select("id").From("notes").WhereRaw("id > ? AND id < ?", 1),
+		qb.Select("id").From("notes").Where(Or(Equal("id", 1), Raw("id > ?"))),
+		qb.Select("id").From("notes").GroupBy("id").Having(Raw("COUNT(*) > ??", 1)),
+	}
+	for _, q := range mismatched {
+		if _, err := q.buildQuery(); !errors.Is(err, ErrPlaceholderMismatch) {
+			t.Fatalf("Expected ErrPlaceholderMismatch, got %v", err)
+		}
+	}
+}
+
+func TestSelectQueryBuilder_GoldenSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(qb *QueryBuilder) *SelectQueryBuilder
+		expected string
+		values   []interface{}
+	}{
+		{
+			name: "tags with usage counts",
+			build: func(qb *QueryBuilder) *SelectQueryBuilder {
+				return qb.SetBaseTable("tags").Select("tags.id", "tags.name", "COUNT(tagged_items.id) AS usageCount").
+					LeftJoin("tagged_items", "id", "tagId").
+					WhereEqual("tags.deletedAt", nil).
+					GroupBy("tags.id", "tags.name").
+					Having(GreaterThan("COUNT(tagged_items.id)", 0)).
+					OrderBy("usageCount", "DESC").
+					OrderBy("tags.name", "ASC")
+			},
+			expected: "SELECT tags.id, tags.name, COUNT(tagged_items.id) AS usageCount FROM tags LEFT JOIN tagged_items ON tags.id = tagged_items.tagId WHERE tags.deletedAt IS NULL GROUP BY tags.id, tags.name HAVING COUNT(tagged_items.id) > $1 ORDER BY usageCount DESC, tags.name ASC",
+			values:   []interface{}{0},
+		},
+		{
+			name: "roles with company and tags",
+			build: func(qb *QueryBuilder) *SelectQueryBuilder {
+				return qb.Select("r.id", "c.name AS company", "t.name AS tag").From("roles AS r").
+					LeftJoin("companies AS c", "companyId", "id").
+					InnerJoinOn("tagged_items AS ti", "ti.itemId = r.id", "ti.itemType = 'roles'").
+					InnerJoin("tags t", "id", "id").
+					WhereEqual("r.deletedAt", nil).
+					WhereIn("t.slug", "go", "sql")
+			},
+			expected: "SELECT r.id, c.name AS company, t.name AS tag FROM roles AS r LEFT JOIN companies AS c ON r.companyId = c.id INNER JOIN tagged_items AS ti ON ti.itemId = r.id AND ti.itemType = 'roles' INNER JOIN tags t ON r.id = t.id WHERE r.deletedAt IS NULL AND t.slug IN ($1, $2)",
+			values:   []interface{}{"go", "sql"},
+		},
+		{
+			name: "right join",
+			build: func(qb *QueryBuilder) *SelectQueryBuilder {
+				return qb.Select("companies.name", "roles.title").From("roles").RightJoinOn("companies", "companies.id = roles.companyId")
+			},
+			expected: "SELECT companies.name, roles.title FROM roles RIGHT JOIN companies ON companies.id = roles.companyId",
+			values:   []interface{}{},
+		},
+		{
+			name: "distinct with offset",
+			build: func(qb *QueryBuilder) *SelectQueryBuilder {
+				return qb.Select("itemType").From("tagged_items").Distinct().WhereEqual("tagId", 3).OrderBy("itemType", "ASC").Limit(10).Offset(20)
+			},
+			expected: "SELECT DISTINCT itemType FROM tagged_items WHERE tagId = $1 ORDER BY itemType ASC LIMIT 10 OFFSET 20",
+			values:   []interface{}{3},
+		},
+		{
+			name: "having placeholders follow where",
+			build: func(qb *QueryBuilder) *SelectQueryBuilder {
+				return qb.SetBaseTable("tagged_items").Select("tagId", "COUNT(*)").
+					InnerJoinOn("notes", "notes.id = tagged_items.itemId", "tagged_items.itemType = 'notes'").
+					WhereGreaterThan("notes.publishedAt", "2024-01-01").
+					GroupBy("tagId").
+					Having(Or(GreaterThanEqual("COUNT(*)", 5), Raw("MAX(tagged_items.id) > ?", 100)))
+			},
+			expected: "SELECT tagId, COUNT(*) FROM tagged_items INNER JOIN notes ON notes.id = tagged_items.itemId AND tagged_items.itemType = 'notes' WHERE notes.publishedAt > $1 GROUP BY tagId HAVING (COUNT(*) >= $2 OR MAX(tagged_items.id) > $3)",
+			values:   []interface{}{"2024-01-01", 5, 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selectQB := tt.build(&QueryBuilder{})
+
+			query, err := selectQB.buildQuery()
+			if err != nil {
+				t.Fatalf("Unexpected error when building select query, got %s", err)
+			}
+
+			if *query != tt.expected {
+				t.Fatalf("Expected query was not generated\nexpected: %s \ngot: %s", tt.expected, *query)
+			}
+
+			if values := selectQB.buildPreparedStatementValues(); !reflect.DeepEqual(values, tt.values) {
+				t.Fatalf("Expected values %v, got %v", tt.values, values)
+			}
+		})
+	}
+}
+
+func TestSelectQueryBuilder_JoinWithoutCondition(t *testing.T) {
+	qb := QueryBuilder{}
+	query, err := qb.Select("id").From("roles").InnerJoinOn("companies").buildQuery()
+	if query != nil || err == nil {
+		t.Fatalf("Expected an error for a join without an ON condition, got %v", query)
+	}
+}
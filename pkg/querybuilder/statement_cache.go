@@ -0,0 +1,195 @@
+package querybuilder
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// StatementCache is an Executor that prepares each distinct statement once and reuses it,
+// sparing the database from parsing and planning SQL that is run over and over. It keeps at
+// most size statements, closing the least recently used one to make room, and drops a
+// statement whenever running it fails so that it is prepared afresh next time.
+//
+// A *sql.Stmt is prepared per pool and transparently re-prepared on each connection that runs
+// it, so one cache serves the whole pool. Statements run inside a transaction bypass it.
+type StatementCache struct {
+	db   *sql.DB
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	recent  *list.List
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cachedStatement struct {
+	query   string
+	stmt    *sql.Stmt
+	users   int
+	evicted bool
+}
+
+// StatementCacheStats reports how often statements were found in the cache.
+type StatementCacheStats struct {
+	Hits       int64
+	Misses     int64
+	Statements int
+}
+
+// NewStatementCache returns a cache of up to size statements prepared on db.
+func NewStatementCache(db *sql.DB, size int) *StatementCache {
+	return &StatementCache{
+		db:      db,
+		size:    max(size, 1),
+		entries: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// BeginTx begins a transaction on the underlying pool, so models over a cache can still run
+// in transactions.
+func (c *StatementCache) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.db.BeginTx(ctx, opts)
+}
+
+func (c *StatementCache) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	entry, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := entry.stmt.ExecContext(ctx, args...)
+	c.release(entry, err)
+	return result, err
+}
+
+func (c *StatementCache) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	entry, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Closing the statement while rows are still being read is safe; database/sql waits for
+	// them before releasing it.
+	rows, err := entry.stmt.QueryContext(ctx, args...)
+	c.release(entry, err)
+	return rows, err
+}
+
+func (c *StatementCache) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	entry, err := c.acquire(ctx, query)
+	if err != nil {
+		// *sql.Row cannot be built outside database/sql, so let the pool report the error
+		// by running the statement unprepared.
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+
+	row := entry.stmt.QueryRowContext(ctx, args...)
+	c.release(entry, row.Err())
+	return row
+}
+
+// Stats reports the cache's hits, misses and current size.
+func (c *StatementCache) Stats() StatementCacheStats {
+	c.mu.Lock()
+	statements := c.recent.Len()
+	c.mu.Unlock()
+
+	return StatementCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Statements: statements}
+}
+
+// Close closes every cached statement. The cache stays usable and prepares statements again.
+func (c *StatementCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for c.recent.Len() > 0 {
+		if err := c.evictLocked(c.recent.Back()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// acquire returns the cached statement for query, preparing it when it is missing. The
+// statement is not closed until it is released.
+func (c *StatementCache) acquire(ctx context.Context, query string) (*cachedStatement, error) {
+	c.mu.Lock()
+	if element, ok := c.entries[query]; ok {
+		entry := element.Value.(*cachedStatement)
+		entry.users++
+		c.recent.MoveToFront(element)
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry, nil
+	}
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+
+	// Prepare without holding the lock, since it is a round trip to the database.
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[query]; ok {
+		// Another caller prepared the same statement in the meantime.
+		stmt.Close()
+		entry := element.Value.(*cachedStatement)
+		entry.users++
+		c.recent.MoveToFront(element)
+		return entry, nil
+	}
+
+	entry := &cachedStatement{query: query, stmt: stmt, users: 1}
+	c.entries[query] = c.recent.PushFront(entry)
+
+	for c.recent.Len() > c.size {
+		c.evictLocked(c.recent.Back())
+	}
+
+	return entry, nil
+}
+
+// release hands a statement back, dropping it from the cache when running it failed for any
+// reason other than there being no rows or the caller giving up.
+func (c *StatementCache) release(entry *cachedStatement, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.users--
+
+	failed := err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	if element, ok := c.entries[entry.query]; ok && element.Value == entry && failed {
+		c.evictLocked(element)
+		return
+	}
+
+	if entry.evicted && entry.users == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// evictLocked removes an entry, closing its statement unless it is still in use, in which
+// case the last user closes it on release.
+func (c *StatementCache) evictLocked(element *list.Element) error {
+	entry := c.recent.Remove(element).(*cachedStatement)
+	delete(c.entries, entry.query)
+	entry.evicted = true
+
+	if entry.users == 0 {
+		return entry.stmt.Close()
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package querybuilder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	_ "github.com/lib/pq"
+)
+
+const cachedTagQuery = "SELECT id, name FROM tags WHERE id = $1"
+
+func selectTag(qb *QueryBuilder, id int) (string, error) {
+	row, err := qb.SetBaseTable("tags").Select("id", "name").WhereEqual("id", id).QueryRow()
+	if err != nil {
+		return "", err
+	}
+
+	var name string
+	err = row.Scan(&id, &name)
+	return name, err
+}
+
+func TestStatementCache_ReusesPreparedStatements(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	prepared := mock.ExpectPrepare(regexp.QuoteMeta(cachedTagQuery))
+	prepared.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Go"))
+	prepared.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	prepared.ExpectQuery().WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "SQL"))
+
+	cache := NewStatementCache(db, 10)
+	qb := &QueryBuilder{DB: cache}
+
+	if name, err := selectTag(qb, 1); err != nil || name != "Go" {
+		t.Fatalf("unexpected result %q, %v", name, err)
+	}
+	if _, err := selectTag(qb, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+	if name, err := selectTag(qb, 3); err != nil || name != "SQL" {
+		t.Fatalf("unexpected result %q, %v", name, err)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Statements != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestStatementCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	first := mock.ExpectPrepare("DELETE FROM a").WillBeClosed()
+	first.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	second := mock.ExpectPrepare("DELETE FROM b")
+	second.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	second.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	third := mock.ExpectPrepare("DELETE FROM c")
+	third.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+
+	cache := NewStatementCache(db, 2)
+	ctx := context.Background()
+
+	for _, query := range []string{"DELETE FROM a", "DELETE FROM b", "DELETE FROM b", "DELETE FROM c"} {
+		if _, err := cache.ExecContext(ctx, query); err != nil {
+			t.Fatalf("unexpected error running %q: %s", query, err)
+		}
+	}
+
+	if stats := cache.Stats(); stats.Statements != 2 {
+		t.Fatalf("expected 2 cached statements, got %+v", stats)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+func TestStatementCache_DropsStatementsThatFail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	stale := mock.ExpectPrepare(regexp.QuoteMeta(cachedTagQuery)).WillBeClosed()
+	stale.ExpectQuery().WithArgs(1).WillReturnError(errors.New("cached plan must not change result type"))
+	fresh := mock.ExpectPrepare(regexp.QuoteMeta(cachedTagQuery))
+	fresh.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Go"))
+
+	cache := NewStatementCache(db, 10)
+	qb := &QueryBuilder{DB: cache}
+
+	if _, err := selectTag(qb, 1); err == nil {
+		t.Fatalf("expected the stale statement to fail")
+	}
+	if name, err := selectTag(qb, 1); err != nil || name != "Go" {
+		t.Fatalf("expected the statement to be prepared again, got %q, %v", name, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unmet expectations: %s", err)
+	}
+}
+
+// statementCacheBatch bounds how many expectations a mock holds, since sqlmock checks every
+// earlier expectation on each call.
+const statementCacheBatch = 200
+
+func benchmarkMockQueries(b *testing.B, cached bool) {
+	for done := 0; done < b.N; done += statementCacheBatch {
+		b.StopTimer()
+		batch := min(statementCacheBatch, b.N-done)
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			b.Fatalf("unexpected error creating sqlmock: %s", err)
+		}
+
+		qb := &QueryBuilder{DB: db}
+		if cached {
+			qb.DB = NewStatementCache(db, 10)
+			prepared := mock.ExpectPrepare(regexp.QuoteMeta(cachedTagQuery))
+			for i := 0; i < batch; i++ {
+				prepared.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Go"))
+			}
+		} else {
+			for i := 0; i < batch; i++ {
+				mock.ExpectQuery(regexp.QuoteMeta(cachedTagQuery)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Go"))
+			}
+		}
+		b.StartTimer()
+
+		for i := 0; i < batch; i++ {
+			if _, err := selectTag(qb, 1); err != nil {
+				b.Fatalf("unexpected error: %s", err)
+			}
+		}
+
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
+
+// BenchmarkStatementCache_Mock measures what the cache itself costs, as sqlmock does no
+// parsing or planning for it to save.
+func BenchmarkStatementCache_Mock(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) { benchmarkMockQueries(b, false) })
+	b.Run("cached", func(b *testing.B) { benchmarkMockQueries(b, true) })
+}
+
+// BenchmarkStatementCache_Postgres runs against the database in QUERYBUILDER_BENCH_DSN, such as
+// postgres://localhost/postgres?sslmode=disable, and is skipped without one.
+func BenchmarkStatementCache_Postgres(b *testing.B) {
+	dsn := os.Getenv("QUERYBUILDER_BENCH_DSN")
+	if dsn == "" {
+		b.Skip("QUERYBUILDER_BENCH_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatalf("unexpected error opening database: %s", err)
+	}
+	defer db.Close()
+
+	run := func(b *testing.B, qb *QueryBuilder) {
+		for i := 0; i < b.N; i++ {
+			row, err := qb.SetBaseTable("pg_class").Select("relname").WhereEqual("relname", "pg_class").QueryRow()
+			if err != nil {
+				b.Fatalf("unexpected error: %s", err)
+			}
+
+			var name string
+			if err := row.Scan(&name); err != nil {
+				b.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) { run(b, &QueryBuilder{DB: db}) })
+
+	cache := NewStatementCache(db, 10)
+	defer cache.Close()
+	b.Run("cached", func(b *testing.B) { run(b, &QueryBuilder{DB: cache}) })
+}
@@ -1,6 +1,7 @@
 package querybuilder
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -34,18 +35,74 @@ func (q *UpdateQueryBuilder) WhereEqual(column string, value interface{}) *Updat
 	return q
 }
 
+// Where adds a predicate, such as an Or group, to the conditions.
+func (q *UpdateQueryBuilder) Where(predicate Predicate) *UpdateQueryBuilder {
+	q.queryBuilder.addPredicate(predicate, &q.conditions)
+	return q
+}
+
+func (q *UpdateQueryBuilder) WhereIn(column string, values ...interface{}) *UpdateQueryBuilder {
+	return q.Where(In(column, values...))
+}
+
+func (q *UpdateQueryBuilder) WhereNotIn(column string, values ...interface{}) *UpdateQueryBuilder {
+	return q.Where(NotIn(column, values...))
+}
+
+func (q *UpdateQueryBuilder) WhereLike(column string, pattern string) *UpdateQueryBuilder {
+	return q.Where(Like(column, pattern))
+}
+
+func (q *UpdateQueryBuilder) WhereILike(column string, pattern string) *UpdateQueryBuilder {
+	return q.Where(ILike(column, pattern))
+}
+
+func (q *UpdateQueryBuilder) WhereBetween(column string, low, high interface{}) *UpdateQueryBuilder {
+	return q.Where(Between(column, low, high))
+}
+
+// WhereRaw adds a hand-written predicate; see Raw.
+func (q *UpdateQueryBuilder) WhereRaw(sql string, values ...interface{}) *UpdateQueryBuilder {
+	return q.Where(Raw(sql, values...))
+}
+
 func (q *UpdateQueryBuilder) Returning(fields ...string) *UpdateQueryBuilder {
 	q.fields = fields
 	return q
 }
 
+func (q *UpdateQueryBuilder) base() *QueryBuilder {
+	return q.queryBuilder
+}
+
 func (q *UpdateQueryBuilder) buildQuery() (*string, error) {
 	if len(q.values) == 0 || q.table == "" {
 		err := errors.New("Incorrectly formatted query. Ensure fields and base tables are set")
 		return nil, err
 	}
 
-	query := fmt.Sprintf("UPDATE %s", q.table)
+	if err := validateTable(q.table); err != nil {
+		return nil, err
+	}
+
+	if err := validateColumns(q.values); err != nil {
+		return nil, err
+	}
+
+	if err := validatePredicates(q.conditions); err != nil {
+		return nil, err
+	}
+
+	if err := validateReturning(q.fields); err != nil {
+		return nil, err
+	}
+
+	commonTableExpressions, err := q.queryBuilder.buildCommonTableExpressions()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("%sUPDATE %s", commonTableExpressions, q.table)
 	query += q.queryBuilder.buildColumnUpdateStatement(q.values)
 	query += q.queryBuilder.buildConditionalStatement(q.conditions)
 
@@ -58,30 +115,43 @@ func (q *UpdateQueryBuilder) buildQuery() (*string, error) {
 }
 
 func (q UpdateQueryBuilder) Query() (*sql.Rows, error) {
+	return q.QueryContext(q.queryBuilder.Context())
+}
+
+func (q UpdateQueryBuilder) QueryContext(ctx context.Context) (*sql.Rows, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
+
 	values := q.buildPreparedStatementValues()
-	return q.queryBuilder.DB.Query(*query, values...)
+	return q.queryBuilder.Executor().QueryContext(ctx, *query, values...)
 }
 
 func (q UpdateQueryBuilder) QueryRow() (*sql.Row, error) {
+	return q.QueryRowContext(q.queryBuilder.Context())
+}
+
+func (q UpdateQueryBuilder) QueryRowContext(ctx context.Context) (*sql.Row, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 
 	values := q.buildPreparedStatementValues()
-	return q.queryBuilder.DB.QueryRow(*query, values...), nil
+	return q.queryBuilder.Executor().QueryRowContext(ctx, *query, values...), nil
 }
 
 func (q UpdateQueryBuilder) Exec() (sql.Result, error) {
+	return q.ExecContext(q.queryBuilder.Context())
+}
+
+func (q UpdateQueryBuilder) ExecContext(ctx context.Context) (sql.Result, error) {
 	query, err := q.buildQuery()
 	if err != nil {
 		return nil, err
 	}
 
 	values := q.buildPreparedStatementValues()
-	return q.queryBuilder.DB.Exec(*query, values...)
+	return q.queryBuilder.Executor().ExecContext(ctx, *query, values...)
 }
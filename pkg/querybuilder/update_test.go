@@ -81,3 +81,25 @@ func TestUpdateQueryBuilder_Returning(t *testing.T) {
 		t.Errorf("Expected query to be '%s', got '%s'", expectedQuery, *query)
 	}
 }
+
+func TestUpdateQueryBuilder_WhereInNumbersAfterValues(t *testing.T) {
+	qb := QueryBuilder{}
+	values := Clauses{{ColumnName: "position", Value: 0}}
+
+	updateQB := qb.SetBaseTable("note_assets").Update(values).WhereEqual("noteId", 7).WhereIn("assetId", []int64{2, 3}).WhereRaw("position > ?", 4)
+
+	query, err := updateQB.buildQuery()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedQuery := "UPDATE note_assets SET position = $1 WHERE noteId = $2 AND assetId IN ($3, $4) AND position > $5"
+	if *query != expectedQuery {
+		t.Errorf("Expected query to be '%s', got '%s'", expectedQuery, *query)
+	}
+
+	expectedValues := []interface{}{0, 7, int64(2), int64(3), 4}
+	if values := updateQB.buildPreparedStatementValues(); !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("Expected values %v, got %v", expectedValues, values)
+	}
+}